@@ -0,0 +1,229 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+	"github.com/popeyeio/gohbase/pool"
+)
+
+// RowResult is the result of a row requested by GetRows.
+type RowResult struct {
+	Row string
+	// Result is the row, or nil if the row is not found or failed.
+	Result *hbase.TRowResult_
+	// Found reports whether the row is found.
+	Found bool
+	// Err is the error of the chunk the row was fetched in.
+	Err error
+}
+
+type getter struct {
+	chunkRows    int
+	concurrency  int
+	retries      int
+	retryBackoff time.Duration
+	byRegion     bool
+}
+
+type GetOption func(*getter)
+
+// WithGetChunkRows limits the number of rows fetched in a single request.
+func WithGetChunkRows(n int) GetOption {
+	return func(g *getter) {
+		if n > 0 {
+			g.chunkRows = n
+		}
+	}
+}
+
+// WithGetConcurrency limits the number of requests sent concurrently.
+func WithGetConcurrency(n int) GetOption {
+	return func(g *getter) {
+		if n > 0 {
+			g.concurrency = n
+		}
+	}
+}
+
+// WithGetRetries sets the max number of retries of a chunk which failed
+// with a retryable error, and the backoff before the first retry.
+func WithGetRetries(n int, backoff time.Duration) GetOption {
+	return func(g *getter) {
+		if n >= 0 && backoff >= 0 {
+			g.retries = n
+			g.retryBackoff = backoff
+		}
+	}
+}
+
+// WithRegionGrouping groups rows by the regions of the table before
+// splitting them into chunks, so that every request hits a single region.
+func WithRegionGrouping(byRegion bool) GetOption {
+	return func(g *getter) {
+		g.byRegion = byRegion
+	}
+}
+
+// GetRows fetches rows of table, with the given columns or all of them if
+// columns is empty. Rows are deduplicated and split into chunks which are
+// fetched concurrently from e. The results are in the order of rows, with
+// Found false for the rows which do not exist.
+func GetRows(ctx context.Context, e pool.Executor, table string, rows, columns []string,
+	attributes map[string]string, opts ...GetOption) ([]*RowResult, error) {
+	g := &getter{
+		chunkRows:    defaultChunkRows,
+		concurrency:  defaultConcurrency,
+		retries:      defaultRetries,
+		retryBackoff: defaultRetryBackoff,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	keys := dedup(rows)
+
+	var groups [][]string
+	if g.byRegion {
+		regions, err := e.GetTableRegions(table)
+		if err != nil {
+			return nil, err
+		}
+		groups = groupByRegion(keys, regions)
+	} else {
+		groups = [][]string{keys}
+	}
+
+	var chunks [][]string
+	for _, group := range groups {
+		for len(group) > g.chunkRows {
+			chunks = append(chunks, group[:g.chunkRows])
+			group = group[g.chunkRows:]
+		}
+		if len(group) > 0 {
+			chunks = append(chunks, group)
+		}
+	}
+
+	var mu sync.Mutex
+	results := make(map[string]*RowResult, len(keys))
+	sem := make(chan struct{}, g.concurrency)
+
+	var wg sync.WaitGroup
+	for _, chunk := range chunks {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			mu.Lock()
+			for _, row := range chunk {
+				results[row] = &RowResult{Row: row, Err: ctx.Err()}
+			}
+			mu.Unlock()
+			continue
+		}
+
+		wg.Add(1)
+		go func(chunk []string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			res := g.get(ctx, e, table, chunk, columns, attributes)
+			mu.Lock()
+			for _, r := range res {
+				results[r.Row] = r
+			}
+			mu.Unlock()
+		}(chunk)
+	}
+	wg.Wait()
+
+	res := make([]*RowResult, len(rows))
+	for i, row := range rows {
+		res[i] = results[row]
+	}
+	return res, nil
+}
+
+// get fetches a chunk of rows in a single request, retrying on retryable errors.
+func (g *getter) get(ctx context.Context, e pool.Executor, table string, chunk, columns []string,
+	attributes map[string]string) []*RowResult {
+	var rsp []*hbase.TRowResult_
+	err := retry(ctx, g.retries, g.retryBackoff, func() error {
+		return e.Do(ctx, func(c pool.Client) (err error) {
+			if len(columns) == 0 {
+				rsp, err = c.GetRows(table, chunk, attributes)
+			} else {
+				rsp, err = c.GetRowsWithColumns(table, chunk, columns, attributes)
+			}
+			return
+		})
+	})
+
+	found := make(map[string]*hbase.TRowResult_, len(rsp))
+	for _, r := range rsp {
+		if r != nil {
+			found[string(r.Row)] = r
+		}
+	}
+
+	res := make([]*RowResult, len(chunk))
+	for i, row := range chunk {
+		res[i] = &RowResult{Row: row, Err: err}
+		if r, ok := found[row]; ok {
+			res[i].Result = r
+			res[i].Found = true
+		}
+	}
+	return res
+}
+
+// dedup returns the distinct rows sorted.
+func dedup(rows []string) []string {
+	keys := make([]string, 0, len(rows))
+	seen := make(map[string]struct{}, len(rows))
+	for _, row := range rows {
+		if _, ok := seen[row]; !ok {
+			seen[row] = struct{}{}
+			keys = append(keys, row)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// groupByRegion groups sorted keys by the regions they belong to.
+func groupByRegion(keys []string, regions []*hbase.TRegionInfo) [][]string {
+	starts := make([][]byte, 0, len(regions))
+	for _, r := range regions {
+		if r != nil {
+			starts = append(starts, r.StartKey)
+		}
+	}
+	sort.Slice(starts, func(i, j int) bool {
+		return bytes.Compare(starts[i], starts[j]) < 0
+	})
+
+	var groups [][]string
+	region, begin := -1, 0
+	for i, key := range keys {
+		// the last region whose start key is not after key
+		r := sort.Search(len(starts), func(j int) bool {
+			return bytes.Compare(starts[j], []byte(key)) > 0
+		}) - 1
+
+		if r != region && i > begin {
+			groups = append(groups, keys[begin:i])
+			begin = i
+		}
+		region = r
+	}
+	if begin < len(keys) {
+		groups = append(groups, keys[begin:])
+	}
+	return groups
+}
@@ -0,0 +1,120 @@
+package batch
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+	"github.com/popeyeio/gohbase/lib/thrift"
+	"github.com/popeyeio/gohbase/pool"
+)
+
+func TestGroupByRegion(t *testing.T) {
+	regions := []*hbase.TRegionInfo{
+		{StartKey: hbase.Text("m"), EndKey: hbase.Text("t")},
+		{StartKey: hbase.Text(""), EndKey: hbase.Text("m")},
+		{StartKey: hbase.Text("t"), EndKey: hbase.Text("")},
+	}
+
+	keys := dedup([]string{"z", "a", "m", "b", "a", "s"})
+	groups := groupByRegion(keys, regions)
+
+	expected := [][]string{{"a", "b"}, {"m", "s"}, {"z"}}
+	if !reflect.DeepEqual(groups, expected) {
+		t.Errorf("expected groups %v, got %v", expected, groups)
+	}
+}
+
+type getExecutor struct {
+	pool.Executor
+
+	sync.Mutex
+	found    map[string]bool
+	regions  []*hbase.TRegionInfo
+	failures map[string]int
+	calls    map[string]int
+}
+
+func (e *getExecutor) GetTableRegions(string) ([]*hbase.TRegionInfo, error) {
+	return e.regions, nil
+}
+
+func (e *getExecutor) Do(ctx context.Context, f func(pool.Client) error) error {
+	return f(&getClient{e: e})
+}
+
+type getClient struct {
+	pool.Client
+
+	e *getExecutor
+}
+
+// GetRows fails the chunks starting with a row in failures, and returns the
+// found rows in reverse order.
+func (c *getClient) GetRows(table string, rows []string, _ map[string]string) ([]*hbase.TRowResult_, error) {
+	c.e.Lock()
+	defer c.e.Unlock()
+
+	c.e.calls[rows[0]]++
+	if c.e.failures[rows[0]] > 0 {
+		c.e.failures[rows[0]]--
+		return nil, thrift.NewTTransportException(thrift.TIMED_OUT, "timed out")
+	}
+
+	var rsp []*hbase.TRowResult_
+	for i := len(rows) - 1; i >= 0; i-- {
+		if c.e.found[rows[i]] {
+			rsp = append(rsp, &hbase.TRowResult_{Row: hbase.Text(rows[i])})
+		}
+	}
+	return rsp, nil
+}
+
+func TestGetRows(t *testing.T) {
+	e := &getExecutor{
+		found: map[string]bool{"a": true, "b": true, "m": true, "s": true, "z": true},
+		regions: []*hbase.TRegionInfo{
+			{StartKey: hbase.Text(""), EndKey: hbase.Text("m")},
+			{StartKey: hbase.Text("m"), EndKey: hbase.Text("t")},
+			{StartKey: hbase.Text("t"), EndKey: hbase.Text("")},
+		},
+		// the second region fails once, and the last one more than retried
+		failures: map[string]int{"m": 1, "x": 3},
+		calls:    make(map[string]int),
+	}
+
+	rows := []string{"z", "a", "m", "n", "b", "a", "s", "x"}
+	res, err := GetRows(context.Background(), e, "t", rows, nil, nil,
+		WithRegionGrouping(true), WithGetRetries(2, 0))
+	if err != nil {
+		t.Fatalf("GetRows error - %v", err)
+	}
+
+	if len(res) != len(rows) {
+		t.Fatalf("expected %d results, got %d", len(rows), len(res))
+	}
+	for i, r := range res {
+		if r.Row != rows[i] {
+			t.Errorf("result %d is row %q, expected %q", i, r.Row, rows[i])
+		}
+
+		failed := r.Row == "x" || r.Row == "z"
+		if failed != (r.Err != nil) {
+			t.Errorf("unexpected error of row %q - %v", r.Row, r.Err)
+		}
+		found := e.found[r.Row] && !failed
+		if r.Found != found || (r.Result != nil) != found {
+			t.Errorf("expected row %q found %v, got %+v", r.Row, found, r)
+		}
+		if found && string(r.Result.Row) != r.Row {
+			t.Errorf("row %q has the result of %q", r.Row, r.Result.Row)
+		}
+	}
+
+	expected := map[string]int{"a": 1, "m": 2, "x": 3}
+	if !reflect.DeepEqual(e.calls, expected) {
+		t.Errorf("expected calls per region %v, got %v", expected, e.calls)
+	}
+}
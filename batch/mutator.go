@@ -0,0 +1,449 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+	"github.com/popeyeio/gohbase/pool"
+)
+
+var (
+	ErrMutatorClosed = errors.New("[gohbase] mutator is closed")
+)
+
+const (
+	defaultFlushCount    = 1000
+	defaultFlushSize     = 1 << 20
+	defaultFlushInterval = time.Second
+	defaultMaxBuffer     = 16 << 20
+	defaultConcurrency   = 4
+	defaultFailureBuffer = 1024
+)
+
+// Failure is a flush which failed, with the original mutations.
+type Failure struct {
+	Table     string
+	Mutations []*hbase.BatchMutation
+	Err       error
+}
+
+// BufferedMutator buffers mutations from many goroutines per table, and
+// flushes them asynchronously with MutateRows.
+type BufferedMutator interface {
+	// Mutate buffers mutations of table. It blocks while the buffer is full,
+	// until there is enough space or ctx is done. If ctx is done while
+	// waiting for a flush worker, the mutations stay buffered for the next
+	// flush and ctx.Err() is returned.
+	Mutate(ctx context.Context, table string, mutations ...*hbase.BatchMutation) error
+	// Flush flushes all the buffered mutations, and waits until every flush
+	// in flight completes or ctx is done.
+	Flush(ctx context.Context) error
+	// Close flushes all the buffered mutations and stops the mutator.
+	Close(ctx context.Context) error
+	// Failures returns the channel failed flushes are sent to if no failure
+	// handler is set. Failures are dropped if the channel is full.
+	Failures() <-chan *Failure
+	Stats() MutatorStats
+}
+
+// MutatorStats contains statistics of a BufferedMutator.
+type MutatorStats struct {
+	// Buffered is the bytes of mutations which are buffered or in flight.
+	Buffered int
+	// Flushes is the total number of flushes.
+	Flushes int64
+	// Mutations is the total number of mutations flushed successfully.
+	Mutations int64
+	// Failed is the total number of mutations failed to flush.
+	Failed int64
+	// Dropped is the total number of failures dropped as the failure channel is full.
+	Dropped int64
+}
+
+type mutator struct {
+	sync.Mutex
+
+	e pool.Executor
+
+	flushCount    int
+	flushSize     int
+	flushInterval time.Duration
+	maxBuffer     int
+	concurrency   int
+	attributes    map[string]string
+	onFailure     func(*Failure)
+
+	tables    map[string]*tableBuffer
+	buffered  int
+	spaceChan chan struct{}
+	inflight  map[*flushJob]struct{}
+
+	jobs      chan *flushJob
+	failures  chan *Failure
+	closed    int32
+	closeChan chan struct{}
+	wg        sync.WaitGroup
+
+	flushes   int64
+	mutations int64
+	failed    int64
+	dropped   int64
+}
+
+var _ BufferedMutator = (*mutator)(nil)
+
+type tableBuffer struct {
+	mutations []*hbase.BatchMutation
+	size      int
+}
+
+type flushJob struct {
+	table     string
+	mutations []*hbase.BatchMutation
+	size      int
+	done      chan struct{}
+}
+
+type MutatorOption func(*mutator)
+
+// WithFlushCount flushes a table once it has n buffered mutations.
+func WithFlushCount(n int) MutatorOption {
+	return func(m *mutator) {
+		if n > 0 {
+			m.flushCount = n
+		}
+	}
+}
+
+// WithFlushSize flushes a table once it has size bytes of buffered mutations.
+func WithFlushSize(size int) MutatorOption {
+	return func(m *mutator) {
+		if size > 0 {
+			m.flushSize = size
+		}
+	}
+}
+
+// WithFlushInterval flushes all the tables every interval.
+func WithFlushInterval(interval time.Duration) MutatorOption {
+	return func(m *mutator) {
+		if interval > 0 {
+			m.flushInterval = interval
+		}
+	}
+}
+
+// WithMaxBuffer limits the bytes of mutations buffered or in flight,
+// beyond which Mutate blocks.
+func WithMaxBuffer(size int) MutatorOption {
+	return func(m *mutator) {
+		if size > 0 {
+			m.maxBuffer = size
+		}
+	}
+}
+
+// WithConcurrency limits the number of concurrent flushes.
+func WithConcurrency(n int) MutatorOption {
+	return func(m *mutator) {
+		if n > 0 {
+			m.concurrency = n
+		}
+	}
+}
+
+// WithAttributes sets the attributes sent with every flush.
+func WithAttributes(attributes map[string]string) MutatorOption {
+	return func(m *mutator) {
+		m.attributes = attributes
+	}
+}
+
+// WithFailureHandler calls handler with every failed flush,
+// instead of sending it to the failure channel.
+func WithFailureHandler(handler func(*Failure)) MutatorOption {
+	return func(m *mutator) {
+		if handler != nil {
+			m.onFailure = handler
+		}
+	}
+}
+
+func NewBufferedMutator(e pool.Executor, opts ...MutatorOption) BufferedMutator {
+	m := &mutator{
+		e:             e,
+		flushCount:    defaultFlushCount,
+		flushSize:     defaultFlushSize,
+		flushInterval: defaultFlushInterval,
+		maxBuffer:     defaultMaxBuffer,
+		concurrency:   defaultConcurrency,
+		tables:        make(map[string]*tableBuffer),
+		spaceChan:     make(chan struct{}),
+		inflight:      make(map[*flushJob]struct{}),
+		jobs:          make(chan *flushJob),
+		failures:      make(chan *Failure, defaultFailureBuffer),
+		closeChan:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	for i := 0; i < m.concurrency; i++ {
+		m.wg.Add(1)
+		go m.work()
+	}
+	m.wg.Add(1)
+	go m.flushPeriodically()
+	return m
+}
+
+func (m *mutator) Mutate(ctx context.Context, table string, mutations ...*hbase.BatchMutation) error {
+	size := 0
+	for _, b := range mutations {
+		size += Size(b)
+	}
+
+	m.Lock()
+	for m.buffered > 0 && m.buffered+size > m.maxBuffer {
+		ch := m.spaceChan
+		m.Unlock()
+
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-m.closeChan:
+			return ErrMutatorClosed
+		}
+
+		m.Lock()
+	}
+
+	if m.IsClosed() {
+		m.Unlock()
+		return ErrMutatorClosed
+	}
+
+	tb := m.tables[table]
+	if tb == nil {
+		tb = &tableBuffer{}
+		m.tables[table] = tb
+	}
+	tb.mutations = append(tb.mutations, mutations...)
+	tb.size += size
+	m.buffered += size
+
+	var job *flushJob
+	if len(tb.mutations) >= m.flushCount || tb.size >= m.flushSize {
+		job = m.takeJob(table)
+	}
+	m.Unlock()
+
+	if job != nil {
+		return m.submit(ctx, job)
+	}
+	return nil
+}
+
+func (m *mutator) Flush(ctx context.Context) error {
+	m.Lock()
+	jobs := make([]*flushJob, 0, len(m.tables)+len(m.inflight))
+	for job := range m.inflight {
+		jobs = append(jobs, job)
+	}
+	var pending []*flushJob
+	for table := range m.tables {
+		if job := m.takeJob(table); job != nil {
+			pending = append(pending, job)
+		}
+	}
+	m.Unlock()
+
+	for i, job := range pending {
+		if err := m.submit(ctx, job); err != nil {
+			for _, job := range pending[i+1:] {
+				m.requeue(job)
+			}
+			return err
+		}
+	}
+
+	for _, job := range append(jobs, pending...) {
+		select {
+		case <-job.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (m *mutator) Close(ctx context.Context) error {
+	// reject mutations before the final flush, so that none is left buffered
+	m.Lock()
+	closed := atomic.CompareAndSwapInt32(&m.closed, 0, 1)
+	m.Unlock()
+	if !closed {
+		return ErrMutatorClosed
+	}
+
+	err := m.Flush(ctx)
+
+	close(m.closeChan)
+	m.wg.Wait()
+	return err
+}
+
+func (m *mutator) IsClosed() bool {
+	return atomic.LoadInt32(&m.closed) == 1
+}
+
+func (m *mutator) Failures() <-chan *Failure {
+	return m.failures
+}
+
+func (m *mutator) Stats() MutatorStats {
+	m.Lock()
+	buffered := m.buffered
+	m.Unlock()
+
+	return MutatorStats{
+		Buffered:  buffered,
+		Flushes:   atomic.LoadInt64(&m.flushes),
+		Mutations: atomic.LoadInt64(&m.mutations),
+		Failed:    atomic.LoadInt64(&m.failed),
+		Dropped:   atomic.LoadInt64(&m.dropped),
+	}
+}
+
+// takeJob takes the buffered mutations of table as a flush job.
+// It must be called with m locked.
+func (m *mutator) takeJob(table string) *flushJob {
+	tb := m.tables[table]
+	if tb == nil || len(tb.mutations) == 0 {
+		return nil
+	}
+	delete(m.tables, table)
+
+	job := &flushJob{
+		table:     table,
+		mutations: tb.mutations,
+		size:      tb.size,
+		done:      make(chan struct{}),
+	}
+	m.inflight[job] = struct{}{}
+	return job
+}
+
+// submit hands job over to a worker. If ctx is done before that, job is
+// buffered again for the next flush. If m is closed, job fails.
+func (m *mutator) submit(ctx context.Context, job *flushJob) error {
+	select {
+	case m.jobs <- job:
+		return nil
+	case <-ctx.Done():
+		m.requeue(job)
+		return ctx.Err()
+	case <-m.closeChan:
+		m.finish(job, ErrMutatorClosed)
+		return ErrMutatorClosed
+	}
+}
+
+// requeue buffers the mutations of job again, ahead of those buffered since.
+func (m *mutator) requeue(job *flushJob) {
+	m.Lock()
+	delete(m.inflight, job)
+	tb := m.tables[job.table]
+	if tb == nil {
+		tb = &tableBuffer{}
+		m.tables[job.table] = tb
+	}
+	tb.mutations = append(job.mutations, tb.mutations...)
+	tb.size += job.size
+	m.Unlock()
+
+	close(job.done)
+}
+
+func (m *mutator) work() {
+	defer m.wg.Done()
+
+	for {
+		select {
+		case job := <-m.jobs:
+			m.flush(job)
+		case <-m.closeChan:
+			return
+		}
+	}
+}
+
+func (m *mutator) flushPeriodically() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.Lock()
+			var jobs []*flushJob
+			for table := range m.tables {
+				if job := m.takeJob(table); job != nil {
+					jobs = append(jobs, job)
+				}
+			}
+			m.Unlock()
+
+			for _, job := range jobs {
+				m.submit(context.Background(), job)
+			}
+		case <-m.closeChan:
+			return
+		}
+	}
+}
+
+func (m *mutator) flush(job *flushJob) {
+	err := m.e.MutateRows(job.table, job.mutations, m.attributes)
+	atomic.AddInt64(&m.flushes, 1)
+	m.finish(job, err)
+}
+
+// finish releases the buffer of job, and reports it as failed if err is not nil.
+func (m *mutator) finish(job *flushJob, err error) {
+	if err != nil {
+		atomic.AddInt64(&m.failed, int64(len(job.mutations)))
+		m.fail(&Failure{Table: job.table, Mutations: job.mutations, Err: err})
+	} else {
+		atomic.AddInt64(&m.mutations, int64(len(job.mutations)))
+	}
+
+	m.Lock()
+	delete(m.inflight, job)
+	m.buffered -= job.size
+	close(m.spaceChan)
+	m.spaceChan = make(chan struct{})
+	m.Unlock()
+
+	close(job.done)
+}
+
+func (m *mutator) fail(f *Failure) {
+	if m.onFailure != nil {
+		m.onFailure(f)
+		return
+	}
+
+	select {
+	case m.failures <- f:
+	default:
+		atomic.AddInt64(&m.dropped, 1)
+	}
+}
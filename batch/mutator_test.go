@@ -0,0 +1,199 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+	"github.com/popeyeio/gohbase/pool"
+)
+
+type fakeExecutor struct {
+	pool.Executor
+
+	sync.Mutex
+	rows map[string][]string
+	fail func(table string, batches []*hbase.BatchMutation) error
+}
+
+func newFakeExecutor() *fakeExecutor {
+	return &fakeExecutor{
+		rows: make(map[string][]string),
+	}
+}
+
+func (e *fakeExecutor) MutateRows(table string, batches []*hbase.BatchMutation, _ map[string]string) error {
+	if e.fail != nil {
+		if err := e.fail(table, batches); err != nil {
+			return err
+		}
+	}
+
+	e.Lock()
+	defer e.Unlock()
+	for _, b := range batches {
+		e.rows[table] = append(e.rows[table], string(b.Row))
+	}
+	return nil
+}
+
+func (e *fakeExecutor) Do(ctx context.Context, f func(pool.Client) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return f(&fakeClient{e: e})
+}
+
+type fakeClient struct {
+	pool.Client
+
+	e *fakeExecutor
+}
+
+func (c *fakeClient) MutateRows(table string, batches []*hbase.BatchMutation, attributes map[string]string) error {
+	return c.e.MutateRows(table, batches, attributes)
+}
+
+func newBatchMutation(row string) *hbase.BatchMutation {
+	return &hbase.BatchMutation{
+		Row: hbase.Text(row),
+		Mutations: []*hbase.Mutation{
+			{Column: hbase.Text("c:q"), Value: hbase.Text(row), WriteToWAL: true},
+		},
+	}
+}
+
+func TestBufferedMutator(t *testing.T) {
+	errFail := errors.New("fail")
+	e := newFakeExecutor()
+	e.fail = func(table string, _ []*hbase.BatchMutation) error {
+		if table == "bad" {
+			return errFail
+		}
+		return nil
+	}
+
+	var failures []*Failure
+	m := NewBufferedMutator(e,
+		WithFlushCount(10),
+		WithFlushInterval(time.Hour),
+		WithFailureHandler(func(f *Failure) {
+			failures = append(failures, f)
+		}),
+	)
+
+	ctx := context.Background()
+	for i := 0; i < 25; i++ {
+		if err := m.Mutate(ctx, "good", newBatchMutation(strconv.Itoa(i))); err != nil {
+			t.Fatalf("Mutate error - %v", err)
+		}
+	}
+	if err := m.Mutate(ctx, "bad", newBatchMutation("x")); err != nil {
+		t.Fatalf("Mutate error - %v", err)
+	}
+
+	if err := m.Close(ctx); err != nil {
+		t.Fatalf("Close error - %v", err)
+	}
+	if n := len(e.rows["good"]); n != 25 {
+		t.Errorf("expected 25 rows flushed, got %d", n)
+	}
+	if len(failures) != 1 || failures[0].Table != "bad" || failures[0].Err != errFail || len(failures[0].Mutations) != 1 {
+		t.Errorf("unexpected failures - %v", failures)
+	}
+	if s := m.Stats(); s.Buffered != 0 || s.Mutations != 25 || s.Failed != 1 {
+		t.Errorf("unexpected stats - %+v", s)
+	}
+	if err := m.Mutate(ctx, "good", newBatchMutation("y")); err != ErrMutatorClosed {
+		t.Errorf("expected error %v, got %v", ErrMutatorClosed, err)
+	}
+}
+
+func TestBufferedMutator_CloseConcurrently(t *testing.T) {
+	flushing, release := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	e := newFakeExecutor()
+	e.fail = func(string, []*hbase.BatchMutation) error {
+		once.Do(func() {
+			close(flushing)
+			<-release
+		})
+		return nil
+	}
+	m := NewBufferedMutator(e, WithFlushInterval(time.Hour))
+
+	ctx := context.Background()
+	if err := m.Mutate(ctx, "t", newBatchMutation("a")); err != nil {
+		t.Fatalf("Mutate error - %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- m.Close(ctx)
+	}()
+	<-flushing
+
+	// a mutation during the final flush would be left buffered
+	if err := m.Mutate(ctx, "t", newBatchMutation("b")); err != ErrMutatorClosed {
+		t.Errorf("expected error %v, got %v", ErrMutatorClosed, err)
+	}
+	close(release)
+
+	if err := <-done; err != nil {
+		t.Fatalf("Close error - %v", err)
+	}
+	if s := m.Stats(); s.Buffered != 0 || s.Mutations != 1 {
+		t.Errorf("unexpected stats - %+v", s)
+	}
+	if err := m.Close(ctx); err != ErrMutatorClosed {
+		t.Errorf("expected error %v, got %v", ErrMutatorClosed, err)
+	}
+}
+
+func TestBufferedMutator_SubmitCanceled(t *testing.T) {
+	flushing, release := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	e := newFakeExecutor()
+	e.fail = func(string, []*hbase.BatchMutation) error {
+		once.Do(func() {
+			close(flushing)
+			<-release
+		})
+		return nil
+	}
+	m := NewBufferedMutator(e, WithFlushCount(1), WithConcurrency(1), WithFlushInterval(time.Hour))
+
+	if err := m.Mutate(context.Background(), "t", newBatchMutation("a")); err != nil {
+		t.Fatalf("Mutate error - %v", err)
+	}
+	<-flushing
+
+	// the only worker is busy, so the flush is not made beyond the concurrency
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := m.Mutate(ctx, "t", newBatchMutation("b")); err != context.Canceled {
+		t.Errorf("expected error %v, got %v", context.Canceled, err)
+	}
+	e.Lock()
+	rows := len(e.rows["t"])
+	e.Unlock()
+	if rows != 0 {
+		t.Errorf("expected no flushed rows while the worker is busy, got %d", rows)
+	}
+	close(release)
+
+	// the mutation is left buffered for the final flush
+	if err := m.Close(context.Background()); err != nil {
+		t.Fatalf("Close error - %v", err)
+	}
+	if rows := e.rows["t"]; len(rows) != 2 || rows[0] != "a" || rows[1] != "b" {
+		t.Errorf("unexpected flushed rows %v", rows)
+	}
+	if s := m.Stats(); s.Buffered != 0 || s.Mutations != 2 {
+		t.Errorf("unexpected stats - %+v", s)
+	}
+}
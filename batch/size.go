@@ -0,0 +1,25 @@
+package batch
+
+import (
+	"github.com/popeyeio/gohbase/gen/hbase"
+)
+
+const (
+	// fieldOverhead is roughly the bytes of a field header in the binary protocol.
+	fieldOverhead = 8
+)
+
+// Size estimates the encoded size of b in bytes.
+func Size(b *hbase.BatchMutation) int {
+	if b == nil {
+		return 0
+	}
+
+	n := fieldOverhead + len(b.Row)
+	for _, m := range b.Mutations {
+		if m != nil {
+			n += 3*fieldOverhead + len(m.Column) + len(m.Value)
+		}
+	}
+	return n
+}
@@ -0,0 +1,235 @@
+package batch
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+	"github.com/popeyeio/gohbase/pool"
+)
+
+const (
+	defaultChunkRows    = 1000
+	defaultChunkSize    = 4 << 20
+	defaultRetries      = 2
+	defaultRetryBackoff = time.Millisecond * 50
+)
+
+// FailedMutation is a mutation which failed to be applied.
+type FailedMutation struct {
+	Mutation *hbase.BatchMutation
+	Err      error
+}
+
+// WriteResult is the result of MutateRows, listing the succeeded and failed
+// mutations in the order they were given.
+type WriteResult struct {
+	Succeeded []*hbase.BatchMutation
+	Failed    []*FailedMutation
+}
+
+// Err returns the errors of the failed mutations, or nil if all succeeded.
+func (r *WriteResult) Err() error {
+	if len(r.Failed) == 0 {
+		return nil
+	}
+
+	var errs pool.Errors
+	for _, f := range r.Failed {
+		errs.Add(f.Err)
+	}
+	return errs
+}
+
+type writer struct {
+	chunkRows    int
+	chunkSize    int
+	concurrency  int
+	retries      int
+	retryBackoff time.Duration
+	bisect       bool
+}
+
+type WriteOption func(*writer)
+
+// WithChunkRows limits the number of mutations sent in a single request.
+func WithChunkRows(n int) WriteOption {
+	return func(w *writer) {
+		if n > 0 {
+			w.chunkRows = n
+		}
+	}
+}
+
+// WithChunkSize limits the estimated bytes of mutations sent in a single request.
+func WithChunkSize(size int) WriteOption {
+	return func(w *writer) {
+		if size > 0 {
+			w.chunkSize = size
+		}
+	}
+}
+
+// WithWriteConcurrency limits the number of requests sent concurrently.
+func WithWriteConcurrency(n int) WriteOption {
+	return func(w *writer) {
+		if n > 0 {
+			w.concurrency = n
+		}
+	}
+}
+
+// WithChunkRetries sets the max number of retries of a chunk which failed
+// with a retryable error, and the backoff before the first retry.
+func WithChunkRetries(n int, backoff time.Duration) WriteOption {
+	return func(w *writer) {
+		if n >= 0 && backoff >= 0 {
+			w.retries = n
+			w.retryBackoff = backoff
+		}
+	}
+}
+
+// WithBisect splits a chunk which failed with an unretryable error in halves
+// and retries them, until the failed mutations are isolated.
+func WithBisect(bisect bool) WriteOption {
+	return func(w *writer) {
+		w.bisect = bisect
+	}
+}
+
+// MutateRows splits batches into chunks by row count and size, and sends the
+// chunks in parallel over connections borrowed from e. Unlike a single
+// MutateRows call, it reports which mutations succeeded and which failed.
+func MutateRows(ctx context.Context, e pool.Executor, table string, batches []*hbase.BatchMutation,
+	attributes map[string]string, opts ...WriteOption) *WriteResult {
+	w := &writer{
+		chunkRows:    defaultChunkRows,
+		chunkSize:    defaultChunkSize,
+		concurrency:  defaultConcurrency,
+		retries:      defaultRetries,
+		retryBackoff: defaultRetryBackoff,
+		bisect:       true,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	chunks := w.split(batches)
+	results := make([]*WriteResult, len(chunks))
+	sem := make(chan struct{}, w.concurrency)
+
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			results[i] = failAll(chunk, ctx.Err())
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, chunk []*hbase.BatchMutation) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			results[i] = &WriteResult{}
+			w.write(ctx, e, table, chunk, attributes, results[i])
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	res := &WriteResult{}
+	for _, r := range results {
+		res.Succeeded = append(res.Succeeded, r.Succeeded...)
+		res.Failed = append(res.Failed, r.Failed...)
+	}
+	return res
+}
+
+// split splits batches into chunks of at most chunkRows mutations and
+// chunkSize bytes. A single mutation larger than chunkSize is a chunk itself.
+func (w *writer) split(batches []*hbase.BatchMutation) [][]*hbase.BatchMutation {
+	var chunks [][]*hbase.BatchMutation
+
+	start, size := 0, 0
+	for i, b := range batches {
+		s := Size(b)
+		if i > start && (i-start >= w.chunkRows || size+s > w.chunkSize) {
+			chunks = append(chunks, batches[start:i])
+			start, size = i, 0
+		}
+		size += s
+	}
+	if start < len(batches) {
+		chunks = append(chunks, batches[start:])
+	}
+	return chunks
+}
+
+func (w *writer) write(ctx context.Context, e pool.Executor, table string, chunk []*hbase.BatchMutation,
+	attributes map[string]string, res *WriteResult) {
+	err := w.mutate(ctx, e, table, chunk, attributes)
+	if err == nil {
+		res.Succeeded = append(res.Succeeded, chunk...)
+		return
+	}
+
+	if !w.bisect || len(chunk) == 1 || ctx.Err() != nil || pool.IsRetryable(err) {
+		res.Failed = append(res.Failed, failAll(chunk, err).Failed...)
+		return
+	}
+
+	mid := len(chunk) / 2
+	w.write(ctx, e, table, chunk[:mid], attributes, res)
+	w.write(ctx, e, table, chunk[mid:], attributes, res)
+}
+
+// mutate sends chunk in a single request, retrying on retryable errors.
+func (w *writer) mutate(ctx context.Context, e pool.Executor, table string, chunk []*hbase.BatchMutation,
+	attributes map[string]string) error {
+	return retry(ctx, w.retries, w.retryBackoff, func() error {
+		return e.Do(ctx, func(c pool.Client) error {
+			return c.MutateRows(table, chunk, attributes)
+		})
+	})
+}
+
+func failAll(chunk []*hbase.BatchMutation, err error) *WriteResult {
+	res := &WriteResult{
+		Failed: make([]*FailedMutation, len(chunk)),
+	}
+	for i, b := range chunk {
+		res.Failed[i] = &FailedMutation{Mutation: b, Err: err}
+	}
+	return res
+}
+
+// retry calls f until it succeeds, fails with an unretryable error, runs out
+// of retries or ctx is done. The backoff is doubled on every retry.
+func retry(ctx context.Context, retries int, backoff time.Duration, f func() error) (err error) {
+	for i := 0; ; i++ {
+		if err = f(); err == nil || i >= retries || !pool.IsRetryable(err) {
+			return
+		}
+
+		if err = sleep(ctx, backoff); err != nil {
+			return
+		}
+		backoff *= 2
+	}
+}
+
+// sleep sleeps for d, or returns the error of ctx if it is done earlier.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
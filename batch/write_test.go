@@ -0,0 +1,52 @@
+package batch
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+)
+
+func TestMutateRows(t *testing.T) {
+	e := newFakeExecutor()
+	e.fail = func(_ string, batches []*hbase.BatchMutation) error {
+		for _, b := range batches {
+			if string(b.Row) == "5" {
+				return &hbase.IOError{Message: "bad row"}
+			}
+		}
+		return nil
+	}
+
+	batches := make([]*hbase.BatchMutation, 10)
+	for i := range batches {
+		batches[i] = newBatchMutation(strconv.Itoa(i))
+	}
+
+	res := MutateRows(context.Background(), e, "t", batches, nil, WithChunkRows(3), WithWriteConcurrency(2))
+	if len(res.Succeeded) != 9 || len(res.Failed) != 1 {
+		t.Fatalf("expected 9 succeeded and 1 failed, got %d and %d", len(res.Succeeded), len(res.Failed))
+	}
+	if string(res.Failed[0].Mutation.Row) != "5" || res.Err() == nil {
+		t.Errorf("unexpected failure - %v", res.Failed[0])
+	}
+	if len(e.rows["t"]) != 9 {
+		t.Errorf("expected 9 rows written, got %v", e.rows["t"])
+	}
+}
+
+func TestWriter_Split(t *testing.T) {
+	w := &writer{chunkRows: 2, chunkSize: Size(newBatchMutation("0")) * 3}
+
+	batches := []*hbase.BatchMutation{newBatchMutation("0"), newBatchMutation("1"), newBatchMutation("2")}
+	if chunks := w.split(batches); len(chunks) != 2 || len(chunks[0]) != 2 || len(chunks[1]) != 1 {
+		t.Errorf("unexpected chunks by rows - %v", chunks)
+	}
+
+	w.chunkRows = 10
+	w.chunkSize = Size(newBatchMutation("0"))
+	if chunks := w.split(batches); len(chunks) != 3 {
+		t.Errorf("unexpected chunks by size - %v", chunks)
+	}
+}
@@ -0,0 +1,371 @@
+package cache
+
+import (
+	"container/list"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+	"github.com/popeyeio/gohbase/pool"
+)
+
+const (
+	defaultCapacity = 10000
+	defaultTTL      = time.Minute
+
+	// epochStripes is the number of stripes rows are hashed into to detect
+	// writes which race with reads.
+	epochStripes = 256
+)
+
+// Cache is a read-through cache of rows, installed in an executor as an
+// interceptor. It caches the results of Get, GetRow and GetRowWithColumns, and
+// invalidates the cached results of a row when it is written by MutateRow,
+// MutateRows, MutateRowsTs, DeleteAll, DeleteAllRow, CheckAndPut or an
+// increment through any executor using the cache.
+//
+// Cached results are handed to every caller as is, so callers must not
+// modify them. Writes by other processes are only seen once the entries expire.
+type Cache interface {
+	// Interceptor returns the interceptor to install with
+	// pool.WithExecutorInterceptors, so that hits are served before a
+	// connection is borrowed.
+	Interceptor() pool.Interceptor
+	// Invalidate drops the cached results of a row.
+	Invalidate(table, row string)
+	// InvalidateTable drops the cached results of a table.
+	InvalidateTable(table string)
+	// Purge drops every cached result.
+	Purge()
+	Stats() Stats
+}
+
+// Stats contains statistics of a Cache.
+type Stats struct {
+	// Hits is the total number of reads served from the cache.
+	Hits int64
+	// NegativeHits is the total number of hits on rows cached as missing.
+	NegativeHits int64
+	// Misses is the total number of reads which reached hbase.
+	Misses int64
+	// Evictions is the total number of entries evicted as the cache is full.
+	Evictions int64
+	// Invalidations is the total number of entries dropped by writes or invalidation.
+	Invalidations int64
+	// Size is the number of cached entries.
+	Size int
+}
+
+type cache struct {
+	sync.Mutex
+
+	capacity    int
+	policy      Policy
+	ttl         time.Duration
+	tableTTLs   map[string]time.Duration
+	negativeTTL time.Duration
+
+	entries map[string]*entry
+	rows    map[string]map[*entry]struct{}
+	evictor evictor
+	epochs  [epochStripes]uint64
+
+	stats Stats
+}
+
+var _ Cache = (*cache)(nil)
+
+type entry struct {
+	key       string
+	table     string
+	row       string
+	result    interface{}
+	negative  bool
+	expiresAt time.Time
+
+	// elem is used by lru, index, freq and seq by lfu.
+	elem  *list.Element
+	index int
+	freq  uint64
+	seq   uint64
+}
+
+type Option func(*cache)
+
+// WithCapacity sets the max number of cached entries, 10000 by default.
+func WithCapacity(n int) Option {
+	return func(c *cache) {
+		if n > 0 {
+			c.capacity = n
+		}
+	}
+}
+
+// WithPolicy sets the eviction policy, LRU by default.
+func WithPolicy(policy Policy) Option {
+	return func(c *cache) {
+		if policy == LRU || policy == LFU {
+			c.policy = policy
+		}
+	}
+}
+
+// WithTTL sets how long results are cached, one minute by default.
+func WithTTL(ttl time.Duration) Option {
+	return func(c *cache) {
+		if ttl > 0 {
+			c.ttl = ttl
+		}
+	}
+}
+
+// WithTableTTL sets how long results of table are cached, overriding WithTTL.
+// Zero disables caching of table.
+func WithTableTTL(table string, ttl time.Duration) Option {
+	return func(c *cache) {
+		if ttl >= 0 {
+			c.tableTTLs[table] = ttl
+		}
+	}
+}
+
+// WithNegativeTTL caches missing rows for ttl, zero by default which
+// disables negative caching.
+func WithNegativeTTL(ttl time.Duration) Option {
+	return func(c *cache) {
+		if ttl >= 0 {
+			c.negativeTTL = ttl
+		}
+	}
+}
+
+// New creates a cache. Entries are only invalidated by writes made with the
+// methods of an executor the cache interceptor is installed in. Writes made
+// with a Client, e.g. in Executor.Do, or through other executors are only
+// seen once the entries expire, unless they are invalidated explicitly.
+func New(opts ...Option) Cache {
+	c := &cache{
+		capacity:  defaultCapacity,
+		ttl:       defaultTTL,
+		tableTTLs: make(map[string]time.Duration),
+		entries:   make(map[string]*entry),
+		rows:      make(map[string]map[*entry]struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.evictor = newEvictor(c.policy)
+	return c
+}
+
+func (c *cache) Interceptor() pool.Interceptor {
+	return c.intercept
+}
+
+func (c *cache) Invalidate(table, row string) {
+	c.Lock()
+	c.invalidateRow(table, row)
+	c.Unlock()
+}
+
+func (c *cache) InvalidateTable(table string) {
+	c.Lock()
+	defer c.Unlock()
+
+	for i := range c.epochs {
+		c.epochs[i]++
+	}
+	for _, e := range c.entries {
+		if e.table == table {
+			c.remove(e)
+			c.stats.Invalidations++
+		}
+	}
+}
+
+func (c *cache) Purge() {
+	c.Lock()
+	defer c.Unlock()
+
+	for i := range c.epochs {
+		c.epochs[i]++
+	}
+	c.stats.Invalidations += int64(len(c.entries))
+	c.entries = make(map[string]*entry)
+	c.rows = make(map[string]map[*entry]struct{})
+	c.evictor = newEvictor(c.policy)
+}
+
+func (c *cache) Stats() Stats {
+	c.Lock()
+	defer c.Unlock()
+
+	s := c.stats
+	s.Size = len(c.entries)
+	return s
+}
+
+func (c *cache) intercept(call *pool.Call, next pool.Handler) error {
+	switch call.Method {
+	case "Get", "GetRow", "GetRowWithColumns":
+		return c.read(call, next)
+	case "MutateRow", "MutateRows", "MutateRowsTs", "DeleteAll", "DeleteAllRow", "CheckAndPut",
+		"AtomicIncrement", "Increment", "IncrementRows":
+		// the rows are invalidated even if the write fails, as it may have
+		// been applied partially.
+		err := next(call)
+		c.Lock()
+		if increments, ok := incrementsOf(call); ok {
+			// the increments may be of different tables
+			for _, increment := range increments {
+				if increment != nil {
+					c.invalidateRow(string(increment.Table), string(increment.Row))
+				}
+			}
+		} else {
+			for _, row := range call.Rows {
+				c.invalidateRow(call.Table, row)
+			}
+		}
+		c.Unlock()
+		return err
+	case "DeleteTable":
+		err := next(call)
+		c.InvalidateTable(call.Table)
+		return err
+	}
+	return next(call)
+}
+
+func (c *cache) read(call *pool.Call, next pool.Handler) error {
+	ttl, ok := c.tableTTLs[call.Table]
+	if !ok {
+		ttl = c.ttl
+	}
+	if ttl == 0 || len(call.Rows) != 1 {
+		return next(call)
+	}
+	key, ok := pool.CallKey(call)
+	if !ok {
+		return next(call)
+	}
+	row := call.Rows[0]
+	stripe := stripeOf(call.Table, row)
+
+	c.Lock()
+	if e, ok := c.entries[key]; ok {
+		if now().Before(e.expiresAt) {
+			c.evictor.touch(e)
+			c.stats.Hits++
+			if e.negative {
+				c.stats.NegativeHits++
+			}
+			call.Result = e.result
+			c.Unlock()
+			return nil
+		}
+		c.remove(e)
+	}
+	c.stats.Misses++
+	epoch := c.epochs[stripe]
+	c.Unlock()
+
+	if err := next(call); err != nil {
+		return err
+	}
+
+	negative := isEmpty(call.Result)
+	if negative {
+		if c.negativeTTL == 0 {
+			return nil
+		}
+		ttl = c.negativeTTL
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	// the row was written while reading it, so the result may be stale.
+	if c.epochs[stripe] != epoch {
+		return nil
+	}
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+	for len(c.entries) >= c.capacity {
+		c.remove(c.evictor.victim())
+		c.stats.Evictions++
+	}
+
+	e := &entry{
+		key:       key,
+		table:     call.Table,
+		row:       row,
+		result:    call.Result,
+		negative:  negative,
+		expiresAt: now().Add(ttl),
+	}
+	c.entries[key] = e
+	rk := rowKey(e.table, e.row)
+	if c.rows[rk] == nil {
+		c.rows[rk] = make(map[*entry]struct{})
+	}
+	c.rows[rk][e] = struct{}{}
+	c.evictor.add(e)
+	return nil
+}
+
+func incrementsOf(call *pool.Call) ([]*hbase.TIncrement, bool) {
+	if call.Method != "IncrementRows" || len(call.Args) == 0 {
+		return nil, false
+	}
+	increments, ok := call.Args[0].([]*hbase.TIncrement)
+	return increments, ok
+}
+
+// invalidateRow must be called with c locked.
+func (c *cache) invalidateRow(table, row string) {
+	c.epochs[stripeOf(table, row)]++
+	for e := range c.rows[rowKey(table, row)] {
+		c.remove(e)
+		c.stats.Invalidations++
+	}
+}
+
+// remove must be called with c locked.
+func (c *cache) remove(e *entry) {
+	delete(c.entries, e.key)
+	rk := rowKey(e.table, e.row)
+	if es := c.rows[rk]; es != nil {
+		delete(es, e)
+		if len(es) == 0 {
+			delete(c.rows, rk)
+		}
+	}
+	c.evictor.remove(e)
+}
+
+var now = time.Now
+
+func rowKey(table, row string) string {
+	return table + "\x00" + row
+}
+
+func stripeOf(table, row string) int {
+	h := fnv.New32a()
+	h.Write([]byte(table))
+	h.Write([]byte{0})
+	h.Write([]byte(row))
+	return int(h.Sum32() % epochStripes)
+}
+
+func isEmpty(result interface{}) bool {
+	switch result := result.(type) {
+	case []*hbase.TCell:
+		return len(result) == 0
+	case []*hbase.TRowResult_:
+		return len(result) == 0
+	}
+	return result == nil
+}
@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+	"github.com/popeyeio/gohbase/pool"
+)
+
+type fakeHBase struct {
+	rows  map[string]string
+	reads int
+}
+
+func (f *fakeHBase) handle(call *pool.Call) error {
+	switch call.Method {
+	case "GetRow":
+		f.reads++
+		var rsp []*hbase.TRowResult_
+		if v, ok := f.rows[call.Rows[0]]; ok {
+			rsp = []*hbase.TRowResult_{{Row: hbase.Text(call.Rows[0]), Columns: map[string]*hbase.TCell{"f:v": {Value: hbase.Bytes(v)}}}}
+		}
+		call.Result = rsp
+	case "MutateRow":
+		f.rows[call.Rows[0]] = "new"
+	}
+	return nil
+}
+
+func getRow(c Cache, f *fakeHBase, row string) []*hbase.TRowResult_ {
+	call := &pool.Call{Method: "GetRow", Table: "t", Rows: []string{row}, Args: []interface{}{"t", row}}
+	c.Interceptor()(call, f.handle)
+	rsp, _ := call.Result.([]*hbase.TRowResult_)
+	return rsp
+}
+
+func TestCache_Invalidation(t *testing.T) {
+	f := &fakeHBase{rows: map[string]string{"r": "old"}}
+	c := New()
+
+	getRow(c, f, "r")
+	rsp := getRow(c, f, "r")
+	if f.reads != 1 || string(rsp[0].Columns["f:v"].Value) != "old" {
+		t.Fatalf("expected a cache hit, got %d reads", f.reads)
+	}
+
+	c.Interceptor()(&pool.Call{Method: "MutateRow", Table: "t", Rows: []string{"r"}}, f.handle)
+	rsp = getRow(c, f, "r")
+	if f.reads != 2 || string(rsp[0].Columns["f:v"].Value) != "new" {
+		t.Errorf("expected the write to invalidate the row, got %d reads", f.reads)
+	}
+	if s := c.Stats(); s.Hits != 1 || s.Misses != 2 || s.Invalidations != 1 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+func TestCache_Negative(t *testing.T) {
+	f := &fakeHBase{rows: map[string]string{}}
+
+	c := New()
+	getRow(c, f, "missing")
+	getRow(c, f, "missing")
+	if f.reads != 2 {
+		t.Errorf("missing rows must not be cached by default")
+	}
+
+	c = New(WithNegativeTTL(defaultTTL))
+	getRow(c, f, "missing")
+	getRow(c, f, "missing")
+	if s := c.Stats(); s.NegativeHits != 1 {
+		t.Errorf("expected a negative hit, got %+v", s)
+	}
+}
+
+func TestCache_Eviction(t *testing.T) {
+	f := &fakeHBase{rows: map[string]string{"a": "1", "b": "2", "c": "3"}}
+
+	c := New(WithCapacity(2), WithPolicy(LFU))
+	getRow(c, f, "a")
+	getRow(c, f, "a")
+	getRow(c, f, "b")
+	getRow(c, f, "c")
+	reads := f.reads
+	getRow(c, f, "a")
+	if f.reads != reads {
+		t.Errorf("LFU must keep the most frequently used row")
+	}
+
+	c = New(WithCapacity(2), WithPolicy(LRU))
+	getRow(c, f, "a")
+	getRow(c, f, "b")
+	getRow(c, f, "a")
+	getRow(c, f, "c")
+	reads = f.reads
+	getRow(c, f, "b")
+	if f.reads != reads+1 {
+		t.Errorf("LRU must evict the least recently used row")
+	}
+	if s := c.Stats(); s.Evictions != 2 || s.Size != 2 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+func TestCache_IncrementRows(t *testing.T) {
+	f := &fakeHBase{rows: map[string]string{"r": "v", "s": "v"}}
+	c := New()
+
+	getRow(c, f, "r")
+	c.Interceptor()(&pool.Call{Method: "GetRow", Table: "u", Rows: []string{"s"}, Args: []interface{}{"u", "s"}}, f.handle)
+
+	increments := []*hbase.TIncrement{
+		{Table: hbase.Text("t"), Row: hbase.Text("r")},
+		{Table: hbase.Text("u"), Row: hbase.Text("s")},
+	}
+	c.Interceptor()(&pool.Call{
+		Method: "IncrementRows",
+		Table:  "t",
+		Rows:   []string{"r", "s"},
+		Args:   []interface{}{increments},
+	}, f.handle)
+	if s := c.Stats(); s.Invalidations != 2 || s.Size != 0 {
+		t.Errorf("expected the rows of every table to be invalidated, got %+v", s)
+	}
+}
+
+type rowClient struct {
+	pool.Client
+}
+
+func (c *rowClient) GetRow(name, row string, attributes map[string]string) ([]*hbase.TRowResult_, error) {
+	return []*hbase.TRowResult_{{Row: hbase.Text(row)}}, nil
+}
+
+func (c *rowClient) Close() error {
+	return nil
+}
+
+type countingPool struct {
+	pool.Pool
+	gets int
+}
+
+func (p *countingPool) GetContext(context.Context) (pool.Client, error) {
+	p.gets++
+	return &rowClient{}, nil
+}
+
+func TestCache_Executor(t *testing.T) {
+	c := New()
+	p := &countingPool{}
+	e := pool.NewExecutor(p, pool.WithExecutorInterceptors(c.Interceptor()))
+
+	for i := 0; i < 3; i++ {
+		if rsp, err := e.GetRow("t", "r", nil); err != nil || len(rsp) != 1 {
+			t.Fatalf("unexpected result %v, %v", rsp, err)
+		}
+	}
+	// hits do not borrow a connection
+	if p.gets != 1 {
+		t.Errorf("expected 1 connection borrowed, got %d", p.gets)
+	}
+	if s := c.Stats(); s.Hits != 2 || s.Misses != 1 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"container/heap"
+	"container/list"
+)
+
+// Policy decides which entry is evicted once the cache is full.
+type Policy int
+
+const (
+	// LRU evicts the least recently used entry.
+	LRU Policy = iota
+	// LFU evicts the least frequently used entry, and the least recently
+	// added one among the entries used equally often.
+	LFU
+)
+
+// evictor keeps track of the entries in the order of eviction.
+type evictor interface {
+	add(*entry)
+	touch(*entry)
+	remove(*entry)
+	victim() *entry
+}
+
+func newEvictor(policy Policy) evictor {
+	if policy == LFU {
+		return &lfu{}
+	}
+	return &lru{l: list.New()}
+}
+
+type lru struct {
+	l *list.List
+}
+
+func (p *lru) add(e *entry) {
+	e.elem = p.l.PushFront(e)
+}
+
+func (p *lru) touch(e *entry) {
+	p.l.MoveToFront(e.elem)
+}
+
+func (p *lru) remove(e *entry) {
+	p.l.Remove(e.elem)
+}
+
+func (p *lru) victim() *entry {
+	if back := p.l.Back(); back != nil {
+		return back.Value.(*entry)
+	}
+	return nil
+}
+
+// lfu is a min-heap of entries ordered by their hits.
+type lfu struct {
+	entries []*entry
+	seq     uint64
+}
+
+func (p *lfu) add(e *entry) {
+	p.seq++
+	e.seq = p.seq
+	heap.Push(p, e)
+}
+
+func (p *lfu) touch(e *entry) {
+	e.freq++
+	heap.Fix(p, e.index)
+}
+
+func (p *lfu) remove(e *entry) {
+	heap.Remove(p, e.index)
+}
+
+func (p *lfu) victim() *entry {
+	if len(p.entries) == 0 {
+		return nil
+	}
+	return p.entries[0]
+}
+
+func (p *lfu) Len() int {
+	return len(p.entries)
+}
+
+func (p *lfu) Less(i, j int) bool {
+	a, b := p.entries[i], p.entries[j]
+	if a.freq != b.freq {
+		return a.freq < b.freq
+	}
+	return a.seq < b.seq
+}
+
+func (p *lfu) Swap(i, j int) {
+	p.entries[i], p.entries[j] = p.entries[j], p.entries[i]
+	p.entries[i].index = i
+	p.entries[j].index = j
+}
+
+func (p *lfu) Push(x interface{}) {
+	e := x.(*entry)
+	e.index = len(p.entries)
+	p.entries = append(p.entries, e)
+}
+
+func (p *lfu) Pop() interface{} {
+	n := len(p.entries)
+	e := p.entries[n-1]
+	p.entries[n-1] = nil
+	p.entries = p.entries[:n-1]
+	return e
+}
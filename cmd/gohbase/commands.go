@@ -0,0 +1,445 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+	"github.com/popeyeio/gohbase/scan"
+)
+
+const (
+	scanBatchSize  = 256
+	countBatchSize = 1000
+	countFilter    = "FirstKeyOnlyFilter() AND KeyOnlyFilter()"
+)
+
+var errLimit = errors.New("limit reached")
+
+func (s *shell) list(ctx context.Context, args []string) error {
+	if len(args) > 1 {
+		return errUsage
+	}
+	var re *regexp.Regexp
+	if len(args) == 1 {
+		var err error
+		if re, err = regexp.Compile(args[0]); err != nil {
+			return err
+		}
+	}
+
+	names, err := s.e.GetTableNames()
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	p := s.printer("table")
+	for _, name := range names {
+		if re == nil || re.MatchString(name) {
+			if err = p.print(escape(name)); err != nil {
+				return err
+			}
+		}
+	}
+	return p.flush()
+}
+
+func (s *shell) describe(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	table := args[0]
+
+	enabled, err := s.e.IsTableEnabled(table)
+	if err != nil {
+		return err
+	}
+	descriptors, err := s.e.GetColumnDescriptors(table)
+	if err != nil {
+		return err
+	}
+
+	families := make([]string, 0, len(descriptors))
+	for family := range descriptors {
+		families = append(families, family)
+	}
+	sort.Strings(families)
+
+	p := s.printer("family", "enabled", "versions", "ttl", "compression", "bloom", "inmemory", "blockcache")
+	for _, family := range families {
+		d := descriptors[family]
+		if err = p.print(escape(strings.TrimSuffix(family, ":")), enabled, d.MaxVersions, d.TimeToLive,
+			d.Compression, d.BloomFilterType, d.InMemory, d.BlockCacheEnabled); err != nil {
+			return err
+		}
+	}
+	return p.flush()
+}
+
+func (s *shell) create(ctx context.Context, args []string) error {
+	if len(args) < 2 || strings.Contains(args[1], "=") {
+		return errUsage
+	}
+	table := args[0]
+
+	var descriptors []*hbase.ColumnDescriptor
+	for _, arg := range args[1:] {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			d := hbase.NewColumnDescriptor()
+			d.Name = hbase.Text(strings.TrimSuffix(arg, ":") + ":")
+			d.BlockCacheEnabled = true
+			descriptors = append(descriptors, d)
+			continue
+		}
+		if err := setFamilyOption(descriptors[len(descriptors)-1], key, value); err != nil {
+			return err
+		}
+	}
+	return s.e.CreateTable(table, descriptors)
+}
+
+func setFamilyOption(d *hbase.ColumnDescriptor, key, value string) error {
+	var err error
+	switch strings.ToLower(key) {
+	case "versions":
+		var n int64
+		n, err = strconv.ParseInt(value, 10, 32)
+		d.MaxVersions = int32(n)
+	case "ttl":
+		var n int64
+		n, err = strconv.ParseInt(value, 10, 32)
+		d.TimeToLive = int32(n)
+	case "compression":
+		d.Compression = strings.ToUpper(value)
+	case "bloom":
+		d.BloomFilterType = strings.ToUpper(value)
+	case "inmemory":
+		d.InMemory, err = strconv.ParseBool(value)
+	case "blockcache":
+		d.BlockCacheEnabled, err = strconv.ParseBool(value)
+	default:
+		return fmt.Errorf("unknown family option %q", key)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s %q", key, value)
+	}
+	return nil
+}
+
+func (s *shell) enable(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	return s.e.EnableTable(args[0])
+}
+
+func (s *shell) disable(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	return s.e.DisableTable(args[0])
+}
+
+func (s *shell) drop(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	enabled, err := s.e.IsTableEnabled(args[0])
+	if err != nil {
+		return err
+	}
+	if enabled {
+		return fmt.Errorf("table %s is enabled, disable it first", escape(args[0]))
+	}
+	return s.e.DeleteTable(args[0])
+}
+
+func (s *shell) get(ctx context.Context, args []string) error {
+	if len(args) < 2 {
+		return errUsage
+	}
+	table, row, columns := args[0], args[1], args[2:]
+
+	var (
+		rows []*hbase.TRowResult_
+		err  error
+	)
+	if len(columns) == 0 {
+		rows, err = s.e.GetRow(table, row, nil)
+	} else {
+		rows, err = s.e.GetRowWithColumns(table, row, columns, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	p := cellPrinter(s)
+	if err = printRows(p, rows); err != nil {
+		return err
+	}
+	return p.flush()
+}
+
+func (s *shell) put(ctx context.Context, args []string) error {
+	if len(args) < 4 || len(args)%2 != 0 {
+		return errUsage
+	}
+	table, row := args[0], args[1]
+
+	var mutations []*hbase.Mutation
+	for i := 2; i < len(args); i += 2 {
+		mutations = append(mutations, &hbase.Mutation{
+			Column:     hbase.Text(args[i]),
+			Value:      hbase.Text(args[i+1]),
+			WriteToWAL: true,
+		})
+	}
+	return s.e.MutateRow(table, row, mutations, nil)
+}
+
+func (s *shell) delete(ctx context.Context, args []string) error {
+	if len(args) < 2 {
+		return errUsage
+	}
+	table, row, columns := args[0], args[1], args[2:]
+
+	if len(columns) == 0 {
+		return s.e.DeleteAllRow(table, row, nil)
+	}
+	for _, column := range columns {
+		if err := s.e.DeleteAll(table, row, column, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *shell) incr(ctx context.Context, args []string) error {
+	if len(args) != 3 && len(args) != 4 {
+		return errUsage
+	}
+
+	amount := int64(1)
+	if len(args) == 4 {
+		var err error
+		if amount, err = strconv.ParseInt(args[3], 10, 64); err != nil {
+			return fmt.Errorf("invalid amount %q", args[3])
+		}
+	}
+
+	value, err := s.e.AtomicIncrement(args[0], args[1], args[2], amount)
+	if err != nil {
+		return err
+	}
+
+	p := s.printer("value")
+	if err = p.print(value); err != nil {
+		return err
+	}
+	return p.flush()
+}
+
+func (s *shell) regions(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	regions, err := s.e.GetTableRegions(args[0])
+	if err != nil {
+		return err
+	}
+
+	p := s.printer("start", "end", "server", "port", "id", "name")
+	for _, r := range regions {
+		if r == nil {
+			continue
+		}
+		if err = p.print(escape(string(r.StartKey)), escape(string(r.EndKey)), string(r.ServerName),
+			r.Port, r.Id, escape(string(r.Name))); err != nil {
+			return err
+		}
+	}
+	return p.flush()
+}
+
+// scanArgs are the options of scan and count.
+type scanArgs struct {
+	table   string
+	r       scan.Range
+	columns []string
+	filter  string
+	limit   int
+	reverse bool
+}
+
+func parseScanArgs(args []string, allowed ...string) (*scanArgs, error) {
+	if len(args) == 0 || strings.Contains(args[0], "=") {
+		return nil, errUsage
+	}
+
+	sa := &scanArgs{table: args[0]}
+	var prefix *string
+	for _, arg := range args[1:] {
+		key, value, _ := strings.Cut(arg, "=")
+		key = strings.ToLower(key)
+		if !contains(allowed, key) {
+			return nil, fmt.Errorf("unknown option %q", arg)
+		}
+
+		switch key {
+		case "start":
+			sa.r.Start = value
+		case "stop":
+			sa.r.Stop = value
+		case "prefix":
+			prefix = &value
+		case "columns":
+			sa.columns = strings.Split(value, ",")
+		case "filter":
+			sa.filter = value
+		case "limit":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid limit %q", value)
+			}
+			sa.limit = n
+		case "reverse":
+			sa.reverse = true
+		}
+	}
+
+	if prefix != nil {
+		sa.r = scan.Prefix(*prefix)
+		if sa.reverse {
+			// scan down from the first row after the prefix, dropping it
+			// and the rows before the prefix with a filter.
+			sa.r = scan.Range{Start: sa.r.Stop}
+			sa.filter = and("PrefixFilter('"+strings.ReplaceAll(*prefix, "'", "''")+"')", sa.filter)
+		}
+	}
+	return sa, nil
+}
+
+func (s *shell) scan(ctx context.Context, args []string) error {
+	sa, err := parseScanArgs(args, "start", "stop", "prefix", "columns", "filter", "limit", "reverse")
+	if err != nil {
+		return err
+	}
+
+	batchSize := scanBatchSize
+	if sa.limit > 0 && sa.limit < batchSize {
+		batchSize = sa.limit
+	}
+	opts := []scan.Option{scan.WithBatchSize(batchSize), scan.WithColumns(sa.columns...), scan.WithFilter(sa.filter)}
+	if sa.reverse {
+		opts = append(opts, scan.WithReversed())
+	}
+
+	p := cellPrinter(s)
+	n := 0
+	err = scan.Scan(ctx, s.e, sa.table, sa.r, func(rows []*hbase.TRowResult_) error {
+		if sa.limit > 0 && n+len(rows) > sa.limit {
+			rows = rows[:sa.limit-n]
+		}
+		n += len(rows)
+		if err := printRows(p, rows); err != nil {
+			return err
+		}
+		if err := p.flush(); err != nil {
+			return err
+		}
+		if n == sa.limit {
+			return errLimit
+		}
+		return nil
+	}, opts...)
+	if err != nil && err != errLimit {
+		return err
+	}
+
+	if s.format == formatTable {
+		fmt.Fprintf(s.errOut, "%d row(s)\n", n)
+	}
+	return nil
+}
+
+func (s *shell) count(ctx context.Context, args []string) error {
+	sa, err := parseScanArgs(args, "start", "stop", "prefix", "filter")
+	if err != nil {
+		return err
+	}
+
+	// only the first cell of every row is needed, unless filtering by
+	// other cells.
+	filter := sa.filter
+	if filter == "" {
+		filter = countFilter
+	}
+
+	n := 0
+	err = scan.Scan(ctx, s.e, sa.table, sa.r, func(rows []*hbase.TRowResult_) error {
+		n += len(rows)
+		return nil
+	}, scan.WithBatchSize(countBatchSize), scan.WithFilter(filter))
+	if err != nil {
+		return err
+	}
+
+	p := s.printer("rows")
+	if err = p.print(n); err != nil {
+		return err
+	}
+	return p.flush()
+}
+
+func cellPrinter(s *shell) printer {
+	return s.printer("row", "column", "timestamp", "value")
+}
+
+// printRows prints every cell of rows, ordered by column.
+func printRows(p printer, rows []*hbase.TRowResult_) error {
+	for _, r := range rows {
+		columns := scan.Columns(r)
+		names := make([]string, 0, len(columns))
+		for name := range columns {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			cell := columns[name]
+			if cell == nil {
+				continue
+			}
+			if err := p.print(escape(string(r.Row)), escape(name), cell.Timestamp, escape(string(cell.Value))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// and combines filter strings.
+func and(a, b string) string {
+	if a == "" || b == "" {
+		return a + b
+	}
+	return "(" + a + ") AND (" + b + ")"
+}
+
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/popeyeio/gohbase/internal/hbasetest"
+)
+
+// run runs line on a shell printing json, and returns the printed records.
+func run(t *testing.T, e *hbasetest.Table, line string) []map[string]interface{} {
+	t.Helper()
+
+	var out bytes.Buffer
+	s := &shell{e: e, out: &out, errOut: &out, format: formatJSON}
+	if err := s.exec(context.Background(), line); err != nil {
+		t.Fatalf("%s: %v", line, err)
+	}
+
+	var records []map[string]interface{}
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var r map[string]interface{}
+		if err := dec.Decode(&r); err != nil {
+			t.Fatalf("%s: invalid output - %v", line, err)
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+// rows returns the row of every record.
+func rows(records []map[string]interface{}) []string {
+	var rows []string
+	for _, r := range records {
+		rows = append(rows, r["row"].(string))
+	}
+	return rows
+}
+
+func TestShell_PutGet(t *testing.T) {
+	e := hbasetest.NewTable()
+	run(t, e, `put t "row 1" f:a "x y" f:b \x00\\`)
+
+	records := run(t, e, `get t "row 1"`)
+	if len(records) != 2 || records[0]["column"] != "f:a" || records[0]["value"] != "x y" ||
+		records[1]["column"] != "f:b" || records[1]["value"] != `\x00\\` {
+		t.Errorf("unexpected cells %v", records)
+	}
+	if v := e.Cells()["row 1"]["f:b"]; len(v) != 1 || string(v[0].Value) != "\x00\\" {
+		t.Errorf("value is not unescaped, got %v", v)
+	}
+
+	if records = run(t, e, `get t "row 1" f:b`); len(records) != 1 || records[0]["column"] != "f:b" {
+		t.Errorf("unexpected cells of column f:b %v", records)
+	}
+}
+
+func TestShell_Scan(t *testing.T) {
+	e := hbasetest.NewTable()
+	for _, row := range []string{"a", "b1", "b2", "b3", "c"} {
+		e.Put(row, "f:q", 1, row)
+	}
+
+	if got := rows(run(t, e, "scan t limit=2")); !reflect.DeepEqual(got, []string{"a", "b1"}) {
+		t.Errorf("unexpected rows of a limited scan %v", got)
+	}
+	if got := rows(run(t, e, "scan t prefix=b reverse")); !reflect.DeepEqual(got, []string{"b3", "b2", "b1"}) {
+		t.Errorf("unexpected rows of a reversed prefix scan %v", got)
+	}
+	if got := rows(run(t, e, "scan t prefix=b reverse limit=1")); !reflect.DeepEqual(got, []string{"b3"}) {
+		t.Errorf("unexpected rows of a limited reversed prefix scan %v", got)
+	}
+}
+
+func TestShell_Count(t *testing.T) {
+	e := hbasetest.NewTable()
+	for _, row := range []string{"a", "b1", "b2", "c"} {
+		e.Put(row, "f:q", 1, row)
+	}
+
+	for line, n := range map[string]float64{"count t": 4, "count t prefix=b": 2, "count t start=b2": 2} {
+		if records := run(t, e, line); len(records) != 1 || records[0]["rows"] != n {
+			t.Errorf("%s: expected %v rows, got %v", line, n, records)
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	args := []string{"t", `a\b`, `f:"q"`, `c\`, "d e"}
+	words, err := split(strings.Join(quote(args), " "))
+	if err != nil {
+		t.Fatalf("split error - %v", err)
+	}
+	for i, word := range words {
+		if words[i], err = unescape(word); err != nil {
+			t.Fatalf("unescape error - %v", err)
+		}
+	}
+	if !reflect.DeepEqual(words, args) {
+		t.Errorf("quoted arguments are split as %q, want %q", words, args)
+	}
+}
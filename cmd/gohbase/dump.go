@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/popeyeio/gohbase/dump"
+)
+
+// dumpOptions parses the options of export and import. The format is taken
+// from the extension of file unless given.
+func dumpOptions(file string, args []string, allowed ...string) ([]dump.Option, error) {
+	format := dump.FormatJSONL
+	if strings.EqualFold(filepath.Ext(file), ".csv") {
+		format = dump.FormatCSV
+	}
+
+	var opts []dump.Option
+	for _, arg := range args {
+		key, value, _ := strings.Cut(arg, "=")
+		key = strings.ToLower(key)
+		if !contains(allowed, key) {
+			return nil, fmt.Errorf("unknown option %q", arg)
+		}
+
+		var err error
+		switch key {
+		case "format":
+			format, err = dump.ParseFormat(value)
+		case "encoding":
+			var enc dump.Encoding
+			enc, err = dump.ParseEncoding(value)
+			opts = append(opts, dump.WithEncoding(enc))
+		case "start", "stop":
+			// set below, as they make a range together
+		case "prefix":
+			opts = append(opts, dump.WithPrefix(value))
+		case "columns":
+			opts = append(opts, dump.WithColumns(strings.Split(value, ",")...))
+		case "filter":
+			opts = append(opts, dump.WithFilter(value))
+		case "versions", "batch":
+			var n int
+			if n, err = strconv.Atoi(value); err == nil && n <= 0 {
+				err = fmt.Errorf("invalid %s %q", key, value)
+			}
+			if key == "versions" {
+				opts = append(opts, dump.WithVersions(n))
+			} else {
+				opts = append(opts, dump.WithBatchSize(n))
+			}
+		case "rate":
+			var rate float64
+			rate, err = strconv.ParseFloat(value, 64)
+			opts = append(opts, dump.WithRate(rate))
+		case "resume":
+			var position int64
+			position, err = strconv.ParseInt(value, 10, 64)
+			opts = append(opts, dump.WithResume(position))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q", key, value)
+		}
+	}
+
+	start, stop := optionValue(args, "start"), optionValue(args, "stop")
+	if start != "" || stop != "" {
+		opts = append(opts, dump.WithRange(start, stop))
+	}
+	return append(opts, dump.WithFormat(format)), nil
+}
+
+func optionValue(args []string, key string) string {
+	for _, arg := range args {
+		if k, v, ok := strings.Cut(arg, "="); ok && strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
+
+func (s *shell) export(ctx context.Context, args []string) error {
+	if len(args) < 2 {
+		return errUsage
+	}
+	table, file := args[0], args[1]
+
+	opts, err := dumpOptions(file, args[2:], "format", "encoding", "start", "stop", "prefix", "columns", "filter", "versions", "batch")
+	if err != nil {
+		return err
+	}
+
+	var f *os.File
+	w := s.out
+	if file != "-" {
+		if f, err = os.Create(file); err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	stats, err := dump.Export(ctx, s.e, table, w, opts...)
+	if err == nil && f != nil {
+		err = f.Close()
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(s.errOut, "exported %d row(s), %d cell(s)\n", stats.Rows, stats.Cells)
+	return nil
+}
+
+func (s *shell) importFile(ctx context.Context, args []string) error {
+	if len(args) < 2 {
+		return errUsage
+	}
+	table, file := args[0], args[1]
+
+	opts, err := dumpOptions(file, args[2:], "format", "encoding", "batch", "rate", "resume")
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	stats, err := dump.Import(ctx, s.e, table, f, opts...)
+	if err != nil {
+		return fmt.Errorf("%v, resume with resume=%d", err, stats.Position)
+	}
+	fmt.Fprintf(s.errOut, "imported %d row(s), %d cell(s)\n", stats.Rows, stats.Cells)
+	return nil
+}
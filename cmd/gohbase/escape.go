@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var errUnterminatedQuote = errors.New("unterminated quote")
+
+// escape escapes the bytes of s which are not printable ascii as \xNN, so
+// that binary row keys and values print on a line and may be passed back to
+// commands as is.
+func escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			b.WriteString(`\\`)
+		case c < 0x80 && unicode.IsPrint(rune(c)):
+			b.WriteByte(c)
+		default:
+			b.WriteString(`\x`)
+			b.WriteString(strconv.FormatUint(uint64(c)>>4, 16))
+			b.WriteString(strconv.FormatUint(uint64(c)&0xf, 16))
+		}
+	}
+	return b.String()
+}
+
+// unescape reverses escape, and also accepts \n, \r, \t and \0.
+func unescape(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i++; i == len(s) {
+			return "", errors.New("trailing backslash in " + strconv.Quote(s))
+		}
+
+		switch s[i] {
+		case 'x':
+			if i+2 >= len(s) {
+				return "", errors.New("invalid escape in " + strconv.Quote(s))
+			}
+			n, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+			if err != nil {
+				return "", errors.New("invalid escape in " + strconv.Quote(s))
+			}
+			b.WriteByte(byte(n))
+			i += 2
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case '0':
+			b.WriteByte(0)
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// split splits a command line into words separated by spaces. Quotes group
+// words with spaces, and escapes are kept for unescape.
+func split(line string) ([]string, error) {
+	var (
+		words  []string
+		word   strings.Builder
+		inWord bool
+		quote  byte
+	)
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+				continue
+			}
+			if c == '\\' && i+1 < len(line) {
+				word.WriteByte(c)
+				i++
+				c = line[i]
+			}
+			word.WriteByte(c)
+		case c == '\'' || c == '"':
+			quote = c
+			inWord = true
+		case c == ' ' || c == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			if c == '\\' && i+1 < len(line) {
+				word.WriteByte(c)
+				i++
+				c = line[i]
+			}
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, errUnterminatedQuote
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEscape(t *testing.T) {
+	for _, s := range []string{"", "row1", "a b", "\x00\xff\\x", "é"} {
+		got, err := unescape(escape(s))
+		if err != nil || got != s {
+			t.Errorf("unescape(escape(%q)) = %q, %v", s, got, err)
+		}
+	}
+	if got := escape("k\x00\\"); got != `k\x00\\` {
+		t.Errorf("escape = %s", got)
+	}
+	if _, err := unescape(`\x0`); err == nil {
+		t.Error("expected error for a short escape")
+	}
+}
+
+func TestSplit(t *testing.T) {
+	words, err := split(`put t "row 1" 'f:q' "say \"hi\"" \x00`)
+	if err != nil {
+		t.Fatalf("split error - %v", err)
+	}
+	want := []string{"put", "t", "row 1", "f:q", `say \"hi\"`, `\x00`}
+	if !reflect.DeepEqual(words, want) {
+		t.Errorf("split = %q, want %q", words, want)
+	}
+
+	if _, err = split(`get t "row`); err != errUnterminatedQuote {
+		t.Errorf("expected errUnterminatedQuote, got %v", err)
+	}
+}
+
+func TestParseScanArgs(t *testing.T) {
+	sa, err := parseScanArgs([]string{"t", "prefix=ab", "limit=10"}, "prefix", "limit", "reverse")
+	if err != nil {
+		t.Fatalf("parseScanArgs error - %v", err)
+	}
+	if sa.r.Start != "ab" || sa.r.Stop != "ac" || sa.limit != 10 || sa.filter != "" {
+		t.Errorf("unexpected args %+v", sa)
+	}
+
+	sa, err = parseScanArgs([]string{"t", "prefix=a'b", "reverse"}, "prefix", "limit", "reverse")
+	if err != nil {
+		t.Fatalf("parseScanArgs error - %v", err)
+	}
+	if sa.r.Start != "a'c" || sa.r.Stop != "" || sa.filter != "PrefixFilter('a''b')" {
+		t.Errorf("unexpected reverse args %+v", sa)
+	}
+
+	if _, err = parseScanArgs([]string{"t", "columns=f"}, "prefix"); err == nil {
+		t.Error("expected error for an option not allowed")
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bufio"
+	"os"
+)
+
+const maxHistory = 1000
+
+// history records the command lines entered in the shell, and appends them
+// to a file so that they are kept across sessions.
+type history struct {
+	lines []string
+	f     *os.File
+}
+
+// openHistory loads the history of the file at path, which is created if it
+// does not exist. An empty path keeps the history in memory only.
+func openHistory(path string) (*history, error) {
+	h := &history{}
+	if path == "" {
+		return h, nil
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		h.push(scanner.Text())
+	}
+	if err = scanner.Err(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	h.f = f
+	return h, nil
+}
+
+func (h *history) push(line string) {
+	h.lines = append(h.lines, line)
+	if len(h.lines) > maxHistory {
+		h.lines = h.lines[len(h.lines)-maxHistory:]
+	}
+}
+
+func (h *history) add(line string) error {
+	if n := len(h.lines); n > 0 && h.lines[n-1] == line {
+		return nil
+	}
+	h.push(line)
+	if h.f == nil {
+		return nil
+	}
+	_, err := h.f.WriteString(line + "\n")
+	return err
+}
+
+// line returns the n-th line of the history, counting from 1.
+func (h *history) line(n int) (string, bool) {
+	if n < 1 || n > len(h.lines) {
+		return "", false
+	}
+	return h.lines[n-1], true
+}
+
+func (h *history) close() error {
+	if h.f == nil {
+		return nil
+	}
+	return h.f.Close()
+}
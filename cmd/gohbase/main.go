@@ -0,0 +1,136 @@
+// Command gohbase is a shell for hbase, connected through the thrift servers
+// at -addrs. It runs the command given as arguments and exits, or else reads
+// commands from stdin; see the help command.
+//
+// Usage:
+//
+//	gohbase -addrs host:port[,host:port] [flags] [command [args...]]
+//
+// It exits with status 1 if the command fails, and 2 on invalid flags.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/popeyeio/gohbase/lib/thrift"
+	"github.com/popeyeio/gohbase/pool"
+)
+
+func main() {
+	var (
+		addrs     = flag.String("addrs", "localhost:9090", "comma separated addresses of the thrift servers")
+		timeout   = flag.Duration("timeout", time.Second*10, "socket timeout")
+		transport = flag.String("transport", "buffered", "thrift transport, buffered or framed")
+		protocol  = flag.String("protocol", "binary", "thrift protocol, binary, compact or json")
+		format    = flag.String("format", formatTable, "output format, table or json")
+		history   = flag.String("history", defaultHistory(), "file to keep the command history in, none if empty")
+	)
+	flag.Parse()
+
+	opts, err := poolOptions(*addrs, *timeout, *transport, *protocol)
+	if err == nil && *format != formatTable && *format != formatJSON {
+		err = fmt.Errorf("unknown format %q", *format)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	p := pool.NewPool(opts...)
+	defer p.Close()
+
+	s := &shell{
+		e:      pool.NewExecutor(p),
+		out:    os.Stdout,
+		errOut: os.Stderr,
+		format: *format,
+	}
+
+	if flag.NArg() > 0 {
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+
+		s.history, _ = openHistory("")
+		if err = s.exec(ctx, strings.Join(quote(flag.Args()), " ")); err != nil {
+			fmt.Fprintf(os.Stderr, "error - %v\n", err)
+			p.Close()
+			os.Exit(1)
+		}
+		return
+	}
+
+	interactive := isTerminal(os.Stdin)
+	if !interactive {
+		*history = ""
+	}
+	if s.history, err = openHistory(*history); err != nil {
+		fmt.Fprintf(os.Stderr, "open history error - %v\n", err)
+		s.history, _ = openHistory("")
+	}
+	defer s.history.close()
+
+	if err = s.repl(os.Stdin, interactive); err != nil {
+		fmt.Fprintf(os.Stderr, "read error - %v\n", err)
+	}
+}
+
+func poolOptions(addrs string, timeout time.Duration, transport, protocol string) ([]pool.Option, error) {
+	opts := []pool.Option{
+		pool.WithAddrs(strings.Split(addrs, ",")...),
+		pool.WithSocketTimeout(timeout),
+		pool.WithMaxActive(4),
+		pool.WithMaxIdle(4),
+		pool.WithBlockMode(true),
+	}
+
+	switch transport {
+	case "buffered":
+	case "framed":
+		opts = append(opts, pool.WithTransportFactory(thrift.NewTFramedTransportFactory(thrift.NewTTransportFactory())))
+	default:
+		return nil, fmt.Errorf("unknown transport %q", transport)
+	}
+
+	switch protocol {
+	case "binary":
+	case "compact":
+		opts = append(opts, pool.WithProtocolFactory(thrift.NewTCompactProtocolFactory()))
+	case "json":
+		opts = append(opts, pool.WithProtocolFactory(thrift.NewTJSONProtocolFactory()))
+	default:
+		return nil, fmt.Errorf("unknown protocol %q", protocol)
+	}
+	return opts, nil
+}
+
+// quote quotes the arguments of a one-shot command, so that they are split
+// and unescaped back to the arguments as they were given.
+func quote(args []string) []string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = `"` + r.Replace(arg) + `"`
+	}
+	return quoted
+}
+
+func defaultHistory() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".gohbase_history")
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+// printer prints the results of a command as records of named fields.
+type printer interface {
+	// print prints a record of values for the fields of the command.
+	print(values ...interface{}) error
+	// flush writes out the printed records.
+	flush() error
+}
+
+func newPrinter(w io.Writer, format string, fields ...string) printer {
+	if format == formatJSON {
+		return &jsonPrinter{enc: json.NewEncoder(w), fields: fields}
+	}
+	return &tablePrinter{w: tabwriter.NewWriter(w, 0, 4, 2, ' ', 0), fields: fields}
+}
+
+// tablePrinter prints records as the rows of a table aligned in columns.
+type tablePrinter struct {
+	w      *tabwriter.Writer
+	fields []string
+	rows   int
+}
+
+func (p *tablePrinter) print(values ...interface{}) error {
+	if p.rows == 0 {
+		if _, err := fmt.Fprintln(p.w, strings.ToUpper(strings.Join(p.fields, "\t"))); err != nil {
+			return err
+		}
+	}
+	p.rows++
+
+	cols := make([]string, len(values))
+	for i, v := range values {
+		cols[i] = fmt.Sprint(v)
+	}
+	_, err := fmt.Fprintln(p.w, strings.Join(cols, "\t"))
+	return err
+}
+
+func (p *tablePrinter) flush() error {
+	return p.w.Flush()
+}
+
+// jsonPrinter prints records as json objects, one per line.
+type jsonPrinter struct {
+	enc    *json.Encoder
+	fields []string
+}
+
+func (p *jsonPrinter) print(values ...interface{}) error {
+	record := make(map[string]interface{}, len(values))
+	for i, v := range values {
+		record[p.fields[i]] = v
+	}
+	return p.enc.Encode(record)
+}
+
+func (p *jsonPrinter) flush() error {
+	return nil
+}
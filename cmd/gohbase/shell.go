@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/popeyeio/gohbase/pool"
+)
+
+const prompt = "gohbase> "
+
+var errUsage = errors.New("usage")
+
+type shell struct {
+	e       pool.Executor
+	out     io.Writer
+	errOut  io.Writer
+	format  string
+	history *history
+}
+
+type command struct {
+	usage string
+	help  string
+	run   func(s *shell, ctx context.Context, args []string) error
+}
+
+// commands is set up by init, as help refers to it.
+var commands map[string]*command
+
+func init() {
+	commands = map[string]*command{
+		"list":     {"list [regexp]", "lists the tables, or those matching regexp", (*shell).list},
+		"describe": {"describe <table>", "describes the column families of a table", (*shell).describe},
+		"create": {"create <table> <family> [option=value...] [<family> [option=value...]...]",
+			"creates a table, with the options versions, ttl, compression, bloom, inmemory and blockcache per family",
+			(*shell).create},
+		"enable":  {"enable <table>", "enables a table", (*shell).enable},
+		"disable": {"disable <table>", "disables a table", (*shell).disable},
+		"drop":    {"drop <table>", "drops a table, which must be disabled", (*shell).drop},
+		"get":     {"get <table> <row> [column...]", "gets the cells of a row, or of the columns given", (*shell).get},
+		"put": {"put <table> <row> <column> <value> [<column> <value>...]",
+			"puts values into the columns of a row", (*shell).put},
+		"delete": {"delete <table> <row> [column...]", "deletes a row, or the columns given of it", (*shell).delete},
+		"scan": {"scan <table> [start=row] [stop=row] [prefix=row] [columns=c[,c...]] [filter=string] [limit=n] [reverse]",
+			"scans the rows of a table; a reverse scan runs from start down to stop", (*shell).scan},
+		"count": {"count <table> [start=row] [stop=row] [prefix=row] [filter=string]",
+			"counts the rows of a table", (*shell).count},
+		"incr":    {"incr <table> <row> <column> [amount]", "increments a counter column, by 1 by default", (*shell).incr},
+		"regions": {"regions <table>", "lists the regions of a table", (*shell).regions},
+		"export": {"export <table> <file|-> [format=jsonl|csv] [encoding=escape|hex|base64] [start=row] [stop=row] [prefix=row] [columns=c[,c...]] [filter=string] [versions=n] [batch=n]",
+			"exports the cells of a table to a file, in the format of its extension by default", (*shell).export},
+		"import": {"import <table> <file> [format=jsonl|csv] [encoding=escape|hex|base64] [batch=n] [rate=cells/s] [resume=position]",
+			"imports the cells of an exported file into a table", (*shell).importFile},
+		"format":  {"format [table|json]", "shows or sets the output format", (*shell).setFormat},
+		"history": {"history", "lists the command history; !n runs the n-th command again", (*shell).listHistory},
+		"help":    {"help [command]", "describes the commands", (*shell).help},
+	}
+}
+
+// exec runs a command line. The arguments are unescaped, so that binary row
+// keys and values may be given as \xNN.
+func (s *shell) exec(ctx context.Context, line string) error {
+	words, err := split(line)
+	if err != nil || len(words) == 0 {
+		return err
+	}
+
+	cmd, ok := commands[strings.ToLower(words[0])]
+	if !ok {
+		return fmt.Errorf("unknown command %q, see help", words[0])
+	}
+
+	args := make([]string, len(words)-1)
+	for i, word := range words[1:] {
+		if args[i], err = unescape(word); err != nil {
+			return err
+		}
+	}
+
+	if err = cmd.run(s, ctx, args); err == errUsage {
+		return fmt.Errorf("usage: %s", cmd.usage)
+	}
+	return err
+}
+
+// repl reads command lines from in and runs them until exit or the end of
+// in. Commands are interrupted by ctrl-c.
+func (s *shell) repl(in io.Reader, interactive bool) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(nil, 16<<20)
+	for {
+		if interactive {
+			fmt.Fprint(s.out, prompt)
+		}
+		if !scanner.Scan() {
+			break
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if line == "exit" || line == "quit" {
+			return nil
+		}
+		if strings.HasPrefix(line, "!") {
+			n, err := strconv.Atoi(line[1:])
+			prev, ok := s.history.line(n)
+			if err != nil || !ok {
+				fmt.Fprintf(s.errOut, "no command %s in history\n", line)
+				continue
+			}
+			line = prev
+			fmt.Fprintln(s.out, line)
+		}
+		if err := s.history.add(line); err != nil {
+			fmt.Fprintf(s.errOut, "write history error - %v\n", err)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		err := s.exec(ctx, line)
+		stop()
+		if err != nil {
+			fmt.Fprintf(s.errOut, "error - %v\n", err)
+		}
+	}
+
+	if interactive {
+		fmt.Fprintln(s.out)
+	}
+	return scanner.Err()
+}
+
+func (s *shell) printer(fields ...string) printer {
+	return newPrinter(s.out, s.format, fields...)
+}
+
+func (s *shell) setFormat(ctx context.Context, args []string) error {
+	switch {
+	case len(args) == 0:
+		fmt.Fprintln(s.out, s.format)
+		return nil
+	case len(args) > 1:
+		return errUsage
+	case args[0] != formatTable && args[0] != formatJSON:
+		return fmt.Errorf("unknown format %q", args[0])
+	}
+	s.format = args[0]
+	return nil
+}
+
+func (s *shell) listHistory(ctx context.Context, args []string) error {
+	for i, line := range s.history.lines {
+		fmt.Fprintf(s.out, "%5d  %s\n", i+1, line)
+	}
+	return nil
+}
+
+func (s *shell) help(ctx context.Context, args []string) error {
+	if len(args) > 0 {
+		cmd, ok := commands[strings.ToLower(args[0])]
+		if !ok {
+			return fmt.Errorf("unknown command %q", args[0])
+		}
+		fmt.Fprintf(s.out, "%s\n    %s\n", cmd.usage, cmd.help)
+		return nil
+	}
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(s.out, "%-9s %s\n", name, commands[name].help)
+	}
+	fmt.Fprintln(s.out, "\nRow keys, columns and values may contain binary bytes escaped as \\xNN.")
+	return nil
+}
@@ -0,0 +1,111 @@
+// Command hbase-compare compares a row range of a table between two clusters,
+// e.g. to verify replication or a migration, and prints a json report.
+//
+// Usage:
+//
+//	hbase-compare -source host:port[,host:port] -target host:port[,host:port] -table name [flags]
+//
+// It exits with status 1 if the table differs, and 2 on errors.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/popeyeio/gohbase/compare"
+	"github.com/popeyeio/gohbase/pool"
+)
+
+func main() {
+	var (
+		source      = flag.String("source", "", "comma separated addresses of the source thrift servers")
+		target      = flag.String("target", "", "comma separated addresses of the target thrift servers")
+		table       = flag.String("table", "", "table to compare")
+		targetTable = flag.String("target-table", "", "table in the target, the same as -table by default")
+		start       = flag.String("start", "", "first row to compare")
+		stop        = flag.String("stop", "", "row to stop before")
+		columns     = flag.String("columns", "", "comma separated families or columns to compare")
+		timestamps  = flag.Bool("timestamps", false, "compare cell timestamps besides values")
+		sample      = flag.Float64("sample", 0, "compare a random fraction of the rows, e.g. 0.01")
+		concurrency = flag.Int("concurrency", 4, "number of regions compared in parallel")
+		maxDiffs    = flag.Int("max-diffs", 1000, "max number of differences listed in the report")
+		checkpoint  = flag.String("checkpoint", "", "file to record progress in, to resume an interrupted comparison")
+		timeout     = flag.Duration("socket-timeout", time.Second*30, "socket timeout")
+		out         = flag.String("out", "", "file to write the report to, stdout by default")
+	)
+	flag.Parse()
+
+	if *source == "" || *target == "" || *table == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *targetTable == "" {
+		*targetTable = *table
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	sp := newPool(*source, *concurrency*2, *timeout)
+	defer sp.Close()
+	tp := newPool(*target, *concurrency*2, *timeout)
+	defer tp.Close()
+
+	opts := []compare.Option{
+		compare.WithRange(*start, *stop),
+		compare.WithTimestamps(*timestamps),
+		compare.WithConcurrency(*concurrency),
+		compare.WithMaxDiffs(*maxDiffs),
+		compare.WithCheckpoint(*checkpoint),
+		compare.WithTargetTable(*targetTable),
+	}
+	if *columns != "" {
+		opts = append(opts, compare.WithColumns(strings.Split(*columns, ",")...))
+	}
+	if *sample > 0 {
+		opts = append(opts, compare.WithSampling(*sample))
+	}
+
+	report, err := compare.Compare(ctx, pool.NewExecutor(sp), pool.NewExecutor(tp), *table, opts...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "compare error - %v\n", err)
+		os.Exit(2)
+	}
+
+	w := os.Stdout
+	if *out != "" {
+		if w, err = os.Create(*out); err != nil {
+			fmt.Fprintf(os.Stderr, "create report error - %v\n", err)
+			os.Exit(2)
+		}
+		defer w.Close()
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err = enc.Encode(report); err != nil {
+		fmt.Fprintf(os.Stderr, "write report error - %v\n", err)
+		os.Exit(2)
+	}
+
+	fmt.Fprintf(os.Stderr, "rows:%d missing:%d extra:%d different:%d\n",
+		report.Rows, report.Missing, report.Extra, report.Different)
+	if !report.Equal() {
+		os.Exit(1)
+	}
+}
+
+func newPool(addrs string, maxActive int, timeout time.Duration) pool.Pool {
+	return pool.NewPool(
+		pool.WithAddrs(strings.Split(addrs, ",")...),
+		pool.WithSocketTimeout(timeout),
+		pool.WithMaxActive(maxActive),
+		pool.WithMaxIdle(maxActive),
+		pool.WithBlockMode(true),
+	)
+}
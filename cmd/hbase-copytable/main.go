@@ -0,0 +1,155 @@
+// Command hbase-copytable copies a row range of a table from one cluster to
+// another, keeping the timestamps of the cells, and prints the stats as json.
+//
+// Usage:
+//
+//	hbase-copytable -source host:port[,host:port] -target host:port[,host:port] -table name [flags]
+//
+// With -checkpoint, an interrupted copy resumes where every region stopped
+// when run again with the same flags. It exits with status 1 on errors.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/popeyeio/gohbase/copytable"
+	"github.com/popeyeio/gohbase/pool"
+)
+
+func main() {
+	var (
+		source      = flag.String("source", "", "comma separated addresses of the source thrift servers")
+		target      = flag.String("target", "", "comma separated addresses of the target thrift servers")
+		table       = flag.String("table", "", "table to copy")
+		targetTable = flag.String("target-table", "", "table in the target, the same as -table by default")
+		start       = flag.String("start", "", "first row to copy")
+		stop        = flag.String("stop", "", "row to stop before")
+		columns     = flag.String("columns", "", "comma separated families or columns to copy")
+		versions    = flag.Int("versions", 1, "number of versions of every cell to copy")
+		create      = flag.Bool("create", false, "create the target table with the families of the source if it does not exist")
+		rename      = flag.String("rename", "", "comma separated renames of families, old:=new:, or columns, old:q=new:q")
+		stripPrefix = flag.String("strip-prefix", "", "prefix to strip from the row keys")
+		addPrefix   = flag.String("add-prefix", "", "prefix to add to the row keys")
+		concurrency = flag.Int("concurrency", 4, "number of regions copied in parallel")
+		batch       = flag.Int("batch", 256, "number of rows read and written by a call")
+		rate        = flag.Float64("rate", 0, "max number of cells copied per second, unlimited by default")
+		checkpoint  = flag.String("checkpoint", "", "file to record progress in, to resume an interrupted copy")
+		saveEvery   = flag.Duration("checkpoint-interval", time.Second*10, "interval to record progress at")
+		timeout     = flag.Duration("socket-timeout", time.Second*30, "socket timeout")
+		interval    = flag.Duration("progress", time.Second*10, "interval to print progress at, none if 0")
+	)
+	flag.Parse()
+
+	if *source == "" || *target == "" || *table == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	renames, err := parseRenames(*rename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	sp := newPool(*source, *concurrency*2, *timeout)
+	defer sp.Close()
+	tp := newPool(*target, *concurrency*2, *timeout)
+	defer tp.Close()
+
+	opts := []copytable.Option{
+		copytable.WithRange(*start, *stop),
+		copytable.WithTargetTable(*targetTable),
+		copytable.WithVersions(*versions),
+		copytable.WithCreateTable(*create),
+		copytable.WithConcurrency(*concurrency),
+		copytable.WithBatchSize(*batch),
+		copytable.WithRate(*rate),
+		copytable.WithCheckpoint(*checkpoint),
+		copytable.WithCheckpointInterval(*saveEvery),
+	}
+	if *columns != "" {
+		opts = append(opts, copytable.WithColumns(strings.Split(*columns, ",")...))
+	}
+	if len(renames) > 0 {
+		opts = append(opts, copytable.WithColumnRename(func(column string) string {
+			return renameColumn(renames, column)
+		}))
+	}
+	if *stripPrefix != "" || *addPrefix != "" {
+		opts = append(opts, copytable.WithRowKey(func(row string) string {
+			return *addPrefix + strings.TrimPrefix(row, *stripPrefix)
+		}))
+	}
+	if *interval > 0 {
+		var last time.Time
+		opts = append(opts, copytable.WithProgress(func(s copytable.Stats) {
+			// called after every batch, so throttled here
+			if t := time.Now(); t.Sub(last) >= *interval {
+				last = t
+				fmt.Fprintf(os.Stderr, "rows:%d cells:%d regions:%d\n", s.Rows, s.Cells, s.Regions)
+			}
+		}))
+	}
+
+	stats, err := copytable.Copy(ctx, pool.NewExecutor(sp), pool.NewExecutor(tp), *table, opts...)
+	if stats != nil {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(stats)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "copy error - %v\n", err)
+		if *checkpoint != "" {
+			fmt.Fprintln(os.Stderr, "run again with the same flags to resume")
+		}
+		os.Exit(1)
+	}
+}
+
+// parseRenames parses old=new pairs of families or columns.
+func parseRenames(s string) (map[string]string, error) {
+	renames := make(map[string]string)
+	if s == "" {
+		return renames, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		old, renamed, ok := strings.Cut(pair, "=")
+		if !ok || !strings.Contains(old, ":") || !strings.Contains(renamed, ":") {
+			return nil, fmt.Errorf("invalid rename %q", pair)
+		}
+		renames[old] = renamed
+	}
+	return renames, nil
+}
+
+// renameColumn renames column, or its family, by renames.
+func renameColumn(renames map[string]string, column string) string {
+	if renamed, ok := renames[column]; ok {
+		return renamed
+	}
+	family, qualifier, _ := strings.Cut(column, ":")
+	if renamed, ok := renames[family+":"]; ok {
+		return renamed + qualifier
+	}
+	return column
+}
+
+func newPool(addrs string, maxActive int, timeout time.Duration) pool.Pool {
+	return pool.NewPool(
+		pool.WithAddrs(strings.Split(addrs, ",")...),
+		pool.WithSocketTimeout(timeout),
+		pool.WithMaxActive(maxActive),
+		pool.WithMaxIdle(maxActive),
+		pool.WithBlockMode(true),
+	)
+}
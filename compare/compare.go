@@ -0,0 +1,478 @@
+package compare
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"github.com/valyala/fastrand"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+	"github.com/popeyeio/gohbase/pool"
+	"github.com/popeyeio/gohbase/scan"
+)
+
+const (
+	defaultConcurrency = 4
+	defaultMaxDiffs    = 1000
+	defaultBatchSize   = 256
+)
+
+// Report is the result of a comparison of a table between a source and a target.
+type Report struct {
+	Table string     `json:"table"`
+	Range scan.Range `json:"range"`
+	// Sampled is true if only a sample of the source rows was compared, in
+	// which case extra rows in the target are not detected.
+	Sampled bool `json:"sampled"`
+
+	// Rows is the number of source rows compared.
+	Rows int64 `json:"rows"`
+	// Missing is the number of rows in the source but not in the target.
+	Missing int64 `json:"missing"`
+	// Extra is the number of rows in the target but not in the source.
+	Extra int64 `json:"extra"`
+	// Different is the number of rows whose cells differ.
+	Different int64 `json:"different"`
+
+	MissingRows []string    `json:"missing_rows,omitempty"`
+	ExtraRows   []string    `json:"extra_rows,omitempty"`
+	Diffs       []*CellDiff `json:"diffs,omitempty"`
+	// Truncated is true if there are more differences than listed.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Equal reports whether no difference was found.
+func (r *Report) Equal() bool {
+	return r.Missing == 0 && r.Extra == 0 && r.Different == 0
+}
+
+// CellDiff is a cell which differs, Source or Target is nil if the cell is missing.
+type CellDiff struct {
+	Row    string `json:"row"`
+	Column string `json:"column"`
+	Source *Cell  `json:"source,omitempty"`
+	Target *Cell  `json:"target,omitempty"`
+}
+
+type Cell struct {
+	Value     string `json:"value"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+type comparer struct {
+	source      pool.Executor
+	target      pool.Executor
+	table       string
+	targetTable string
+
+	start       string
+	stop        string
+	columns     []string
+	timestamps  bool
+	sample      float64
+	concurrency int
+	maxDiffs    int
+	batchSize   int
+	checkpoint  string
+}
+
+type Option func(*comparer)
+
+// WithRange compares only the rows in [start, stop), the whole table by default.
+func WithRange(start, stop string) Option {
+	return func(c *comparer) {
+		c.start = start
+		c.stop = stop
+	}
+}
+
+// WithTargetTable compares the table with table in the target, which has the
+// same name by default.
+func WithTargetTable(table string) Option {
+	return func(c *comparer) {
+		c.targetTable = table
+	}
+}
+
+// WithColumns compares only columns, which are families or family:qualifier.
+func WithColumns(columns ...string) Option {
+	return func(c *comparer) {
+		c.columns = columns
+	}
+}
+
+// WithTimestamps compares the timestamps of cells besides their values.
+func WithTimestamps(enabled bool) Option {
+	return func(c *comparer) {
+		c.timestamps = enabled
+	}
+}
+
+// WithSampling compares only a random fraction rate of the source rows, which
+// are looked up in the target rather than scanning it.
+func WithSampling(rate float64) Option {
+	return func(c *comparer) {
+		if rate > 0 && rate <= 1 {
+			c.sample = rate
+		}
+	}
+}
+
+// WithConcurrency sets the number of regions compared in parallel.
+func WithConcurrency(n int) Option {
+	return func(c *comparer) {
+		if n > 0 {
+			c.concurrency = n
+		}
+	}
+}
+
+// WithMaxDiffs sets the max number of rows and cells listed in the report,
+// the differences are still counted past it.
+func WithMaxDiffs(n int) Option {
+	return func(c *comparer) {
+		if n >= 0 {
+			c.maxDiffs = n
+		}
+	}
+}
+
+// WithBatchSize sets the number of rows fetched by a call.
+func WithBatchSize(n int) Option {
+	return func(c *comparer) {
+		if n > 0 {
+			c.batchSize = n
+		}
+	}
+}
+
+// WithCheckpoint records the regions compared in the file at path, so that a
+// comparison which is interrupted resumes from the regions left.
+func WithCheckpoint(path string) Option {
+	return func(c *comparer) {
+		c.checkpoint = path
+	}
+}
+
+// Compare compares the rows of table between source and target, scanning the
+// regions of the source in parallel.
+func Compare(ctx context.Context, source, target pool.Executor, table string, opts ...Option) (*Report, error) {
+	c := &comparer{
+		source:      source,
+		target:      target,
+		table:       table,
+		concurrency: defaultConcurrency,
+		maxDiffs:    defaultMaxDiffs,
+		batchSize:   defaultBatchSize,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.targetTable == "" {
+		c.targetTable = table
+	}
+
+	cp, err := scan.OpenCheckpoint(c.checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	ranges, err := scan.Split(source, table, c.start, c.stop)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		Table:   table,
+		Range:   scan.Range{Start: c.start, Stop: c.stop},
+		Sampled: c.sample > 0,
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	jobs := make(chan scan.Range)
+	for i := 0; i < c.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range jobs {
+				rr, err := c.compareRange(ctx, cp, r)
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+						cancel()
+					}
+				} else {
+					c.merge(report, rr)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, r := range ranges {
+		select {
+		case jobs <- r:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	sort.Strings(report.MissingRows)
+	sort.Strings(report.ExtraRows)
+	sort.SliceStable(report.Diffs, func(i, j int) bool {
+		return report.Diffs[i].Row < report.Diffs[j].Row
+	})
+	return report, nil
+}
+
+// compareRange compares r, or loads its report from the checkpoint if r has
+// been compared already.
+func (c *comparer) compareRange(ctx context.Context, cp *scan.Checkpoint, r scan.Range) (*Report, error) {
+	if p := cp.Get(r); p != nil && p.Done {
+		rr := &Report{}
+		if err := json.Unmarshal(p.Result, rr); err == nil {
+			return rr, nil
+		}
+	}
+
+	rr := &Report{}
+	var err error
+	if c.sample > 0 {
+		err = c.sampleRange(ctx, r, rr)
+	} else {
+		err = c.scanRange(ctx, r, rr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := json.Marshal(rr)
+	if err != nil {
+		return nil, err
+	}
+	if err = cp.Set(r, &scan.Progress{Done: true, Result: result}); err != nil {
+		return nil, err
+	}
+	return rr, nil
+}
+
+// stream is the rows of a range scanned in the background.
+type stream struct {
+	rows chan *hbase.TRowResult_
+	err  error
+}
+
+func (c *comparer) stream(ctx context.Context, e pool.Executor, table string, r scan.Range) *stream {
+	s := &stream{rows: make(chan *hbase.TRowResult_, c.batchSize)}
+	go func() {
+		defer close(s.rows)
+		s.err = scan.Scan(ctx, e, table, r, func(rows []*hbase.TRowResult_) error {
+			for _, row := range rows {
+				select {
+				case s.rows <- row:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			return nil
+		}, scan.WithColumns(c.columns...), scan.WithBatchSize(c.batchSize))
+	}()
+	return s
+}
+
+func (s *stream) next() *hbase.TRowResult_ {
+	return <-s.rows
+}
+
+// scanRange scans r in the source and the target, and merges the rows in order.
+func (c *comparer) scanRange(ctx context.Context, r scan.Range, rr *Report) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	src, tgt := c.stream(ctx, c.source, c.table, r), c.stream(ctx, c.target, c.targetTable, r)
+	a, b := src.next(), tgt.next()
+	for a != nil || b != nil {
+		switch {
+		case b == nil || (a != nil && bytes.Compare(a.Row, b.Row) < 0):
+			rr.Rows++
+			c.missing(rr, string(a.Row))
+			a = src.next()
+		case a == nil || bytes.Compare(b.Row, a.Row) < 0:
+			c.extra(rr, string(b.Row))
+			b = tgt.next()
+		default:
+			rr.Rows++
+			c.diffRow(rr, a, b)
+			a, b = src.next(), tgt.next()
+		}
+	}
+
+	if src.err != nil {
+		return src.err
+	}
+	return tgt.err
+}
+
+// sampleRange scans r in the source, and looks up a sample of its rows in
+// the target.
+func (c *comparer) sampleRange(ctx context.Context, r scan.Range, rr *Report) error {
+	var sampled []*hbase.TRowResult_
+	lookup := func() error {
+		if len(sampled) == 0 {
+			return nil
+		}
+
+		keys := make([]string, len(sampled))
+		for i, row := range sampled {
+			keys[i] = string(row.Row)
+		}
+		var found []*hbase.TRowResult_
+		var err error
+		if len(c.columns) > 0 {
+			found, err = c.target.GetRowsWithColumns(c.targetTable, keys, c.columns, nil)
+		} else {
+			found, err = c.target.GetRows(c.targetTable, keys, nil)
+		}
+		if err != nil {
+			return err
+		}
+
+		byRow := make(map[string]*hbase.TRowResult_, len(found))
+		for _, row := range found {
+			if row != nil {
+				byRow[string(row.Row)] = row
+			}
+		}
+		for _, row := range sampled {
+			rr.Rows++
+			if t, ok := byRow[string(row.Row)]; ok {
+				c.diffRow(rr, row, t)
+			} else {
+				c.missing(rr, string(row.Row))
+			}
+		}
+		sampled = sampled[:0]
+		return nil
+	}
+
+	err := scan.Scan(ctx, c.source, c.table, r, func(rows []*hbase.TRowResult_) error {
+		for _, row := range rows {
+			if float64(fastrand.Uint32n(1<<20)) < c.sample*(1<<20) {
+				sampled = append(sampled, row)
+			}
+		}
+		if len(sampled) >= c.batchSize {
+			return lookup()
+		}
+		return nil
+	}, scan.WithColumns(c.columns...), scan.WithBatchSize(c.batchSize))
+	if err != nil {
+		return err
+	}
+	return lookup()
+}
+
+func (c *comparer) missing(rr *Report, row string) {
+	rr.Missing++
+	if c.listed(rr) {
+		rr.MissingRows = append(rr.MissingRows, row)
+	}
+}
+
+func (c *comparer) extra(rr *Report, row string) {
+	rr.Extra++
+	if c.listed(rr) {
+		rr.ExtraRows = append(rr.ExtraRows, row)
+	}
+}
+
+// diffRow compares the cells of the same row in the source and the target.
+func (c *comparer) diffRow(rr *Report, a, b *hbase.TRowResult_) {
+	ca, cb := scan.Columns(a), scan.Columns(b)
+
+	columns := make([]string, 0, len(ca)+len(cb))
+	for column := range ca {
+		columns = append(columns, column)
+	}
+	for column := range cb {
+		if _, ok := ca[column]; !ok {
+			columns = append(columns, column)
+		}
+	}
+	sort.Strings(columns)
+
+	different := false
+	for _, column := range columns {
+		sa, sb := toCell(ca[column]), toCell(cb[column])
+		if sa != nil && sb != nil && sa.Value == sb.Value && (!c.timestamps || sa.Timestamp == sb.Timestamp) {
+			continue
+		}
+
+		different = true
+		if c.listed(rr) {
+			rr.Diffs = append(rr.Diffs, &CellDiff{Row: string(a.Row), Column: column, Source: sa, Target: sb})
+		}
+	}
+	if different {
+		rr.Different++
+	}
+}
+
+// listed reports whether another difference may be listed in rr, and marks
+// rr truncated otherwise.
+func (c *comparer) listed(rr *Report) bool {
+	if len(rr.MissingRows)+len(rr.ExtraRows)+len(rr.Diffs) < c.maxDiffs {
+		return true
+	}
+	rr.Truncated = true
+	return false
+}
+
+// merge adds the report of a range to the report of the table.
+func (c *comparer) merge(report, rr *Report) {
+	report.Rows += rr.Rows
+	report.Missing += rr.Missing
+	report.Extra += rr.Extra
+	report.Different += rr.Different
+	report.Truncated = report.Truncated || rr.Truncated
+
+	for _, row := range rr.MissingRows {
+		if c.listed(report) {
+			report.MissingRows = append(report.MissingRows, row)
+		}
+	}
+	for _, row := range rr.ExtraRows {
+		if c.listed(report) {
+			report.ExtraRows = append(report.ExtraRows, row)
+		}
+	}
+	for _, diff := range rr.Diffs {
+		if c.listed(report) {
+			report.Diffs = append(report.Diffs, diff)
+		}
+	}
+}
+
+func toCell(cell *hbase.TCell) *Cell {
+	if cell == nil {
+		return nil
+	}
+	return &Cell{Value: string(cell.Value), Timestamp: cell.Timestamp}
+}
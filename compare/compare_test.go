@@ -0,0 +1,52 @@
+package compare
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/popeyeio/gohbase/internal/hbasetest"
+)
+
+func newTable(regions []string, rows map[string]string) *hbasetest.Table {
+	t := hbasetest.NewTable(regions...)
+	for row, value := range rows {
+		t.Put(row, "f:v", 1, value)
+	}
+	return t
+}
+
+func TestCompare(t *testing.T) {
+	source := newTable([]string{"", "m"}, map[string]string{"a": "1", "b": "2", "n": "3", "o": "4"})
+	target := newTable(nil, map[string]string{"a": "1", "b": "x", "n": "3", "z": "5"})
+	checkpoint := filepath.Join(t.TempDir(), "checkpoint")
+
+	report, err := Compare(context.Background(), source, target, "t", WithBatchSize(1), WithCheckpoint(checkpoint))
+	if err != nil {
+		t.Fatalf("Compare error - %v", err)
+	}
+	if report.Rows != 4 || report.Missing != 1 || report.Extra != 1 || report.Different != 1 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if report.MissingRows[0] != "o" || report.ExtraRows[0] != "z" || report.Diffs[0].Row != "b" || report.Diffs[0].Target.Value != "x" {
+		t.Errorf("unexpected differences %+v", report)
+	}
+
+	// a resumed comparison reuses the regions compared
+	scans := source.Calls("ScannerOpenWithScan")
+	resumed, err := Compare(context.Background(), source, target, "t", WithCheckpoint(checkpoint))
+	if err != nil {
+		t.Fatalf("Compare error - %v", err)
+	}
+	if scans := source.Calls("ScannerOpenWithScan") - scans; scans != 0 || resumed.Rows != report.Rows || resumed.Different != 1 {
+		t.Errorf("expected the checkpoint to be reused, got %d scans, %+v", scans, resumed)
+	}
+
+	sampled, err := Compare(context.Background(), source, target, "t", WithSampling(1))
+	if err != nil {
+		t.Fatalf("Compare error - %v", err)
+	}
+	if !sampled.Sampled || sampled.Rows != 4 || sampled.Missing != 1 || sampled.Extra != 0 || sampled.Different != 1 {
+		t.Errorf("unexpected sampled report %+v", sampled)
+	}
+}
@@ -0,0 +1,449 @@
+package copytable
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+	"github.com/popeyeio/gohbase/internal/ratelimit"
+	"github.com/popeyeio/gohbase/pool"
+	"github.com/popeyeio/gohbase/scan"
+)
+
+const (
+	defaultConcurrency        = 4
+	defaultBatchSize          = 256
+	defaultCheckpointInterval = time.Second * 10
+)
+
+// Stats contains statistics of a copy.
+type Stats struct {
+	// Rows is the number of rows copied.
+	Rows int64 `json:"rows"`
+	// Cells is the number of cells copied.
+	Cells int64 `json:"cells"`
+	// Skipped is the number of rows skipped by the row key function.
+	Skipped int64 `json:"skipped"`
+	// Regions is the number of regions of the source copied completely.
+	Regions int `json:"regions"`
+}
+
+func (s *Stats) add(o *Stats) {
+	s.Rows += o.Rows
+	s.Cells += o.Cells
+	s.Skipped += o.Skipped
+	s.Regions += o.Regions
+}
+
+type copier struct {
+	source      pool.Executor
+	target      pool.Executor
+	table       string
+	targetTable string
+	start       string
+	stop        string
+	columns     []string
+	versions    int32
+	create      bool
+	rowKey      func(string) string
+	column      func(string) string
+	concurrency int
+	batchSize   int
+	checkpoint  string
+	interval    time.Duration
+	progress    func(Stats)
+	limiter     *ratelimit.Limiter
+
+	mu    sync.Mutex
+	total Stats
+}
+
+type Option func(*copier)
+
+// WithRange copies only the rows in [start, stop), the whole table by default.
+func WithRange(start, stop string) Option {
+	return func(c *copier) {
+		c.start = start
+		c.stop = stop
+	}
+}
+
+// WithTargetTable copies the table to table in the target, which has the
+// same name by default.
+func WithTargetTable(table string) Option {
+	return func(c *copier) {
+		c.targetTable = table
+	}
+}
+
+// WithColumns copies only columns, which are families or family:qualifier.
+func WithColumns(columns ...string) Option {
+	return func(c *copier) {
+		c.columns = columns
+	}
+}
+
+// WithVersions copies up to n versions of every cell, the latest one by
+// default. Copying more versions takes a call per copied column.
+func WithVersions(n int) Option {
+	return func(c *copier) {
+		if n > 0 {
+			c.versions = int32(n)
+		}
+	}
+}
+
+// WithCreateTable creates the target table with the column families of the
+// source table, renamed by the column function, if it does not exist.
+func WithCreateTable(create bool) Option {
+	return func(c *copier) {
+		c.create = create
+	}
+}
+
+// WithRowKey rewrites the row keys with f. Rows for which f returns an empty
+// key are skipped.
+func WithRowKey(f func(row string) string) Option {
+	return func(c *copier) {
+		c.rowKey = f
+	}
+}
+
+// WithColumnRename renames the columns, family:qualifier, with f. Cells for
+// which f returns an empty column are skipped.
+func WithColumnRename(f func(column string) string) Option {
+	return func(c *copier) {
+		c.column = f
+	}
+}
+
+// WithConcurrency sets the number of regions copied in parallel.
+func WithConcurrency(n int) Option {
+	return func(c *copier) {
+		if n > 0 {
+			c.concurrency = n
+		}
+	}
+}
+
+// WithBatchSize sets the number of rows read and written by a call.
+func WithBatchSize(n int) Option {
+	return func(c *copier) {
+		if n > 0 {
+			c.batchSize = n
+		}
+	}
+}
+
+// WithRate limits the copy to rate cells per second across the regions.
+func WithRate(rate float64) Option {
+	return func(c *copier) {
+		if rate > 0 {
+			c.limiter = ratelimit.New(rate)
+		}
+	}
+}
+
+// WithCheckpoint records the progress of every region in the file at path,
+// so that a copy which is interrupted resumes from the last row recorded of
+// every region. The rows copied after it are copied again.
+func WithCheckpoint(path string) Option {
+	return func(c *copier) {
+		c.checkpoint = path
+	}
+}
+
+// WithCheckpointInterval sets how often the progress of a region is recorded,
+// 10 seconds by default. It is also recorded once a region is done or fails.
+func WithCheckpointInterval(d time.Duration) Option {
+	return func(c *copier) {
+		if d > 0 {
+			c.interval = d
+		}
+	}
+}
+
+// WithProgress calls f with the stats so far after every batch. The calls of
+// f do not overlap.
+func WithProgress(f func(Stats)) Option {
+	return func(c *copier) {
+		c.progress = f
+	}
+}
+
+// Copy copies the rows of table from source to target, scanning the regions
+// of the source in parallel. The cells are written with their timestamps, so
+// copying a row again overwrites the same versions. Only the latest version
+// of every cell is copied, unless WithVersions is given.
+func Copy(ctx context.Context, source, target pool.Executor, table string, opts ...Option) (*Stats, error) {
+	c := &copier{
+		source:      source,
+		target:      target,
+		table:       table,
+		versions:    1,
+		concurrency: defaultConcurrency,
+		batchSize:   defaultBatchSize,
+		interval:    defaultCheckpointInterval,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.targetTable == "" {
+		c.targetTable = table
+	}
+
+	if c.create {
+		if err := c.createTable(); err != nil {
+			return nil, err
+		}
+	}
+
+	cp, err := scan.OpenCheckpoint(c.checkpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	ranges, err := scan.Split(source, table, c.start, c.stop)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	jobs := make(chan scan.Range)
+	for i := 0; i < c.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range jobs {
+				if err := c.copyRange(ctx, cp, r); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+						cancel()
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, r := range ranges {
+		select {
+		case jobs <- r:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	stats := c.stats()
+	if firstErr != nil {
+		return &stats, firstErr
+	}
+	return &stats, ctx.Err()
+}
+
+// createTable creates the target table if it does not exist.
+func (c *copier) createTable() error {
+	names, err := c.target.GetTableNames()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if name == c.targetTable {
+			return nil
+		}
+	}
+
+	descriptors, err := c.source.GetColumnDescriptors(c.table)
+	if err != nil {
+		return err
+	}
+
+	families := make([]string, 0, len(descriptors))
+	for family := range descriptors {
+		families = append(families, family)
+	}
+	sort.Strings(families)
+
+	var created []*hbase.ColumnDescriptor
+	for _, family := range families {
+		d := *descriptors[family]
+		name := strings.TrimSuffix(string(d.Name), ":") + ":"
+		if c.column != nil {
+			if name = c.column(name); name == "" {
+				continue
+			}
+			name = strings.SplitN(name, ":", 2)[0] + ":"
+		}
+		d.Name = hbase.Text(name)
+		created = append(created, &d)
+	}
+	return c.target.CreateTable(c.targetTable, created)
+}
+
+// copyRange copies the part of r which is left, according to the checkpoint.
+func (c *copier) copyRange(ctx context.Context, cp *scan.Checkpoint, r scan.Range) error {
+	rs := &Stats{}
+	if p := cp.Get(r); p != nil {
+		if len(p.Result) > 0 {
+			if err := json.Unmarshal(p.Result, rs); err != nil {
+				return err
+			}
+		}
+		c.update(rs)
+		if p.Done {
+			return nil
+		}
+	}
+
+	save := func(p *scan.Progress) (err error) {
+		p.Result, err = json.Marshal(rs)
+		if err != nil {
+			return err
+		}
+		return cp.Set(r, p)
+	}
+
+	var (
+		last  string
+		saved = time.Now()
+	)
+	err := scan.Scan(ctx, c.source, c.table, cp.Resume(r), func(rows []*hbase.TRowResult_) error {
+		bs := &Stats{}
+		batches, err := c.rewrite(rows, bs)
+		if err != nil {
+			return err
+		}
+		if err = c.limiter.Wait(ctx, bs.Cells); err != nil {
+			return err
+		}
+		if err = c.write(batches); err != nil {
+			return err
+		}
+
+		rs.add(bs)
+		last = string(rows[len(rows)-1].Row)
+		if time.Since(saved) >= c.interval {
+			if err = save(&scan.Progress{Last: last}); err != nil {
+				return err
+			}
+			last, saved = "", time.Now()
+		}
+		c.update(bs)
+		return nil
+	}, scan.WithColumns(c.columns...), scan.WithBatchSize(c.batchSize))
+	if err != nil {
+		if last != "" {
+			if serr := save(&scan.Progress{Last: last}); serr != nil {
+				return serr
+			}
+		}
+		return err
+	}
+
+	rs.Regions = 1
+	if err = save(&scan.Progress{Done: true}); err != nil {
+		return err
+	}
+	c.update(&Stats{Regions: 1})
+	return nil
+}
+
+// rewrite renames the rows and the columns, and returns the mutations of
+// rows by timestamp.
+func (c *copier) rewrite(rows []*hbase.TRowResult_, stats *Stats) (map[int64][]*hbase.BatchMutation, error) {
+	batches := make(map[int64][]*hbase.BatchMutation)
+	for _, r := range rows {
+		row := string(r.Row)
+		if c.rowKey != nil {
+			if row = c.rowKey(row); row == "" {
+				stats.Skipped++
+				continue
+			}
+		}
+
+		byTimestamp := make(map[int64]*hbase.BatchMutation)
+		for column, cell := range scan.Columns(r) {
+			if cell == nil {
+				continue
+			}
+			name := column
+			if c.column != nil {
+				if name = c.column(column); name == "" {
+					continue
+				}
+			}
+
+			vs := []*hbase.TCell{cell}
+			if c.versions > 1 {
+				var err error
+				if vs, err = c.source.GetVer(c.table, string(r.Row), column, c.versions, nil); err != nil {
+					return nil, err
+				}
+			}
+
+			for _, v := range vs {
+				b := byTimestamp[v.Timestamp]
+				if b == nil {
+					b = &hbase.BatchMutation{Row: hbase.Text(row)}
+					byTimestamp[v.Timestamp] = b
+					batches[v.Timestamp] = append(batches[v.Timestamp], b)
+				}
+				b.Mutations = append(b.Mutations, &hbase.Mutation{
+					Column:     hbase.Text(name),
+					Value:      hbase.Text(v.Value),
+					WriteToWAL: true,
+				})
+				stats.Cells++
+			}
+		}
+		stats.Rows++
+	}
+	return batches, nil
+}
+
+// write writes the mutations with a call per timestamp.
+func (c *copier) write(batches map[int64][]*hbase.BatchMutation) error {
+	timestamps := make([]int64, 0, len(batches))
+	for ts := range batches {
+		timestamps = append(timestamps, ts)
+	}
+	sort.Slice(timestamps, func(i, j int) bool {
+		return timestamps[i] < timestamps[j]
+	})
+
+	for _, ts := range timestamps {
+		if err := c.target.MutateRowsTs(c.targetTable, batches[ts], ts, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *copier) update(s *Stats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.total.add(s)
+	if c.progress != nil {
+		c.progress(c.total)
+	}
+}
+
+func (c *copier) stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.total
+}
@@ -0,0 +1,136 @@
+package copytable
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/popeyeio/gohbase/internal/hbasetest"
+	"github.com/popeyeio/gohbase/scan"
+)
+
+func TestCopy(t *testing.T) {
+	source := hbasetest.NewTable("", "c")
+	for i, row := range []string{"a", "b", "c", "d", "skip"} {
+		source.Put(row, "f:q", int64(i+1), row)
+		source.Put(row, "x:q", 1, "dropped")
+	}
+
+	// the copy fails after copying two rows
+	target := hbasetest.NewTable()
+	target.Fail(func(method string) error {
+		if method == "MutateRowsTs" && target.Calls(method) >= 2 {
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+	checkpoint := filepath.Join(t.TempDir(), "checkpoint")
+
+	opts := []Option{
+		WithCreateTable(true),
+		WithRowKey(func(row string) string {
+			if row == "skip" {
+				return ""
+			}
+			return "new-" + row
+		}),
+		WithColumnRename(func(column string) string {
+			if strings.HasPrefix(column, "x:") {
+				return ""
+			}
+			return "g:" + strings.TrimPrefix(column, "f:")
+		}),
+		WithConcurrency(1),
+		WithBatchSize(1),
+		WithCheckpoint(checkpoint),
+	}
+
+	if _, err := Copy(context.Background(), source, target, "t", opts...); err == nil {
+		t.Fatal("expected the copy to fail")
+	}
+	if created := target.Descriptors(); len(created) != 1 || string(created[0].Name) != "g:" {
+		t.Errorf("unexpected families created %v", created)
+	}
+
+	target.Fail(nil)
+	stats, err := Copy(context.Background(), source, target, "t", opts...)
+	if err != nil {
+		t.Fatalf("Copy error - %v", err)
+	}
+	if stats.Rows != 4 || stats.Cells != 4 || stats.Skipped != 1 || stats.Regions != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if writes := target.Calls("MutateRowsTs"); writes != 4 {
+		t.Errorf("expected the copy to resume after the rows copied, got %d writes", writes-2)
+	}
+	cells := target.Cells()
+	for i, row := range []string{"a", "b", "c", "d"} {
+		vs := cells["new-"+row]["g:q"]
+		if len(vs) != 1 || string(vs[0].Value) != row || vs[0].Timestamp != int64(i+1) {
+			t.Errorf("row %s not copied with its timestamp, %v", row, cells)
+		}
+	}
+	if len(cells) != 4 {
+		t.Errorf("unexpected cells %v", cells)
+	}
+}
+
+func TestCopy_Versions(t *testing.T) {
+	source := hbasetest.NewTable()
+	source.Put("a", "f:q", 1, "a1")
+	source.Put("a", "f:q", 2, "a2")
+	source.Put("a", "f:q", 3, "a3")
+
+	target := hbasetest.NewTable()
+	if _, err := Copy(context.Background(), source, target, "t"); err != nil {
+		t.Fatalf("Copy error - %v", err)
+	}
+	if vs := target.Cells()["a"]["f:q"]; len(vs) != 1 || vs[0].Timestamp != 3 {
+		t.Errorf("expected the latest version only, got %v", vs)
+	}
+
+	target = hbasetest.NewTable()
+	stats, err := Copy(context.Background(), source, target, "t", WithVersions(2))
+	if err != nil {
+		t.Fatalf("Copy error - %v", err)
+	}
+	vs := target.Cells()["a"]["f:q"]
+	if len(vs) != 2 || vs[0].Timestamp != 3 || string(vs[1].Value) != "a2" || stats.Cells != 2 {
+		t.Errorf("expected the 2 latest versions, got %v, %+v", vs, stats)
+	}
+}
+
+func TestCopy_CheckpointInterval(t *testing.T) {
+	source := hbasetest.NewTable()
+	for _, row := range []string{"a", "b", "c"} {
+		source.Put(row, "f:q", 1, row)
+	}
+	checkpoint := filepath.Join(t.TempDir(), "checkpoint")
+	whole := scan.Range{}
+
+	// the progress is not recorded after every batch
+	_, err := Copy(context.Background(), source, hbasetest.NewTable(), "t", WithBatchSize(1), WithCheckpoint(checkpoint),
+		WithProgress(func(s Stats) {
+			cp, err := scan.OpenCheckpoint(checkpoint)
+			if err != nil {
+				t.Errorf("OpenCheckpoint error - %v", err)
+				return
+			}
+			if p := cp.Get(whole); p != nil && !p.Done {
+				t.Errorf("progress is recorded after %d rows", s.Rows)
+			}
+		}))
+	if err != nil {
+		t.Fatalf("Copy error - %v", err)
+	}
+
+	cp, err := scan.OpenCheckpoint(checkpoint)
+	if err != nil {
+		t.Fatalf("OpenCheckpoint error - %v", err)
+	}
+	if p := cp.Get(whole); p == nil || !p.Done {
+		t.Errorf("expected the region to be done, got %+v", p)
+	}
+}
@@ -0,0 +1,409 @@
+package dualwrite
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+	"github.com/popeyeio/gohbase/pool"
+)
+
+var (
+	ErrClientClosed     = errors.New("[gohbase] dual-write client is closed")
+	ErrSecondaryQueued  = errors.New("[gohbase] write to secondary failed and is queued for retry")
+	errUnknownWriteType = errors.New("[gohbase] unknown write method")
+
+	now = time.Now
+)
+
+const (
+	defaultRetryInterval = time.Second
+	defaultMaxAttempts   = 100
+)
+
+// Mode is the consistency mode of a dual-write Client.
+type Mode int
+
+const (
+	// ModePrimarySync writes to the primary synchronously, and queues the
+	// write for the secondary, which is written asynchronously.
+	ModePrimarySync Mode = iota
+	// ModeBothSync writes to the primary and then to the secondary
+	// synchronously. A write which fails on the secondary is queued for
+	// retry and returns ErrSecondaryQueued. So do the writes issued while
+	// earlier writes are still queued, so that the secondary applies the
+	// writes in order.
+	ModeBothSync
+)
+
+// Client writes to a primary and a secondary cluster, for migrating tables
+// between clusters without downtime. A write fails if it fails on the
+// primary, and is never sent to the secondary then.
+//
+// Writes to the secondary are retried in order from a queue, which is durable
+// if it has a journal, until they succeed or run out of attempts. Note that a
+// retried increment may be applied more than once if an attempt times out.
+type Client interface {
+	MutateRow(string, string, []*hbase.Mutation, map[string]string) error
+	MutateRows(string, []*hbase.BatchMutation, map[string]string) error
+	DeleteAll(string, string, string, map[string]string) error
+	DeleteAllRow(string, string, map[string]string) error
+	AtomicIncrement(string, string, string, int64) (int64, error)
+	Increment(*hbase.TIncrement) error
+	IncrementRows([]*hbase.TIncrement) error
+
+	// Report returns the writes which have not reached the secondary.
+	Report() *Report
+	// Close stops retrying, and closes the journal. The pending writes are
+	// retried once a client is opened with the same journal.
+	Close(ctx context.Context) error
+	Stats() Stats
+}
+
+// Report is a reconciliation report of the writes which have not reached
+// the secondary, in the order they were written.
+type Report struct {
+	Generated time.Time `json:"generated"`
+	// Pending are the writes still being retried.
+	Pending []*Write `json:"pending"`
+	// Dead are the writes given up after running out of attempts.
+	Dead []*Write `json:"dead"`
+}
+
+// Stats contains statistics of a dual-write Client.
+type Stats struct {
+	// Writes is the total number of writes applied to the primary.
+	Writes int64
+	// Secondary is the total number of writes applied to the secondary.
+	Secondary int64
+	// Retries is the total number of failed attempts on the secondary.
+	Retries int64
+	// Pending is the number of writes queued for the secondary.
+	Pending int
+}
+
+type client struct {
+	primary   pool.Executor
+	secondary pool.Executor
+
+	mode          Mode
+	journal       string
+	retryInterval time.Duration
+	maxAttempts   int
+	logger        *slog.Logger
+
+	q         *queue
+	closed    int32
+	closeChan chan struct{}
+	wg        sync.WaitGroup
+
+	writes  int64
+	applied int64
+	retries int64
+}
+
+var _ Client = (*client)(nil)
+
+type Option func(*client)
+
+// WithMode sets the consistency mode, ModePrimarySync by default.
+func WithMode(mode Mode) Option {
+	return func(c *client) {
+		if mode == ModePrimarySync || mode == ModeBothSync {
+			c.mode = mode
+		}
+	}
+}
+
+// WithJournal makes the queue of the secondary durable, journaled to the file
+// at path. The queue is in memory by default.
+func WithJournal(path string) Option {
+	return func(c *client) {
+		c.journal = path
+	}
+}
+
+// WithRetryInterval sets how long to wait before retrying a failed write.
+func WithRetryInterval(d time.Duration) Option {
+	return func(c *client) {
+		if d > 0 {
+			c.retryInterval = d
+		}
+	}
+}
+
+// WithMaxAttempts sets how many times a write is attempted on the secondary
+// before it is given up and reported as dead.
+func WithMaxAttempts(n int) Option {
+	return func(c *client) {
+		if n > 0 {
+			c.maxAttempts = n
+		}
+	}
+}
+
+// WithLogger sets the logger failures on the secondary are logged to.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *client) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
+}
+
+func NewClient(primary, secondary pool.Executor, opts ...Option) (Client, error) {
+	c := &client{
+		primary:       primary,
+		secondary:     secondary,
+		retryInterval: defaultRetryInterval,
+		maxAttempts:   defaultMaxAttempts,
+		logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+		closeChan:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	q, err := openQueue(c.journal)
+	if err != nil {
+		return nil, err
+	}
+	c.q = q
+
+	c.wg.Add(1)
+	go c.run()
+	return c, nil
+}
+
+func (c *client) MutateRow(name, row string, mutations []*hbase.Mutation, attributes map[string]string) error {
+	return c.write(&Write{Method: "MutateRow", Table: name, Row: row, Mutations: mutations, Attributes: attributes})
+}
+
+func (c *client) MutateRows(name string, rowBatches []*hbase.BatchMutation, attributes map[string]string) error {
+	return c.write(&Write{Method: "MutateRows", Table: name, Batches: rowBatches, Attributes: attributes})
+}
+
+func (c *client) DeleteAll(name, row, column string, attributes map[string]string) error {
+	return c.write(&Write{Method: "DeleteAll", Table: name, Row: row, Column: column, Attributes: attributes})
+}
+
+func (c *client) DeleteAllRow(name, row string, attributes map[string]string) error {
+	return c.write(&Write{Method: "DeleteAllRow", Table: name, Row: row, Attributes: attributes})
+}
+
+func (c *client) AtomicIncrement(name, row, column string, value int64) (rsp int64, err error) {
+	w := &Write{Method: "AtomicIncrement", Table: name, Row: row, Column: column, Value: value}
+	if c.isClosed() {
+		return 0, ErrClientClosed
+	}
+	if rsp, err = c.primary.AtomicIncrement(name, row, column, value); err != nil {
+		return
+	}
+	err = c.replicate(w)
+	return
+}
+
+func (c *client) Increment(increment *hbase.TIncrement) error {
+	w := &Write{Method: "Increment", Increments: []*hbase.TIncrement{increment}}
+	if increment != nil {
+		w.Table = string(increment.Table)
+		w.Row = string(increment.Row)
+	}
+	return c.write(w)
+}
+
+func (c *client) IncrementRows(increments []*hbase.TIncrement) error {
+	w := &Write{Method: "IncrementRows", Increments: increments}
+	if len(increments) > 0 && increments[0] != nil {
+		w.Table = string(increments[0].Table)
+	}
+	return c.write(w)
+}
+
+func (c *client) Report() *Report {
+	pending, dead := c.q.snapshot()
+	return &Report{
+		Generated: now(),
+		Pending:   pending,
+		Dead:      dead,
+	}
+}
+
+func (c *client) Close(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt32(&c.closed, 0, 1) {
+		return ErrClientClosed
+	}
+	close(c.closeChan)
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return c.q.close()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *client) Stats() Stats {
+	return Stats{
+		Writes:    atomic.LoadInt64(&c.writes),
+		Secondary: atomic.LoadInt64(&c.applied),
+		Retries:   atomic.LoadInt64(&c.retries),
+		Pending:   c.q.len(),
+	}
+}
+
+func (c *client) isClosed() bool {
+	return atomic.LoadInt32(&c.closed) == 1
+}
+
+func (c *client) write(w *Write) error {
+	if c.isClosed() {
+		return ErrClientClosed
+	}
+	if err := apply(c.primary, w); err != nil {
+		return err
+	}
+	return c.replicate(w)
+}
+
+// replicate sends w, which is applied to the primary, to the secondary.
+func (c *client) replicate(w *Write) error {
+	atomic.AddInt64(&c.writes, 1)
+	w = w.copyArgs()
+	w.Time = now()
+
+	if c.mode == ModePrimarySync {
+		return c.enqueue(w)
+	}
+
+	// queue behind the earlier writes which are still queued
+	if c.q.len() == 0 {
+		err := apply(c.secondary, w)
+		if err == nil {
+			atomic.AddInt64(&c.applied, 1)
+			return nil
+		}
+		w.Attempts = 1
+		w.LastError = err.Error()
+		w.nextAttempt = now().Add(c.retryInterval)
+		atomic.AddInt64(&c.retries, 1)
+	}
+
+	if err := c.enqueue(w); err != nil {
+		return err
+	}
+	return ErrSecondaryQueued
+}
+
+func (c *client) enqueue(w *Write) error {
+	if err := c.q.add(w); err != nil {
+		if err == ErrClientClosed {
+			return err
+		}
+		c.logger.Error("[gohbase] journal write failed", "method", w.Method, "table", w.Table, "error", err)
+		return err
+	}
+	return nil
+}
+
+func (c *client) run() {
+	defer c.wg.Done()
+
+	timer := time.NewTimer(c.retryInterval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-c.q.ready:
+		case <-timer.C:
+		case <-c.closeChan:
+			return
+		}
+
+		d := c.retryInterval
+		if next := c.drain(); !next.IsZero() {
+			d = next.Sub(now())
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(d)
+	}
+}
+
+// drain applies the queued writes to the secondary in order, until one of
+// them fails and has attempts left or is not due to be retried yet, so that
+// later writes wait for it. It returns when that write is due.
+func (c *client) drain() time.Time {
+	for _, w := range c.q.head() {
+		if c.isClosed() {
+			return time.Time{}
+		}
+		if next := c.q.next(w); now().Before(next) {
+			return next
+		}
+
+		err := apply(c.secondary, w)
+		if err == nil {
+			atomic.AddInt64(&c.applied, 1)
+			if err = c.q.done(w); err != nil {
+				c.logger.Error("[gohbase] journal write failed", "error", err)
+			}
+			continue
+		}
+
+		atomic.AddInt64(&c.retries, 1)
+		next := now().Add(c.retryInterval)
+		if attempts := c.q.fail(w, err, next); attempts < c.maxAttempts {
+			c.logger.Warn("[gohbase] write to secondary failed",
+				"method", w.Method, "table", w.Table, "attempts", attempts, "error", err)
+			return next
+		}
+
+		c.logger.Error("[gohbase] write to secondary given up",
+			"method", w.Method, "table", w.Table, "row", w.Row, "attempts", w.Attempts, "error", err)
+		if err = c.q.kill(w); err != nil {
+			c.logger.Error("[gohbase] journal write failed", "error", err)
+		}
+	}
+	return time.Time{}
+}
+
+// apply applies w to e.
+func apply(e pool.Executor, w *Write) error {
+	switch w.Method {
+	case "MutateRow":
+		return e.MutateRow(w.Table, w.Row, w.Mutations, w.Attributes)
+	case "MutateRows":
+		return e.MutateRows(w.Table, w.Batches, w.Attributes)
+	case "DeleteAll":
+		return e.DeleteAll(w.Table, w.Row, w.Column, w.Attributes)
+	case "DeleteAllRow":
+		return e.DeleteAllRow(w.Table, w.Row, w.Attributes)
+	case "AtomicIncrement":
+		_, err := e.AtomicIncrement(w.Table, w.Row, w.Column, w.Value)
+		return err
+	case "Increment":
+		if len(w.Increments) == 0 {
+			return nil
+		}
+		return e.Increment(w.Increments[0])
+	case "IncrementRows":
+		return e.IncrementRows(w.Increments)
+	}
+	return errUnknownWriteType
+}
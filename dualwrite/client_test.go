@@ -0,0 +1,166 @@
+package dualwrite
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+	"github.com/popeyeio/gohbase/pool"
+)
+
+type fakeExecutor struct {
+	pool.Executor
+	sync.Mutex
+	err    error
+	rows   []string
+	values []string
+}
+
+func (e *fakeExecutor) MutateRow(name, row string, mutations []*hbase.Mutation, attributes map[string]string) error {
+	e.Lock()
+	defer e.Unlock()
+
+	if e.err != nil {
+		return e.err
+	}
+	e.rows = append(e.rows, row)
+	for _, m := range mutations {
+		e.values = append(e.values, string(m.Value))
+	}
+	return nil
+}
+
+func (e *fakeExecutor) applied() []string {
+	e.Lock()
+	defer e.Unlock()
+	return append([]string(nil), e.rows...)
+}
+
+func waitFor(t *testing.T, f func() bool) {
+	for deadline := time.Now().Add(time.Second); !f(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out")
+		}
+	}
+}
+
+func TestClient_Journal(t *testing.T) {
+	journal := filepath.Join(t.TempDir(), "journal")
+	primary := &fakeExecutor{}
+	secondary := &fakeExecutor{err: errors.New("unavailable")}
+
+	c, err := NewClient(primary, secondary, WithMode(ModeBothSync), WithJournal(journal), WithRetryInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("NewClient error - %v", err)
+	}
+	if err = c.MutateRow("t", "a", nil, nil); err != ErrSecondaryQueued {
+		t.Fatalf("expected error %v, got %v", ErrSecondaryQueued, err)
+	}
+	if err = c.MutateRow("t", "b", nil, nil); err != ErrSecondaryQueued {
+		t.Fatalf("expected later writes to queue behind, got %v", err)
+	}
+	if r := c.Report(); len(r.Pending) != 2 || r.Pending[0].Row != "a" || r.Pending[0].Attempts != 1 {
+		t.Fatalf("unexpected report %+v", r)
+	}
+	if err = c.Close(context.Background()); err != nil {
+		t.Fatalf("Close error - %v", err)
+	}
+
+	// the pending writes survive a restart, and are replayed in order
+	secondary.err = nil
+	c, err = NewClient(primary, secondary, WithJournal(journal), WithRetryInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("NewClient error - %v", err)
+	}
+	defer c.Close(context.Background())
+
+	waitFor(t, func() bool { return c.Stats().Pending == 0 })
+	if rows := secondary.applied(); len(rows) != 2 || rows[0] != "a" || rows[1] != "b" {
+		t.Errorf("unexpected writes on secondary %v", rows)
+	}
+	if rows := primary.applied(); len(rows) != 2 {
+		t.Errorf("unexpected writes on primary %v", rows)
+	}
+}
+
+func TestClient_Dead(t *testing.T) {
+	secondary := &fakeExecutor{err: errors.New("unavailable")}
+	c, err := NewClient(&fakeExecutor{}, secondary, WithMaxAttempts(2), WithRetryInterval(time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewClient error - %v", err)
+	}
+	defer c.Close(context.Background())
+
+	if err = c.MutateRow("t", "a", nil, nil); err != nil {
+		t.Fatalf("MutateRow error - %v", err)
+	}
+	waitFor(t, func() bool { return len(c.Report().Dead) == 1 })
+	if r := c.Report(); len(r.Pending) != 0 || r.Dead[0].Attempts != 2 || r.Dead[0].LastError != "unavailable" {
+		t.Errorf("unexpected report %+v", r)
+	}
+}
+
+func TestClient_RetryInterval(t *testing.T) {
+	secondary := &fakeExecutor{err: errors.New("unavailable")}
+	c, err := NewClient(&fakeExecutor{}, secondary, WithMode(ModeBothSync), WithRetryInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("NewClient error - %v", err)
+	}
+
+	for _, row := range []string{"a", "b"} {
+		if err = c.MutateRow("t", row, nil, nil); err != ErrSecondaryQueued {
+			t.Fatalf("expected error %v, got %v", ErrSecondaryQueued, err)
+		}
+	}
+
+	// the queued writes do not retry the failed head before it is due
+	next := c.(*client).drain()
+	if s := c.Stats(); s.Retries != 1 || s.Pending != 2 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+	if next.Before(now().Add(time.Minute)) {
+		t.Errorf("head is due at %v, expected after the retry interval", next)
+	}
+
+	// a write which races with Close is not queued on a closed journal
+	if err = c.Close(context.Background()); err != nil {
+		t.Fatalf("Close error - %v", err)
+	}
+	if err = c.(*client).replicate(&Write{Method: "MutateRow", Table: "t", Row: "c"}); err != ErrClientClosed {
+		t.Errorf("expected error %v, got %v", ErrClientClosed, err)
+	}
+}
+
+func TestClient_CopyArgs(t *testing.T) {
+	primary := &fakeExecutor{}
+	secondary := &fakeExecutor{err: errors.New("unavailable")}
+	c, err := NewClient(primary, secondary, WithMode(ModeBothSync), WithRetryInterval(time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewClient error - %v", err)
+	}
+	defer c.Close(context.Background())
+
+	mutations := []*hbase.Mutation{{Column: hbase.Text("f:q"), Value: hbase.Text("a")}}
+	if err = c.MutateRow("t", "r", mutations, nil); err != ErrSecondaryQueued {
+		t.Fatalf("expected error %v, got %v", ErrSecondaryQueued, err)
+	}
+
+	// the caller reuses its mutations once the write returns
+	mutations[0].Value[0] = 'b'
+	mutations[0] = &hbase.Mutation{Column: hbase.Text("f:q"), Value: hbase.Text("c")}
+
+	secondary.Lock()
+	secondary.err = nil
+	secondary.Unlock()
+	waitFor(t, func() bool { return c.Stats().Pending == 0 })
+
+	secondary.Lock()
+	defer secondary.Unlock()
+	if len(secondary.values) != 1 || secondary.values[0] != "a" {
+		t.Errorf("expected the secondary to receive the original value, got %v", secondary.values)
+	}
+}
@@ -0,0 +1,330 @@
+package dualwrite
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+	"github.com/popeyeio/gohbase/internal/clone"
+)
+
+// Write is a write to replay on the secondary cluster.
+type Write struct {
+	ID         uint64                 `json:"id"`
+	Method     string                 `json:"method"`
+	Table      string                 `json:"table,omitempty"`
+	Row        string                 `json:"row,omitempty"`
+	Column     string                 `json:"column,omitempty"`
+	Value      int64                  `json:"value,omitempty"`
+	Mutations  []*hbase.Mutation      `json:"mutations,omitempty"`
+	Batches    []*hbase.BatchMutation `json:"batches,omitempty"`
+	Increments []*hbase.TIncrement    `json:"increments,omitempty"`
+	Attributes map[string]string      `json:"attributes,omitempty"`
+
+	Time      time.Time `json:"time"`
+	Attempts  int       `json:"attempts,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+
+	// nextAttempt is when a failed write is due to be retried.
+	nextAttempt time.Time
+}
+
+// copyArgs deep copies the slices and structs of w, which the caller may
+// reuse once the write returns.
+func (w *Write) copyArgs() *Write {
+	cp := *w
+	cp.Mutations = clone.Mutations(w.Mutations)
+	cp.Batches = clone.BatchMutations(w.Batches)
+	cp.Increments = clone.Increments(w.Increments)
+	cp.Attributes = clone.Attributes(w.Attributes)
+	return &cp
+}
+
+// record is a line of the journal of a queue.
+type record struct {
+	Op    string `json:"op"`
+	Write *Write `json:"write,omitempty"`
+	ID    uint64 `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+const (
+	opAdd  = "add"
+	opDone = "done"
+	opDead = "dead"
+)
+
+// queue holds the writes pending for the secondary in the order they were
+// added, and the dead writes which were given up. If it has a path, every
+// change is appended to a journal file there, so that the queue survives
+// restarts.
+type queue struct {
+	sync.Mutex
+
+	path string
+	f    *os.File
+	w    *bufio.Writer
+
+	nextID  uint64
+	pending map[uint64]*Write
+	dead    map[uint64]*Write
+	ready   chan struct{}
+	closed  bool
+}
+
+// openQueue opens the queue journaled at path, or an in-memory queue if path
+// is empty. The journal is compacted to the pending and dead writes.
+func openQueue(path string) (*queue, error) {
+	q := &queue{
+		path:    path,
+		nextID:  1,
+		pending: make(map[uint64]*Write),
+		dead:    make(map[uint64]*Write),
+		ready:   make(chan struct{}, 1),
+	}
+	if path == "" {
+		return q, nil
+	}
+
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+	if err := q.compact(); err != nil {
+		return nil, err
+	}
+	if len(q.pending) > 0 {
+		q.signal()
+	}
+	return q, nil
+}
+
+func (q *queue) load() error {
+	f, err := os.Open(q.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64<<20)
+	for scanner.Scan() {
+		var r record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			// a torn write at the end of the journal
+			break
+		}
+
+		switch r.Op {
+		case opAdd:
+			if r.Write == nil {
+				continue
+			}
+			q.pending[r.Write.ID] = r.Write
+			if r.Write.ID >= q.nextID {
+				q.nextID = r.Write.ID + 1
+			}
+		case opDone:
+			delete(q.pending, r.ID)
+		case opDead:
+			if w, ok := q.pending[r.ID]; ok {
+				w.LastError = r.Error
+				delete(q.pending, r.ID)
+				q.dead[r.ID] = w
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// compact rewrites the journal with the current writes only.
+func (q *queue) compact() error {
+	tmp, err := os.CreateTemp(filepath.Dir(q.path), filepath.Base(q.path)+".*")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+
+	var records []*record
+	for _, wr := range append(sorted(q.dead), sorted(q.pending)...) {
+		records = append(records, &record{Op: opAdd, Write: wr})
+	}
+	for _, wr := range sorted(q.dead) {
+		records = append(records, &record{Op: opDead, ID: wr.ID, Error: wr.LastError})
+	}
+	for _, r := range records {
+		if err = enc.Encode(r); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), q.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if q.f, err = os.OpenFile(q.path, os.O_WRONLY|os.O_APPEND, 0644); err != nil {
+		return err
+	}
+	q.w = bufio.NewWriter(q.f)
+	return nil
+}
+
+// append journals r. It must be called with q locked.
+func (q *queue) append(r *record, sync bool) error {
+	if q.f == nil {
+		return nil
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if _, err = q.w.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	if err = q.w.Flush(); err != nil {
+		return err
+	}
+	if sync {
+		return q.f.Sync()
+	}
+	return nil
+}
+
+// add queues w, and returns once it is durable. w is queued even if it
+// cannot be journaled, in which case it is lost on restart. It fails with
+// ErrClientClosed once the queue is closed.
+func (q *queue) add(w *Write) error {
+	q.Lock()
+	if q.closed {
+		q.Unlock()
+		return ErrClientClosed
+	}
+	w.ID = q.nextID
+	q.nextID++
+	err := q.append(&record{Op: opAdd, Write: w}, true)
+	q.pending[w.ID] = w
+	q.Unlock()
+
+	q.signal()
+	return err
+}
+
+// fail records a failed attempt of w, which is retried at next, and returns
+// the number of attempts.
+func (q *queue) fail(w *Write, err error, next time.Time) int {
+	q.Lock()
+	defer q.Unlock()
+
+	w.Attempts++
+	w.LastError = err.Error()
+	w.nextAttempt = next
+	return w.Attempts
+}
+
+// next returns when w is due to be attempted.
+func (q *queue) next(w *Write) time.Time {
+	q.Lock()
+	defer q.Unlock()
+	return w.nextAttempt
+}
+
+func (q *queue) len() int {
+	q.Lock()
+	defer q.Unlock()
+	return len(q.pending)
+}
+
+func (q *queue) done(w *Write) error {
+	q.Lock()
+	defer q.Unlock()
+
+	delete(q.pending, w.ID)
+	return q.append(&record{Op: opDone, ID: w.ID}, false)
+}
+
+func (q *queue) kill(w *Write) error {
+	q.Lock()
+	defer q.Unlock()
+
+	delete(q.pending, w.ID)
+	q.dead[w.ID] = w
+	return q.append(&record{Op: opDead, ID: w.ID, Error: w.LastError}, false)
+}
+
+func (q *queue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// snapshot returns copies of the pending and the dead writes in the order
+// they were added.
+func (q *queue) snapshot() (pending, dead []*Write) {
+	q.Lock()
+	defer q.Unlock()
+	return copies(sorted(q.pending)), copies(sorted(q.dead))
+}
+
+// head returns the pending writes in the order they were added.
+func (q *queue) head() []*Write {
+	q.Lock()
+	defer q.Unlock()
+	return sorted(q.pending)
+}
+
+func (q *queue) close() error {
+	q.Lock()
+	defer q.Unlock()
+
+	q.closed = true
+	if q.f == nil {
+		return nil
+	}
+	err := q.w.Flush()
+	if cerr := q.f.Close(); err == nil {
+		err = cerr
+	}
+	q.f = nil
+	return err
+}
+
+func sorted(m map[uint64]*Write) []*Write {
+	ws := make([]*Write, 0, len(m))
+	for _, w := range m {
+		ws = append(ws, w)
+	}
+	sort.Slice(ws, func(i, j int) bool {
+		return ws[i].ID < ws[j].ID
+	})
+	return ws
+}
+
+func copies(ws []*Write) []*Write {
+	for i, w := range ws {
+		c := *w
+		ws[i] = &c
+	}
+	return ws
+}
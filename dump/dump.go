@@ -0,0 +1,332 @@
+package dump
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+	"github.com/popeyeio/gohbase/internal/ratelimit"
+	"github.com/popeyeio/gohbase/pool"
+	"github.com/popeyeio/gohbase/scan"
+)
+
+const (
+	defaultBatchSize = 256
+)
+
+var (
+	ErrFilterVersions = errors.New("[gohbase] a filter does not apply to the versions before the latest")
+)
+
+// Stats contains statistics of an export or an import.
+type Stats struct {
+	// Rows is the number of rows exported or imported.
+	Rows int64
+	// Cells is the number of cells exported or imported.
+	Cells int64
+	// Position is the number of cells of the file imported, including those
+	// skipped with WithResume. An interrupted import resumes from it.
+	Position int64
+}
+
+type options struct {
+	format    Format
+	encoding  Encoding
+	r         scan.Range
+	columns   []string
+	filter    string
+	versions  int32
+	batchSize int
+	rate      float64
+	resume    int64
+	progress  func(Stats)
+}
+
+type Option func(*options)
+
+// WithFormat sets the format of the file, FormatJSONL by default.
+func WithFormat(f Format) Option {
+	return func(o *options) {
+		o.format = f
+	}
+}
+
+// WithEncoding sets the encoding of row keys, columns and values,
+// EncodingEscape by default.
+func WithEncoding(enc Encoding) Option {
+	return func(o *options) {
+		o.encoding = enc
+	}
+}
+
+// WithRange exports the rows of [start, stop) only.
+func WithRange(start, stop string) Option {
+	return func(o *options) {
+		o.r = scan.Range{Start: start, Stop: stop}
+	}
+}
+
+// WithPrefix exports the rows starting with prefix only.
+func WithPrefix(prefix string) Option {
+	return func(o *options) {
+		o.r = scan.Prefix(prefix)
+	}
+}
+
+// WithColumns exports only columns, which are families or family:qualifier.
+func WithColumns(columns ...string) Option {
+	return func(o *options) {
+		o.columns = columns
+	}
+}
+
+// WithFilter exports the rows and cells passing a filter string only. It
+// cannot be combined with WithVersions, as the older versions of a cell are
+// read without the filter.
+func WithFilter(filter string) Option {
+	return func(o *options) {
+		o.filter = filter
+	}
+}
+
+// WithVersions exports up to n versions of every cell, the latest one by
+// default. Exporting more versions takes a call per exported column, and
+// fails with ErrFilterVersions if there is a filter.
+func WithVersions(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.versions = int32(n)
+		}
+	}
+}
+
+// WithBatchSize sets the number of rows exported or imported per call, 256
+// by default.
+func WithBatchSize(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.batchSize = n
+		}
+	}
+}
+
+// WithRate limits an import to rate cells per second.
+func WithRate(rate float64) Option {
+	return func(o *options) {
+		if rate > 0 {
+			o.rate = rate
+		}
+	}
+}
+
+// WithResume resumes an import from position, the Position of the Stats of
+// an interrupted import, skipping the cells before it.
+func WithResume(position int64) Option {
+	return func(o *options) {
+		if position > 0 {
+			o.resume = position
+		}
+	}
+}
+
+// WithProgress calls f with the stats so far after every batch of rows.
+func WithProgress(f func(Stats)) Option {
+	return func(o *options) {
+		o.progress = f
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		versions:  1,
+		batchSize: defaultBatchSize,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Export writes the cells of table to w, row by row and with the columns of
+// a row in order. Multiple versions of a cell are written newest first.
+func Export(ctx context.Context, e pool.Executor, table string, w io.Writer, opts ...Option) (*Stats, error) {
+	o := newOptions(opts)
+	if o.filter != "" && o.versions > 1 {
+		return &Stats{}, ErrFilterVersions
+	}
+	cw := newCellWriter(w, o.format, o.encoding)
+	stats := &Stats{}
+
+	err := scan.Scan(ctx, e, table, o.r, func(rows []*hbase.TRowResult_) error {
+		for _, r := range rows {
+			cells, err := rowCells(e, table, r, o.versions)
+			if err != nil {
+				return err
+			}
+			for _, c := range cells {
+				if err = cw.write(c); err != nil {
+					return err
+				}
+			}
+			stats.Rows++
+			stats.Cells += int64(len(cells))
+		}
+		if err := cw.flush(); err != nil {
+			return err
+		}
+		if o.progress != nil {
+			o.progress(*stats)
+		}
+		return nil
+	}, scan.WithBatchSize(o.batchSize), scan.WithColumns(o.columns...), scan.WithFilter(o.filter))
+	if err != nil {
+		return stats, err
+	}
+	return stats, cw.flush()
+}
+
+func rowCells(e pool.Executor, table string, r *hbase.TRowResult_, versions int32) ([]*Cell, error) {
+	columns := scan.Columns(r)
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	row := string(r.Row)
+	cells := make([]*Cell, 0, len(names))
+	for _, name := range names {
+		if versions == 1 {
+			if cell := columns[name]; cell != nil {
+				cells = append(cells, &Cell{Row: row, Column: name, Timestamp: cell.Timestamp, Value: string(cell.Value)})
+			}
+			continue
+		}
+
+		vs, err := e.GetVer(table, row, name, versions, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, cell := range vs {
+			cells = append(cells, &Cell{Row: row, Column: name, Timestamp: cell.Timestamp, Value: string(cell.Value)})
+		}
+	}
+	return cells, nil
+}
+
+// Import writes the cells read from r, as written by Export, to table with
+// MutateRows. A batch is written once it has as many rows as the batch size,
+// so the cells of a row must be consecutive as they are in an export.
+//
+// If Import fails, the returned Stats tell the position to resume from.
+func Import(ctx context.Context, e pool.Executor, table string, r io.Reader, opts ...Option) (*Stats, error) {
+	o := newOptions(opts)
+	cr := newCellReader(r, o.format, o.encoding)
+	stats := &Stats{}
+	im := &importer{e: e, table: table, o: o, stats: stats, limiter: ratelimit.New(o.rate)}
+
+	for ; stats.Position < o.resume; stats.Position++ {
+		if _, err := cr.read(); err != nil {
+			if err == io.EOF {
+				return stats, nil
+			}
+			return stats, err
+		}
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+
+		c, err := cr.read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return stats, err
+		}
+
+		if im.rows == o.batchSize && c.Row != im.last {
+			if err = im.flush(ctx); err != nil {
+				return stats, err
+			}
+		}
+		im.add(c)
+	}
+	return stats, im.flush(ctx)
+}
+
+// importer batches the cells of an import.
+type importer struct {
+	e       pool.Executor
+	table   string
+	o       *options
+	stats   *Stats
+	limiter *ratelimit.Limiter
+
+	// batches are the mutations of the batch by timestamp.
+	batches map[int64][]*hbase.BatchMutation
+	cells   int64
+	rows    int
+	last    string
+}
+
+func (im *importer) add(c *Cell) {
+	if im.batches == nil {
+		im.batches = make(map[int64][]*hbase.BatchMutation)
+	}
+	if im.rows == 0 || c.Row != im.last {
+		im.rows++
+		im.last = c.Row
+	}
+
+	m := &hbase.Mutation{Column: hbase.Text(c.Column), Value: hbase.Text(c.Value), WriteToWAL: true}
+	bs := im.batches[c.Timestamp]
+	if n := len(bs); n > 0 && string(bs[n-1].Row) == c.Row {
+		bs[n-1].Mutations = append(bs[n-1].Mutations, m)
+	} else {
+		im.batches[c.Timestamp] = append(bs, &hbase.BatchMutation{Row: hbase.Text(c.Row), Mutations: []*hbase.Mutation{m}})
+	}
+	im.cells++
+}
+
+// flush writes the batch, a call per timestamp, once the rate allows.
+func (im *importer) flush(ctx context.Context) error {
+	if im.cells == 0 {
+		return nil
+	}
+	if err := im.limiter.Wait(ctx, im.cells); err != nil {
+		return err
+	}
+
+	timestamps := make([]int64, 0, len(im.batches))
+	for ts := range im.batches {
+		timestamps = append(timestamps, ts)
+	}
+	sort.Slice(timestamps, func(i, j int) bool {
+		return timestamps[i] < timestamps[j]
+	})
+	for _, ts := range timestamps {
+		var err error
+		if ts == 0 {
+			err = im.e.MutateRows(im.table, im.batches[ts], nil)
+		} else {
+			err = im.e.MutateRowsTs(im.table, im.batches[ts], ts, nil)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	im.stats.Rows += int64(im.rows)
+	im.stats.Cells += im.cells
+	im.stats.Position += im.cells
+	im.batches, im.cells, im.rows = nil, 0, 0
+	if im.o.progress != nil {
+		im.o.progress(*im.stats)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package dump
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+	"github.com/popeyeio/gohbase/internal/hbasetest"
+)
+
+func TestEncoding(t *testing.T) {
+	b := "r\x00\xff\\ k"
+	for _, enc := range []Encoding{EncodingEscape, EncodingHex, EncodingBase64} {
+		got, err := enc.decode(enc.encode(b))
+		if err != nil || got != b {
+			t.Errorf("%s: decode(encode(%q)) = %q, %v", enc, b, got, err)
+		}
+	}
+	if got := EncodingEscape.encode(b); got != `r\x00\xFF\x5C k` {
+		t.Errorf("escape encode = %s", got)
+	}
+	if got, _ := EncodingEscape.decode(`a\xZZ\x4`); got != `a\xZZ\x4` {
+		t.Errorf("expected invalid escapes to be kept, got %q", got)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	src := hbasetest.NewTable()
+	src.Put("a", "f:x", 1, "a1")
+	src.Put("a", "f:x", 2, "a2")
+	src.Put("a", "f:y", 1, "\x00\x01")
+	src.Put("b\xff", "f:x", 3, "b,\"3\"\n")
+	src.Put("c", "f:x", 1, "c1")
+
+	for _, f := range []Format{FormatJSONL, FormatCSV} {
+		var buf bytes.Buffer
+		stats, err := Export(context.Background(), src, "t", &buf, WithFormat(f), WithVersions(2))
+		if err != nil {
+			t.Fatalf("%s: Export error - %v", f, err)
+		}
+		if stats.Rows != 3 || stats.Cells != 5 {
+			t.Errorf("%s: unexpected export stats %+v", f, stats)
+		}
+
+		dst := hbasetest.NewTable()
+		stats, err = Import(context.Background(), dst, "t", bytes.NewReader(buf.Bytes()), WithFormat(f), WithBatchSize(1))
+		if err != nil {
+			t.Fatalf("%s: Import error - %v", f, err)
+		}
+		if stats.Rows != 3 || stats.Cells != 5 || stats.Position != 5 {
+			t.Errorf("%s: unexpected import stats %+v", f, stats)
+		}
+		if !reflect.DeepEqual(dst.Cells(), src.Cells()) {
+			t.Errorf("%s: imported %v, want %v", f, dst.Cells(), src.Cells())
+		}
+
+		// resuming after the cells of row a imports the other rows only
+		dst = hbasetest.NewTable()
+		stats, err = Import(context.Background(), dst, "t", bytes.NewReader(buf.Bytes()), WithFormat(f), WithResume(3))
+		if err != nil {
+			t.Fatalf("%s: Import error - %v", f, err)
+		}
+		if stats.Rows != 2 || stats.Position != 5 || len(dst.Cells()) != 2 || dst.Cells()["a"] != nil {
+			t.Errorf("%s: unexpected resumed import %+v, %v", f, stats, dst.Cells())
+		}
+	}
+}
+
+func TestExport_FilterVersions(t *testing.T) {
+	src := hbasetest.NewTable()
+	src.Put("a", "f:x", 1, "a1")
+
+	var buf bytes.Buffer
+	if _, err := Export(context.Background(), src, "t", &buf, WithFilter("KeyOnlyFilter()"), WithVersions(2)); err != ErrFilterVersions {
+		t.Errorf("expected error %v, got %v", ErrFilterVersions, err)
+	}
+	if _, err := Export(context.Background(), src, "t", &buf, WithFilter("KeyOnlyFilter()")); err != nil {
+		t.Errorf("Export error - %v", err)
+	}
+}
+
+func TestImport_Resume(t *testing.T) {
+	src := hbasetest.NewTable()
+	for _, row := range []string{"a", "b", "c", "d"} {
+		src.Put(row, "f:x", 1, row+"1")
+		src.Put(row, "f:y", 2, row+"2")
+	}
+	var buf bytes.Buffer
+	if _, err := Export(context.Background(), src, "t", &buf); err != nil {
+		t.Fatalf("Export error - %v", err)
+	}
+
+	// the import fails on the third call, after the cells of row a
+	dst := hbasetest.NewTable()
+	dst.Fail(func(method string) error {
+		if dst.Calls(method) >= 2 {
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+	stats, err := Import(context.Background(), dst, "t", bytes.NewReader(buf.Bytes()), WithBatchSize(1))
+	if err == nil {
+		t.Fatal("expected the import to fail")
+	}
+	if stats.Position != 2 || stats.Rows != 1 {
+		t.Fatalf("unexpected stats of the failed import %+v", stats)
+	}
+
+	// resuming from the position imports the rest once
+	dst.Fail(nil)
+	resumed, err := Import(context.Background(), dst, "t", bytes.NewReader(buf.Bytes()), WithBatchSize(1), WithResume(stats.Position))
+	if err != nil {
+		t.Fatalf("Import error - %v", err)
+	}
+	if resumed.Position != 8 || resumed.Rows != 3 || resumed.Cells != 6 {
+		t.Errorf("unexpected stats of the resumed import %+v", resumed)
+	}
+	if !reflect.DeepEqual(dst.Cells(), src.Cells()) {
+		t.Errorf("imported %v, want %v", dst.Cells(), src.Cells())
+	}
+
+	// resuming past the end imports nothing
+	resumed, err = Import(context.Background(), hbasetest.NewTable(), "t", bytes.NewReader(buf.Bytes()), WithResume(100))
+	if err != nil || resumed.Position != 8 || resumed.Cells != 0 {
+		t.Errorf("unexpected import past the end %+v, %v", resumed, err)
+	}
+}
+
+func TestImport_CSV(t *testing.T) {
+	// without a header, and without a timestamp
+	in := "a,f:x,1,a1\na,f:y,,a2\nb,f:x,0,b1\n"
+	for _, header := range []string{"", strings.Join(csvHeader, ",") + "\n"} {
+		dst := hbasetest.NewTable()
+		stats, err := Import(context.Background(), dst, "t", strings.NewReader(header+in), WithFormat(FormatCSV))
+		if err != nil {
+			t.Fatalf("Import error - %v", err)
+		}
+		if stats.Rows != 2 || stats.Cells != 3 || stats.Position != 3 {
+			t.Errorf("unexpected stats %+v", stats)
+		}
+
+		// the cells without a timestamp take the time of the server
+		if dst.Calls("MutateRows") != 1 || dst.Calls("MutateRowsTs") != 1 {
+			t.Errorf("expected a call per timestamp, got %d and %d",
+				dst.Calls("MutateRows"), dst.Calls("MutateRowsTs"))
+		}
+		cells := dst.Cells()
+		if vs := cells["a"]["f:x"]; len(vs) != 1 || vs[0].Timestamp != 1 {
+			t.Errorf("unexpected versions of a/f:x %v", vs)
+		}
+		for _, vs := range [][]*hbase.TCell{cells["a"]["f:y"], cells["b"]["f:x"]} {
+			if len(vs) != 1 || vs[0].Timestamp == 0 {
+				t.Errorf("expected a server timestamp, got %v", vs)
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package dump
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Encoding is how binary row keys, columns and values are written as text.
+type Encoding int
+
+const (
+	// EncodingEscape writes printable characters as is and other bytes as
+	// \xNN escapes, like Bytes.toStringBinary in java and the hbase shell.
+	EncodingEscape Encoding = iota
+	// EncodingHex writes every byte as two hex digits.
+	EncodingHex
+	// EncodingBase64 writes bytes in standard base64.
+	EncodingBase64
+)
+
+func (e Encoding) String() string {
+	switch e {
+	case EncodingEscape:
+		return "escape"
+	case EncodingHex:
+		return "hex"
+	case EncodingBase64:
+		return "base64"
+	}
+	return "unknown"
+}
+
+// ParseEncoding returns the Encoding named s, as returned by String.
+func ParseEncoding(s string) (Encoding, error) {
+	for _, e := range []Encoding{EncodingEscape, EncodingHex, EncodingBase64} {
+		if strings.EqualFold(s, e.String()) {
+			return e, nil
+		}
+	}
+	return 0, fmt.Errorf("[gohbase] unknown encoding %q", s)
+}
+
+func (e Encoding) encode(b string) string {
+	switch e {
+	case EncodingHex:
+		return hex.EncodeToString([]byte(b))
+	case EncodingBase64:
+		return base64.StdEncoding.EncodeToString([]byte(b))
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(b); i++ {
+		if c := b[i]; isPrintable(c) {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, `\x%02X`, c)
+		}
+	}
+	return sb.String()
+}
+
+func (e Encoding) decode(s string) (string, error) {
+	switch e {
+	case EncodingHex:
+		b, err := hex.DecodeString(s)
+		return string(b), err
+	case EncodingBase64:
+		b, err := base64.StdEncoding.DecodeString(s)
+		return string(b), err
+	}
+
+	// like Bytes.toBytesBinary, anything but a valid \xNN escape is taken
+	// as is.
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) && s[i+1] == 'x' {
+			if n, err := strconv.ParseUint(s[i+2:i+4], 16, 8); err == nil {
+				sb.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String(), nil
+}
+
+// isPrintable reports whether Bytes.toStringBinary writes c as is.
+func isPrintable(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' ||
+		strings.IndexByte(" `~!@#$%^&*()-_=+[]{}|;:'\",.<>/?", c) >= 0
+}
@@ -0,0 +1,182 @@
+package dump
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Format is the file format of a dump.
+type Format int
+
+const (
+	// FormatJSONL writes a json object per cell and line, with the fields
+	// row, column, timestamp and value.
+	FormatJSONL Format = iota
+	// FormatCSV writes a record per cell, after a header of the fields row,
+	// column, timestamp and value.
+	FormatCSV
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatJSONL:
+		return "jsonl"
+	case FormatCSV:
+		return "csv"
+	}
+	return "unknown"
+}
+
+// ParseFormat returns the Format named s, as returned by String.
+func ParseFormat(s string) (Format, error) {
+	for _, f := range []Format{FormatJSONL, FormatCSV} {
+		if strings.EqualFold(s, f.String()) {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("[gohbase] unknown format %q", s)
+}
+
+// Cell is a version of a cell, the unit a table is dumped in. Cells are
+// dumped row by row. A zero Timestamp is imported as the current time.
+type Cell struct {
+	Row       string `json:"row"`
+	Column    string `json:"column"`
+	Timestamp int64  `json:"timestamp,omitempty"`
+	Value     string `json:"value"`
+}
+
+var csvHeader = []string{"row", "column", "timestamp", "value"}
+
+type cellWriter interface {
+	write(*Cell) error
+	flush() error
+}
+
+type cellReader interface {
+	// read returns io.EOF after the last cell.
+	read() (*Cell, error)
+}
+
+func newCellWriter(w io.Writer, f Format, enc Encoding) cellWriter {
+	if f == FormatCSV {
+		return &csvWriter{w: csv.NewWriter(w), enc: enc}
+	}
+	return &jsonWriter{enc: json.NewEncoder(w), encoding: enc}
+}
+
+func newCellReader(r io.Reader, f Format, enc Encoding) cellReader {
+	if f == FormatCSV {
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = len(csvHeader)
+		cr.ReuseRecord = true
+		return &csvReader{r: cr, enc: enc}
+	}
+	return &jsonReader{dec: json.NewDecoder(r), enc: enc}
+}
+
+func encodeCell(c *Cell, enc Encoding) *Cell {
+	return &Cell{
+		Row:       enc.encode(c.Row),
+		Column:    enc.encode(c.Column),
+		Timestamp: c.Timestamp,
+		Value:     enc.encode(c.Value),
+	}
+}
+
+func decodeCell(c *Cell, enc Encoding) error {
+	for _, field := range []*string{&c.Row, &c.Column, &c.Value} {
+		b, err := enc.decode(*field)
+		if err != nil {
+			return fmt.Errorf("[gohbase] invalid %s %q - %v", enc, *field, err)
+		}
+		*field = b
+	}
+	return nil
+}
+
+type jsonWriter struct {
+	enc      *json.Encoder
+	encoding Encoding
+}
+
+func (w *jsonWriter) write(c *Cell) error {
+	return w.enc.Encode(encodeCell(c, w.encoding))
+}
+
+func (w *jsonWriter) flush() error {
+	return nil
+}
+
+type csvWriter struct {
+	w      *csv.Writer
+	enc    Encoding
+	header bool
+}
+
+func (w *csvWriter) write(c *Cell) error {
+	if !w.header {
+		if err := w.w.Write(csvHeader); err != nil {
+			return err
+		}
+		w.header = true
+	}
+
+	e := encodeCell(c, w.enc)
+	return w.w.Write([]string{e.Row, e.Column, strconv.FormatInt(e.Timestamp, 10), e.Value})
+}
+
+func (w *csvWriter) flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type jsonReader struct {
+	dec *json.Decoder
+	enc Encoding
+}
+
+func (r *jsonReader) read() (*Cell, error) {
+	c := &Cell{}
+	if err := r.dec.Decode(c); err != nil {
+		return nil, err
+	}
+	if err := decodeCell(c, r.enc); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+type csvReader struct {
+	r      *csv.Reader
+	enc    Encoding
+	header bool
+}
+
+func (r *csvReader) read() (*Cell, error) {
+	record, err := r.r.Read()
+	if err != nil {
+		return nil, err
+	}
+	if !r.header {
+		r.header = true
+		if strings.Join(record, ",") == strings.Join(csvHeader, ",") {
+			return r.read()
+		}
+	}
+
+	c := &Cell{Row: record[0], Column: record[1], Value: record[3]}
+	if record[2] != "" {
+		if c.Timestamp, err = strconv.ParseInt(record[2], 10, 64); err != nil {
+			return nil, fmt.Errorf("[gohbase] invalid timestamp %q", record[2])
+		}
+	}
+	if err = decodeCell(c, r.enc); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
@@ -1,3 +1,5 @@
 module github.com/popeyeio/gohbase
 
+go 1.21
+
 require github.com/valyala/fastrand v0.0.0-20170531153657-19dd0f0bf014
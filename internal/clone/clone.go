@@ -0,0 +1,70 @@
+// Package clone deep copies the hbase arguments callers may reuse once a call
+// returns, for the writes which are replayed after that.
+package clone
+
+import (
+	"github.com/popeyeio/gohbase/gen/hbase"
+)
+
+func Text(t hbase.Text) hbase.Text {
+	if t == nil {
+		return nil
+	}
+	return append(hbase.Text{}, t...)
+}
+
+func Mutation(m *hbase.Mutation) *hbase.Mutation {
+	if m == nil {
+		return nil
+	}
+	cp := *m
+	cp.Column, cp.Value = Text(m.Column), Text(m.Value)
+	return &cp
+}
+
+func Mutations(mutations []*hbase.Mutation) []*hbase.Mutation {
+	var cp []*hbase.Mutation
+	for _, m := range mutations {
+		cp = append(cp, Mutation(m))
+	}
+	return cp
+}
+
+func BatchMutations(batches []*hbase.BatchMutation) []*hbase.BatchMutation {
+	var cp []*hbase.BatchMutation
+	for _, b := range batches {
+		if b != nil {
+			b = &hbase.BatchMutation{Row: Text(b.Row), Mutations: Mutations(b.Mutations)}
+		}
+		cp = append(cp, b)
+	}
+	return cp
+}
+
+func Increment(increment *hbase.TIncrement) *hbase.TIncrement {
+	if increment == nil {
+		return nil
+	}
+	cp := *increment
+	cp.Table, cp.Row, cp.Column = Text(increment.Table), Text(increment.Row), Text(increment.Column)
+	return &cp
+}
+
+func Increments(increments []*hbase.TIncrement) []*hbase.TIncrement {
+	var cp []*hbase.TIncrement
+	for _, increment := range increments {
+		cp = append(cp, Increment(increment))
+	}
+	return cp
+}
+
+func Attributes(attributes map[string]string) map[string]string {
+	if attributes == nil {
+		return nil
+	}
+	cp := make(map[string]string, len(attributes))
+	for k, v := range attributes {
+		cp[k] = v
+	}
+	return cp
+}
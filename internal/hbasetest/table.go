@@ -0,0 +1,426 @@
+// Package hbasetest provides an in-memory table for the tests of the packages
+// built on pool.Executor.
+package hbasetest
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+	"github.com/popeyeio/gohbase/pool"
+)
+
+// Table is an in-memory table, which keeps every version of its cells newest
+// first. It serves scans, reads and writes by table name alone, so a Table
+// stands for a whole cluster of one table. Calls of the methods it does not
+// implement panic.
+type Table struct {
+	pool.Executor
+
+	mu          sync.Mutex
+	regions     []string
+	cells       map[string]map[string][]*hbase.TCell
+	descriptors []*hbase.ColumnDescriptor
+	calls       map[string]int
+	fail        func(method string) error
+}
+
+var _ pool.Executor = (*Table)(nil)
+
+// NewTable creates an empty table split into regions starting at regions,
+// besides the first region which starts at the first row.
+func NewTable(regions ...string) *Table {
+	return &Table{
+		regions: regions,
+		cells:   make(map[string]map[string][]*hbase.TCell),
+		calls:   make(map[string]int),
+	}
+}
+
+// Put stores the value of column in row at ts.
+func (t *Table) Put(row, column string, ts int64, value string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.put(row, column, ts, value)
+}
+
+func (t *Table) put(row, column string, ts int64, value string) {
+	if t.cells[row] == nil {
+		t.cells[row] = make(map[string][]*hbase.TCell)
+	}
+	vs := t.cells[row][column]
+	for i, v := range vs {
+		if v.Timestamp == ts {
+			vs = append(vs[:i], vs[i+1:]...)
+			break
+		}
+	}
+	vs = append(vs, &hbase.TCell{Value: hbase.Bytes(value), Timestamp: ts})
+	sort.Slice(vs, func(i, j int) bool {
+		return vs[i].Timestamp > vs[j].Timestamp
+	})
+	t.cells[row][column] = vs
+}
+
+// Cells returns the versions of the cells keyed by row and column.
+func (t *Table) Cells() map[string]map[string][]*hbase.TCell {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	cells := make(map[string]map[string][]*hbase.TCell, len(t.cells))
+	for row, columns := range t.cells {
+		cells[row] = make(map[string][]*hbase.TCell, len(columns))
+		for column, vs := range columns {
+			cells[row][column] = append([]*hbase.TCell(nil), vs...)
+		}
+	}
+	return cells
+}
+
+// Descriptors returns the column descriptors the table was created with.
+func (t *Table) Descriptors() []*hbase.ColumnDescriptor {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.descriptors
+}
+
+// Calls returns the number of calls of method, where scanners count their
+// ScannerOpenWithScan calls.
+func (t *Table) Calls(method string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.calls[method]
+}
+
+// Fail makes every call fail with the error f returns for its method, if any.
+// Failed calls are not counted.
+func (t *Table) Fail(f func(method string) error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.fail = f
+}
+
+// call counts a call of method, or returns the error it fails with.
+func (t *Table) call(method string) error {
+	t.mu.Lock()
+	fail := t.fail
+	t.mu.Unlock()
+
+	if fail != nil {
+		if err := fail(method); err != nil {
+			return err
+		}
+	}
+
+	t.mu.Lock()
+	t.calls[method]++
+	t.mu.Unlock()
+	return nil
+}
+
+// result returns the latest versions of the columns of row, nil if there is
+// none. It must be called with t locked.
+func (t *Table) result(row string, columns []string) *hbase.TRowResult_ {
+	r := &hbase.TRowResult_{Row: hbase.Text(row), Columns: make(map[string]*hbase.TCell)}
+	for column, vs := range t.cells[row] {
+		if len(vs) > 0 && selected(column, columns) {
+			r.Columns[column] = vs[0]
+		}
+	}
+	if len(r.Columns) == 0 {
+		return nil
+	}
+	return r
+}
+
+// selected reports whether column is selected by columns, which are families
+// or family:qualifier. No columns select every column.
+func selected(column string, columns []string) bool {
+	if len(columns) == 0 {
+		return true
+	}
+	for _, c := range columns {
+		if c == column {
+			return true
+		}
+		if f := strings.TrimSuffix(c, ":"); !strings.Contains(f, ":") && f+":" == family(column) {
+			return true
+		}
+	}
+	return false
+}
+
+func family(column string) string {
+	if i := strings.IndexByte(column, ':'); i >= 0 {
+		return column[:i+1]
+	}
+	return column + ":"
+}
+
+func (t *Table) GetTableNames() ([]string, error) {
+	return nil, t.call("GetTableNames")
+}
+
+func (t *Table) CreateTable(name string, columnFamilies []*hbase.ColumnDescriptor) error {
+	if err := t.call("CreateTable"); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.descriptors = columnFamilies
+	return nil
+}
+
+func (t *Table) GetTableRegions(name string) ([]*hbase.TRegionInfo, error) {
+	if err := t.call("GetTableRegions"); err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var regions []*hbase.TRegionInfo
+	for _, start := range t.regions {
+		regions = append(regions, &hbase.TRegionInfo{StartKey: hbase.Text(start)})
+	}
+	return regions, nil
+}
+
+// GetColumnDescriptors returns the families of the cells, each keeping as
+// many versions as its cell with the most versions.
+func (t *Table) GetColumnDescriptors(name string) (map[string]*hbase.ColumnDescriptor, error) {
+	if err := t.call("GetColumnDescriptors"); err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	descriptors := make(map[string]*hbase.ColumnDescriptor)
+	for _, columns := range t.cells {
+		for column, vs := range columns {
+			f := family(column)
+			d, ok := descriptors[f]
+			if !ok {
+				d = &hbase.ColumnDescriptor{Name: hbase.Text(f), MaxVersions: 1}
+				descriptors[f] = d
+			}
+			if int32(len(vs)) > d.MaxVersions {
+				d.MaxVersions = int32(len(vs))
+			}
+		}
+	}
+	return descriptors, nil
+}
+
+func (t *Table) GetVer(name, row, column string, numVersions int32, attributes map[string]string) ([]*hbase.TCell, error) {
+	if err := t.call("GetVer"); err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	vs := t.cells[row][column]
+	if len(vs) > int(numVersions) {
+		vs = vs[:numVersions]
+	}
+	return append([]*hbase.TCell(nil), vs...), nil
+}
+
+func (t *Table) GetRow(name, row string, attributes map[string]string) ([]*hbase.TRowResult_, error) {
+	return t.GetRowWithColumns(name, row, nil, attributes)
+}
+
+func (t *Table) GetRowWithColumns(name, row string, columns []string, attributes map[string]string) ([]*hbase.TRowResult_, error) {
+	if err := t.call("GetRow"); err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if r := t.result(row, columns); r != nil {
+		return []*hbase.TRowResult_{r}, nil
+	}
+	return nil, nil
+}
+
+func (t *Table) GetRows(name string, rows []string, attributes map[string]string) ([]*hbase.TRowResult_, error) {
+	return t.GetRowsWithColumns(name, rows, nil, attributes)
+}
+
+func (t *Table) GetRowsWithColumns(name string, rows, columns []string, attributes map[string]string) ([]*hbase.TRowResult_, error) {
+	if err := t.call("GetRows"); err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var results []*hbase.TRowResult_
+	for _, row := range rows {
+		if r := t.result(row, columns); r != nil {
+			results = append(results, r)
+		}
+	}
+	return results, nil
+}
+
+// MutateRow applies mutations at the current time, like hbase does.
+func (t *Table) MutateRow(name, row string, mutations []*hbase.Mutation, attributes map[string]string) error {
+	return t.mutate("MutateRow", []*hbase.BatchMutation{{Row: hbase.Text(row), Mutations: mutations}}, 0)
+}
+
+// MutateRows applies rowBatches at the current time, like hbase does.
+func (t *Table) MutateRows(name string, rowBatches []*hbase.BatchMutation, attributes map[string]string) error {
+	return t.mutate("MutateRows", rowBatches, 0)
+}
+
+func (t *Table) MutateRowsTs(name string, rowBatches []*hbase.BatchMutation, timestamp int64, attributes map[string]string) error {
+	return t.mutate("MutateRowsTs", rowBatches, timestamp)
+}
+
+func (t *Table) mutate(method string, batches []*hbase.BatchMutation, ts int64) error {
+	if err := t.call(method); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if ts == 0 {
+		ts = time.Now().UnixMilli()
+	}
+	for _, b := range batches {
+		for _, m := range b.Mutations {
+			if m.IsDelete {
+				delete(t.cells[string(b.Row)], string(m.Column))
+				continue
+			}
+			t.put(string(b.Row), string(m.Column), ts, string(m.Value))
+		}
+	}
+	return nil
+}
+
+func (t *Table) DeleteAll(name, row, column string, attributes map[string]string) error {
+	if err := t.call("DeleteAll"); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.cells[row], column)
+	return nil
+}
+
+func (t *Table) DeleteAllRow(name, row string, attributes map[string]string) error {
+	if err := t.call("DeleteAllRow"); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.cells, row)
+	return nil
+}
+
+// Do calls f with a client which serves scanners.
+func (t *Table) Do(ctx context.Context, f func(pool.Client) error) error {
+	return f(&scanner{t: t})
+}
+
+// scanner is a client of a Table serving a single scanner.
+type scanner struct {
+	pool.Client
+
+	t    *Table
+	rows []*hbase.TRowResult_
+}
+
+// ScannerOpenWithScan snapshots the rows within the range of tscan, in
+// reverse order if it is reversed. Of the filters, only PrefixFilter is
+// applied, and the others are ignored.
+func (s *scanner) ScannerOpenWithScan(name string, tscan *hbase.TScan, attributes map[string]string) (hbase.ScannerID, error) {
+	if err := s.t.call("ScannerOpenWithScan"); err != nil {
+		return 0, err
+	}
+
+	s.t.mu.Lock()
+	defer s.t.mu.Unlock()
+
+	var columns []string
+	for _, column := range tscan.Columns {
+		columns = append(columns, string(column))
+	}
+	start, stop := string(tscan.StartRow), string(tscan.StopRow)
+	reversed := tscan.Reversed != nil && *tscan.Reversed
+	prefix, filtered := prefixFilter(string(tscan.FilterString))
+
+	keys := make([]string, 0, len(s.t.cells))
+	for row := range s.t.cells {
+		if filtered && !strings.HasPrefix(row, prefix) {
+			continue
+		}
+		if reversed && (start == "" || row <= start) && row > stop ||
+			!reversed && row >= start && (stop == "" || row < stop) {
+			keys = append(keys, row)
+		}
+	}
+	sort.Strings(keys)
+	if reversed {
+		for i, j := 0, len(keys)-1; i < j; i, j = i+1, j-1 {
+			keys[i], keys[j] = keys[j], keys[i]
+		}
+	}
+	for _, row := range keys {
+		if r := s.t.result(row, columns); r != nil {
+			s.rows = append(s.rows, r)
+		}
+	}
+	return 1, nil
+}
+
+// prefixFilter returns the prefix of the PrefixFilter in filter, if any.
+func prefixFilter(filter string) (string, bool) {
+	const name = "PrefixFilter('"
+	i := strings.Index(filter, name)
+	if i < 0 {
+		return "", false
+	}
+
+	var b strings.Builder
+	for s := filter[i+len(name):]; len(s) > 0; s = s[1:] {
+		if s[0] == '\'' {
+			if !strings.HasPrefix(s, "''") {
+				break
+			}
+			s = s[1:]
+		}
+		b.WriteByte(s[0])
+	}
+	return b.String(), true
+}
+
+func (s *scanner) ScannerGetList(id hbase.ScannerID, nbRows int32) ([]*hbase.TRowResult_, error) {
+	if int(nbRows) > len(s.rows) {
+		nbRows = int32(len(s.rows))
+	}
+	rows := s.rows[:nbRows]
+	s.rows = s.rows[nbRows:]
+	return rows, nil
+}
+
+func (s *scanner) ScannerClose(id hbase.ScannerID) error {
+	return nil
+}
@@ -0,0 +1,58 @@
+// Package ratelimit spaces out the work of bulk jobs, such as a copy or an
+// import, to a rate per second.
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter spaces out units of work shared by many goroutines to a rate per
+// second. It does not save up for a burst while it is idle, so that a job
+// which pauses does not exceed the rate once it continues.
+type Limiter struct {
+	sync.Mutex
+
+	rate float64
+	next time.Time
+}
+
+// New creates a limiter of rate units per second. A rate of zero or less
+// makes a nil limiter, which never waits.
+func New(rate float64) *Limiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &Limiter{rate: rate}
+}
+
+// Wait waits for the turn of n units, or until ctx is done.
+func (l *Limiter) Wait(ctx context.Context, n int64) error {
+	if l == nil || n <= 0 {
+		return nil
+	}
+
+	l.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	at := l.next
+	l.next = l.next.Add(time.Duration(float64(n) / l.rate * float64(time.Second)))
+	l.Unlock()
+
+	d := at.Sub(now)
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,42 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	l := New(1000)
+	ctx := context.Background()
+
+	wait := func(n int64) time.Duration {
+		start := time.Now()
+		if err := l.Wait(ctx, n); err != nil {
+			t.Fatalf("Wait error - %v", err)
+		}
+		return time.Since(start)
+	}
+
+	wait(50)
+	if d := wait(10); d < time.Millisecond*40 {
+		t.Errorf("expected to wait for the turn of 50 units, waited %v", d)
+	}
+
+	// no burst is saved up while idle
+	time.Sleep(time.Millisecond * 100)
+	wait(20)
+	if d := wait(10); d < time.Millisecond*15 {
+		t.Errorf("expected to wait for the turn of 20 units after idling, waited %v", d)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := l.Wait(canceled, 1000); err != context.Canceled {
+		t.Errorf("expected error %v, got %v", context.Canceled, err)
+	}
+
+	if err := New(0).Wait(ctx, 1000); err != nil {
+		t.Errorf("a nil limiter must not wait - %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package mirror
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+)
+
+// Diff compares two results of the same read, which are either []*hbase.TCell
+// or []*hbase.TRowResult_, and describes every cell which differs. Cells are
+// compared by value only, as timestamps may differ across clusters.
+func Diff(primary, shadow interface{}) []string {
+	a, b := cells(primary), cells(shadow)
+
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var diffs []string
+	for _, k := range keys {
+		va, oka := a[k]
+		vb, okb := b[k]
+		switch {
+		case !okb:
+			diffs = append(diffs, fmt.Sprintf("%s: primary=%q shadow=<missing>", k, va))
+		case !oka:
+			diffs = append(diffs, fmt.Sprintf("%s: primary=<missing> shadow=%q", k, vb))
+		case va != vb:
+			diffs = append(diffs, fmt.Sprintf("%s: primary=%q shadow=%q", k, va, vb))
+		}
+	}
+	return diffs
+}
+
+// cells flattens a result into the values of its cells keyed by row and column.
+func cells(result interface{}) map[string]string {
+	m := make(map[string]string)
+	switch result := result.(type) {
+	case []*hbase.TCell:
+		for i, cell := range result {
+			if cell != nil {
+				m[fmt.Sprintf("version %d", i)] = string(cell.Value)
+			}
+		}
+	case []*hbase.TRowResult_:
+		for _, r := range result {
+			if r == nil {
+				continue
+			}
+			for column, cell := range r.Columns {
+				if cell != nil {
+					m[string(r.Row)+"/"+column] = string(cell.Value)
+				}
+			}
+			for _, column := range r.SortedColumns {
+				if column != nil && column.Cell != nil {
+					m[string(r.Row)+"/"+string(column.ColumnName)] = string(column.Cell.Value)
+				}
+			}
+		}
+	}
+	return m
+}
@@ -0,0 +1,390 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+
+	"github.com/valyala/fastrand"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+	"github.com/popeyeio/gohbase/internal/clone"
+	"github.com/popeyeio/gohbase/pool"
+)
+
+var (
+	ErrMirrorClosed = errors.New("[gohbase] mirror is closed")
+)
+
+const (
+	defaultQueueSize   = 1024
+	defaultConcurrency = 4
+)
+
+var (
+	discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+)
+
+// Mirror replays a sample of the calls of a pool to a shadow cluster,
+// installed in the pool as an interceptor. Calls are replayed asynchronously
+// once they complete on the primary, and are dropped if the replay queue is
+// full, so that the shadow never affects the latency or the errors of the
+// primary. Writes are only replayed if they succeed on the primary.
+//
+// Only the point reads and writes are mirrored, scanners are bound to the
+// connection they are opened on and admin calls are never mirrored. The
+// arguments are copied when a call is queued, so the caller may reuse them.
+// Increments and CheckAndPut are replayed once without retries, as a retry
+// may apply them twice.
+type Mirror interface {
+	// Interceptor returns the interceptor to install with pool.WithInterceptors.
+	Interceptor() pool.Interceptor
+	// Close stops mirroring, and waits until the queued calls are replayed
+	// or ctx is done.
+	Close(ctx context.Context) error
+	Stats() Stats
+}
+
+// Stats contains statistics of a Mirror.
+type Stats struct {
+	// Mirrored is the total number of calls replayed to the shadow.
+	Mirrored int64
+	// Dropped is the total number of sampled calls dropped as the queue is full.
+	Dropped int64
+	// Failed is the total number of calls which failed on the shadow.
+	Failed int64
+	// Compared is the total number of reads compared.
+	Compared int64
+	// Mismatches is the total number of reads whose results differ.
+	Mismatches int64
+}
+
+type mirror struct {
+	shadow pool.Executor
+
+	percent     float64
+	classes     map[pool.OpClass]bool
+	compare     bool
+	logger      *slog.Logger
+	queueSize   int
+	concurrency int
+
+	jobs   chan *job
+	closed int32
+	wg     sync.WaitGroup
+
+	stats Stats
+}
+
+var _ Mirror = (*mirror)(nil)
+
+// job is a call to replay, with its result on the primary.
+type job struct {
+	method     string
+	table      string
+	rows       []string
+	attributes map[string]string
+	args       []interface{}
+	result     interface{}
+	err        error
+}
+
+type Option func(*mirror)
+
+// WithPercent mirrors percent of the calls, 100 by default.
+func WithPercent(percent float64) Option {
+	return func(m *mirror) {
+		if percent >= 0 && percent <= 100 {
+			m.percent = percent
+		}
+	}
+}
+
+// WithClasses sets the classes of calls to mirror, pool.OpRead by default.
+// Only pool.OpRead and pool.OpWrite are mirrored.
+func WithClasses(classes ...pool.OpClass) Option {
+	return func(m *mirror) {
+		m.classes = make(map[pool.OpClass]bool)
+		for _, class := range classes {
+			if class == pool.OpRead || class == pool.OpWrite {
+				m.classes[class] = true
+			}
+		}
+	}
+}
+
+// WithComparison compares the results of mirrored reads, and logs the
+// mismatches with their rows and differing cells.
+func WithComparison(enabled bool) Option {
+	return func(m *mirror) {
+		m.compare = enabled
+	}
+}
+
+// WithLogger sets the logger mismatches and shadow errors are logged to.
+func WithLogger(logger *slog.Logger) Option {
+	return func(m *mirror) {
+		if logger != nil {
+			m.logger = logger
+		}
+	}
+}
+
+// WithQueueSize sets the max number of calls queued for replay.
+func WithQueueSize(n int) Option {
+	return func(m *mirror) {
+		if n > 0 {
+			m.queueSize = n
+		}
+	}
+}
+
+// WithConcurrency sets the number of goroutines replaying calls.
+func WithConcurrency(n int) Option {
+	return func(m *mirror) {
+		if n > 0 {
+			m.concurrency = n
+		}
+	}
+}
+
+func NewMirror(shadow pool.Executor, opts ...Option) Mirror {
+	m := &mirror{
+		shadow:      shadow,
+		percent:     100,
+		classes:     map[pool.OpClass]bool{pool.OpRead: true},
+		logger:      discardLogger,
+		queueSize:   defaultQueueSize,
+		concurrency: defaultConcurrency,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	m.jobs = make(chan *job, m.queueSize)
+	for i := 0; i < m.concurrency; i++ {
+		m.wg.Add(1)
+		go m.run()
+	}
+	return m
+}
+
+func (m *mirror) Interceptor() pool.Interceptor {
+	return m.intercept
+}
+
+func (m *mirror) Close(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt32(&m.closed, 0, 1) {
+		return ErrMirrorClosed
+	}
+
+	// the interceptor may still be sending, so jobs is never closed, and the
+	// workers are stopped by a nil job each once the queue is drained
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		for i := 0; i < m.concurrency; i++ {
+			m.jobs <- nil
+		}
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (m *mirror) Stats() Stats {
+	return Stats{
+		Mirrored:   atomic.LoadInt64(&m.stats.Mirrored),
+		Dropped:    atomic.LoadInt64(&m.stats.Dropped),
+		Failed:     atomic.LoadInt64(&m.stats.Failed),
+		Compared:   atomic.LoadInt64(&m.stats.Compared),
+		Mismatches: atomic.LoadInt64(&m.stats.Mismatches),
+	}
+}
+
+func (m *mirror) intercept(call *pool.Call, next pool.Handler) error {
+	err := next(call)
+
+	class := pool.ClassOf(call.Method)
+	if atomic.LoadInt32(&m.closed) == 1 || !m.classes[class] || (class == pool.OpWrite && err != nil) || !m.sample() {
+		return err
+	}
+
+	attrs := make(map[string]string, len(call.Attributes))
+	for k, v := range call.Attributes {
+		attrs[k] = v
+	}
+	j := &job{
+		method:     call.Method,
+		table:      call.Table,
+		rows:       append([]string(nil), call.Rows...),
+		attributes: attrs,
+		args:       copyArgs(call.Args),
+		result:     call.Result,
+		err:        err,
+	}
+
+	select {
+	case m.jobs <- j:
+	default:
+		atomic.AddInt64(&m.stats.Dropped, 1)
+	}
+	return err
+}
+
+func (m *mirror) sample() bool {
+	if m.percent >= 100 {
+		return true
+	}
+	return float64(fastrand.Uint32n(10000)) < m.percent*100
+}
+
+func (m *mirror) run() {
+	defer m.wg.Done()
+
+	for j := range m.jobs {
+		if j == nil {
+			return
+		}
+		m.replay(j)
+	}
+}
+
+func (m *mirror) replay(j *job) {
+	result, err := call(m.shadow, j)
+	atomic.AddInt64(&m.stats.Mirrored, 1)
+	if err != nil {
+		atomic.AddInt64(&m.stats.Failed, 1)
+		m.logger.Warn("[gohbase] mirrored call failed", "method", j.method, "table", j.table, "error", err)
+		return
+	}
+
+	if !m.compare || pool.ClassOf(j.method) != pool.OpRead || j.err != nil {
+		return
+	}
+
+	atomic.AddInt64(&m.stats.Compared, 1)
+	if diffs := Diff(j.result, result); len(diffs) > 0 {
+		atomic.AddInt64(&m.stats.Mismatches, 1)
+		m.logger.Warn("[gohbase] mirrored read mismatch",
+			"method", j.method,
+			"table", j.table,
+			"rows", j.rows,
+			"diffs", diffs,
+		)
+	}
+}
+
+// call replays j on e by its method and arguments.
+func call(e pool.Executor, j *job) (result interface{}, err error) {
+	a := j.args
+	str := func(i int) string {
+		s, _ := a[i].(string)
+		return s
+	}
+	strs := func(i int) []string {
+		s, _ := a[i].([]string)
+		return s
+	}
+
+	switch j.method {
+	case "Get":
+		return e.Get(str(0), str(1), str(2), j.attributes)
+	case "GetVer":
+		numVersions, _ := a[3].(int32)
+		return e.GetVer(str(0), str(1), str(2), numVersions, j.attributes)
+	case "GetRow":
+		return e.GetRow(str(0), str(1), j.attributes)
+	case "GetRowWithColumns":
+		return e.GetRowWithColumns(str(0), str(1), strs(2), j.attributes)
+	case "GetRows":
+		return e.GetRows(str(0), strs(1), j.attributes)
+	case "GetRowsWithColumns":
+		return e.GetRowsWithColumns(str(0), strs(1), strs(2), j.attributes)
+	case "MutateRow":
+		mutations, _ := a[2].([]*hbase.Mutation)
+		return nil, e.MutateRow(str(0), str(1), mutations, j.attributes)
+	case "MutateRows":
+		batches, _ := a[1].([]*hbase.BatchMutation)
+		return nil, e.MutateRows(str(0), batches, j.attributes)
+	case "MutateRowsTs":
+		batches, _ := a[1].([]*hbase.BatchMutation)
+		timestamp, _ := a[2].(int64)
+		return nil, e.MutateRowsTs(str(0), batches, timestamp, j.attributes)
+	case "DeleteAll":
+		return nil, e.DeleteAll(str(0), str(1), str(2), j.attributes)
+	case "DeleteAllRow":
+		return nil, e.DeleteAllRow(str(0), str(1), j.attributes)
+	case "CheckAndPut":
+		value, _ := a[3].([]byte)
+		mput, _ := a[4].(*hbase.Mutation)
+		err = once(e, func(c pool.Client) (err error) {
+			result, err = c.CheckAndPut(str(0), str(1), str(2), value, mput, j.attributes)
+			return
+		})
+		return
+	case "AtomicIncrement":
+		value, _ := a[3].(int64)
+		err = once(e, func(c pool.Client) (err error) {
+			result, err = c.AtomicIncrement(str(0), str(1), str(2), value)
+			return
+		})
+		return
+	case "Increment":
+		increment, _ := a[0].(*hbase.TIncrement)
+		return nil, once(e, func(c pool.Client) error {
+			return c.Increment(increment)
+		})
+	case "IncrementRows":
+		increments, _ := a[0].([]*hbase.TIncrement)
+		return nil, once(e, func(c pool.Client) error {
+			return c.IncrementRows(increments)
+		})
+	}
+	return nil, nil
+}
+
+// once performs f on e without retries.
+func once(e pool.Executor, f func(pool.Client) error) error {
+	return e.Do(context.Background(), f)
+}
+
+// copyArgs deep copies the slices and structs of args, which the caller may
+// reuse once the call returns.
+func copyArgs(args []interface{}) []interface{} {
+	cp := make([]interface{}, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case []string:
+			cp[i] = append([]string(nil), v...)
+		case []byte:
+			cp[i] = append([]byte(nil), v...)
+		case *hbase.Mutation:
+			cp[i] = clone.Mutation(v)
+		case []*hbase.Mutation:
+			cp[i] = clone.Mutations(v)
+		case []*hbase.BatchMutation:
+			cp[i] = clone.BatchMutations(v)
+		case *hbase.TIncrement:
+			cp[i] = clone.Increment(v)
+		case []*hbase.TIncrement:
+			cp[i] = clone.Increments(v)
+		default:
+			cp[i] = arg
+		}
+	}
+	return cp
+}
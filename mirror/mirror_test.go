@@ -0,0 +1,125 @@
+package mirror
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+	"github.com/popeyeio/gohbase/pool"
+)
+
+type fakeExecutor struct {
+	pool.Executor
+	sync.Mutex
+	value   string
+	mutated []string
+}
+
+func (e *fakeExecutor) GetRow(name, row string, attributes map[string]string) ([]*hbase.TRowResult_, error) {
+	return rowResult(row, e.value), nil
+}
+
+func (e *fakeExecutor) MutateRow(name, row string, mutations []*hbase.Mutation, attributes map[string]string) error {
+	e.Lock()
+	e.mutated = append(e.mutated, row)
+	e.Unlock()
+	return nil
+}
+
+func rowResult(row, value string) []*hbase.TRowResult_ {
+	return []*hbase.TRowResult_{{
+		Row:     hbase.Text(row),
+		Columns: map[string]*hbase.TCell{"f:v": {Value: hbase.Bytes(value)}},
+	}}
+}
+
+func TestMirror(t *testing.T) {
+	shadow := &fakeExecutor{value: "shadow"}
+	m := NewMirror(shadow, WithClasses(pool.OpRead, pool.OpWrite), WithComparison(true))
+	interceptor := m.Interceptor()
+
+	read := &pool.Call{Method: "GetRow", Table: "t", Rows: []string{"r"}, Args: []interface{}{"t", "r"}}
+	interceptor(read, func(call *pool.Call) error {
+		call.Result = rowResult("r", "primary")
+		return nil
+	})
+	write := &pool.Call{Method: "MutateRow", Table: "t", Rows: []string{"w"}, Args: []interface{}{"t", "w", []*hbase.Mutation(nil)}}
+	interceptor(write, func(*pool.Call) error { return nil })
+	interceptor(&pool.Call{Method: "GetTableNames"}, func(*pool.Call) error { return nil })
+
+	if err := m.Close(context.Background()); err != nil {
+		t.Fatalf("Close error - %v", err)
+	}
+	if s := m.Stats(); s.Mirrored != 2 || s.Compared != 1 || s.Mismatches != 1 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+	if len(shadow.mutated) != 1 || shadow.mutated[0] != "w" {
+		t.Errorf("expected the write to be mirrored, got %v", shadow.mutated)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	diffs := Diff(rowResult("r", "a"), append(rowResult("r", "b"), rowResult("s", "c")...))
+	expected := []string{`r/f:v: primary="a" shadow="b"`, `s/f:v: primary=<missing> shadow="c"`}
+	if len(diffs) != len(expected) || diffs[0] != expected[0] || diffs[1] != expected[1] {
+		t.Errorf("expected diffs %v, got %v", expected, diffs)
+	}
+}
+
+type incrementClient struct {
+	pool.Client
+	e *incrementExecutor
+}
+
+func (c *incrementClient) AtomicIncrement(name, row, column string, value int64) (int64, error) {
+	c.e.calls++
+	return 0, c.e.err
+}
+
+// incrementExecutor performs every Do once, and records the mutated rows.
+type incrementExecutor struct {
+	pool.Executor
+	err   error
+	calls int
+	rows  []string
+}
+
+func (e *incrementExecutor) Do(ctx context.Context, f func(pool.Client) error) error {
+	return f(&incrementClient{e: e})
+}
+
+func (e *incrementExecutor) MutateRows(name string, rowBatches []*hbase.BatchMutation, attributes map[string]string) error {
+	for _, b := range rowBatches {
+		e.rows = append(e.rows, string(b.Row))
+	}
+	return nil
+}
+
+func TestMirror_Replay(t *testing.T) {
+	shadow := &incrementExecutor{err: pool.ErrPoolFull}
+	m := NewMirror(shadow, WithClasses(pool.OpWrite), WithConcurrency(1))
+	interceptor := m.Interceptor()
+
+	// the caller reuses its batches once the call returns
+	batches := []*hbase.BatchMutation{{Row: hbase.Text("a")}}
+	interceptor(&pool.Call{Method: "MutateRows", Table: "t", Args: []interface{}{"t", batches}},
+		func(*pool.Call) error { return nil })
+	batches[0].Row[0] = 'b'
+
+	interceptor(&pool.Call{Method: "AtomicIncrement", Table: "t", Args: []interface{}{"t", "r", "f:q", int64(1)}},
+		func(*pool.Call) error { return nil })
+
+	if err := m.Close(context.Background()); err != nil {
+		t.Fatalf("Close error - %v", err)
+	}
+	if len(shadow.rows) != 1 || shadow.rows[0] != "a" {
+		t.Errorf("expected the queued row a to be replayed, got %v", shadow.rows)
+	}
+	if shadow.calls != 1 {
+		t.Errorf("increment is replayed %d times", shadow.calls)
+	}
+	if s := m.Stats(); s.Mirrored != 2 || s.Failed != 1 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
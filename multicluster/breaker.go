@@ -0,0 +1,101 @@
+package multicluster
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	stateClosed    = "closed"
+	stateOpen      = "open"
+	stateRecovered = "recovered"
+)
+
+// breaker is the circuit breaker of a cluster. It opens after a number of
+// consecutive failures, lets a trial call through every cooldown while open,
+// and turns recovered on the first success. A recovered circuit closes once
+// it has been stable for the stable period, and opens again on any failure.
+type breaker struct {
+	sync.Mutex
+
+	threshold int
+	cooldown  time.Duration
+	stable    time.Duration
+
+	state     string
+	failures  int
+	since     time.Time
+	lastTrial time.Time
+}
+
+func newBreaker(threshold int, cooldown, stable time.Duration) *breaker {
+	return &breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		stable:    stable,
+		state:     stateClosed,
+	}
+}
+
+// usable reports whether the circuit is closed.
+func (b *breaker) usable() bool {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.state == stateRecovered && !now().Before(b.since.Add(b.stable)) {
+		b.state = stateClosed
+		b.since = now()
+	}
+	return b.state == stateClosed
+}
+
+// available reports whether the circuit is not open.
+func (b *breaker) available() bool {
+	b.usable()
+
+	b.Lock()
+	defer b.Unlock()
+	return b.state != stateOpen
+}
+
+// trial reports whether a trial call may go through a circuit which is not
+// closed, at most once every cooldown.
+func (b *breaker) trial() bool {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.state == stateClosed || now().Before(b.lastTrial.Add(b.cooldown)) {
+		return false
+	}
+	b.lastTrial = now()
+	return true
+}
+
+func (b *breaker) record(failed bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	if !failed {
+		b.failures = 0
+		if b.state == stateOpen {
+			b.state = stateRecovered
+			b.since = now()
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == stateRecovered || (b.state == stateClosed && b.failures >= b.threshold) {
+		b.state = stateOpen
+		b.since = now()
+		b.lastTrial = now()
+	}
+}
+
+func (b *breaker) current() string {
+	b.usable()
+
+	b.Lock()
+	defer b.Unlock()
+	return b.state
+}
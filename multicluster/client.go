@@ -0,0 +1,313 @@
+package multicluster
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+	"github.com/popeyeio/gohbase/pool"
+)
+
+var (
+	ErrCircuitOpen = errors.New("[gohbase] circuit is open")
+
+	now = time.Now
+)
+
+const (
+	defaultFailureThreshold = 5
+	defaultCooldown         = time.Second * 5
+	defaultStablePeriod     = time.Minute
+)
+
+// Cluster is an hbase cluster served by a pool.
+type Cluster struct {
+	Name     string
+	Executor pool.Executor
+}
+
+// NewCluster creates a Cluster named name out of p. The name is usually the
+// GetCluster of the instances of p.
+func NewCluster(name string, p pool.Pool, opts ...pool.ExecutorOption) Cluster {
+	return Cluster{
+		Name:     name,
+		Executor: pool.NewExecutor(p, opts...),
+	}
+}
+
+// Client routes calls to a primary cluster, and fails over to the secondary
+// clusters in order while the circuit of the primary is open. Reads fail
+// over, and writes only if write failover is enabled, as the clusters are
+// assumed to replicate each other. Calls return to the primary once its
+// circuit has been stable for the stable period.
+//
+// Every method returns the name of the cluster which served the call.
+type Client interface {
+	// Do calls f with a client borrowed from the cluster which serves calls
+	// of class. f may be called once per cluster if it fails over.
+	Do(context.Context, pool.OpClass, func(pool.Client) error) (string, error)
+
+	Get(string, string, string, map[string]string) ([]*hbase.TCell, string, error)
+	GetRow(string, string, map[string]string) ([]*hbase.TRowResult_, string, error)
+	GetRowWithColumns(string, string, []string, map[string]string) ([]*hbase.TRowResult_, string, error)
+	GetRows(string, []string, map[string]string) ([]*hbase.TRowResult_, string, error)
+	GetRowsWithColumns(string, []string, []string, map[string]string) ([]*hbase.TRowResult_, string, error)
+	MutateRow(string, string, []*hbase.Mutation, map[string]string) (string, error)
+	MutateRows(string, []*hbase.BatchMutation, map[string]string) (string, error)
+	DeleteAll(string, string, string, map[string]string) (string, error)
+	DeleteAllRow(string, string, map[string]string) (string, error)
+	CheckAndPut(string, string, string, []byte, *hbase.Mutation, map[string]string) (bool, string, error)
+
+	Stats() []ClusterStats
+}
+
+// ClusterStats contains statistics of a cluster of a Client.
+type ClusterStats struct {
+	Name string
+	// State is the state of the circuit, "closed", "open" or "recovered".
+	State string
+	// Calls is the total number of calls served.
+	Calls int64
+	// Failures is the total number of failed calls.
+	Failures int64
+	// Failovers is the total number of calls failed over to the cluster.
+	Failovers int64
+}
+
+type client struct {
+	clusters []*cluster
+
+	threshold     int
+	cooldown      time.Duration
+	stable        time.Duration
+	writeFailover bool
+	isFailure     func(error) bool
+}
+
+var _ Client = (*client)(nil)
+
+type cluster struct {
+	name string
+	e    pool.Executor
+	b    *breaker
+
+	calls     int64
+	failures  int64
+	failovers int64
+}
+
+type Option func(*client)
+
+// WithFailureThreshold opens the circuit of a cluster after n consecutive failures.
+func WithFailureThreshold(n int) Option {
+	return func(c *client) {
+		if n > 0 {
+			c.threshold = n
+		}
+	}
+}
+
+// WithCooldown sets how often a trial call is sent to a cluster whose circuit
+// is open.
+func WithCooldown(d time.Duration) Option {
+	return func(c *client) {
+		if d > 0 {
+			c.cooldown = d
+		}
+	}
+}
+
+// WithStablePeriod sets how long the circuit of a recovered cluster must stay
+// free of failures before calls fail back to it.
+func WithStablePeriod(d time.Duration) Option {
+	return func(c *client) {
+		if d >= 0 {
+			c.stable = d
+		}
+	}
+}
+
+// WithWriteFailover enables writes to fail over to the secondary clusters.
+// Calls which are not idempotent, such as CheckAndPut, never fail over
+// regardless, as they may have been applied by the cluster they failed on.
+func WithWriteFailover(enabled bool) Option {
+	return func(c *client) {
+		c.writeFailover = enabled
+	}
+}
+
+// WithFailureClassifier sets the function which decides if an error counts as
+// a failure of the cluster, pool.IsRetryable by default.
+func WithFailureClassifier(isFailure func(error) bool) Option {
+	return func(c *client) {
+		if isFailure != nil {
+			c.isFailure = isFailure
+		}
+	}
+}
+
+func NewClient(primary Cluster, secondaries []Cluster, opts ...Option) Client {
+	c := &client{
+		threshold: defaultFailureThreshold,
+		cooldown:  defaultCooldown,
+		stable:    defaultStablePeriod,
+		isFailure: pool.IsRetryable,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	for _, cl := range append([]Cluster{primary}, secondaries...) {
+		c.clusters = append(c.clusters, &cluster{
+			name: cl.Name,
+			e:    cl.Executor,
+			b:    newBreaker(c.threshold, c.cooldown, c.stable),
+		})
+	}
+	return c
+}
+
+func (c *client) Stats() []ClusterStats {
+	stats := make([]ClusterStats, len(c.clusters))
+	for i, cl := range c.clusters {
+		stats[i] = ClusterStats{
+			Name:      cl.name,
+			State:     cl.b.current(),
+			Calls:     atomic.LoadInt64(&cl.calls),
+			Failures:  atomic.LoadInt64(&cl.failures),
+			Failovers: atomic.LoadInt64(&cl.failovers),
+		}
+	}
+	return stats
+}
+
+// route returns the clusters to try in order for a call which may fail over
+// or not.
+func (c *client) route(failover bool) []*cluster {
+	primary := c.clusters[0]
+	if !failover {
+		if primary.b.available() || primary.b.trial() {
+			return []*cluster{primary}
+		}
+		return nil
+	}
+
+	var targets []*cluster
+	for _, cl := range c.clusters {
+		if cl.b.usable() || cl.b.trial() {
+			targets = append(targets, cl)
+		}
+	}
+	return targets
+}
+
+// failover reports whether idempotent calls of class may fail over.
+func (c *client) failover(class pool.OpClass) bool {
+	return class != pool.OpWrite || c.writeFailover
+}
+
+func (c *client) call(failover bool, f func(pool.Executor) error) (string, error) {
+	targets := c.route(failover)
+	if len(targets) == 0 {
+		return c.clusters[0].name, ErrCircuitOpen
+	}
+
+	var err error
+	for _, cl := range targets {
+		if cl != c.clusters[0] {
+			atomic.AddInt64(&cl.failovers, 1)
+		}
+		atomic.AddInt64(&cl.calls, 1)
+
+		err = f(cl.e)
+		failed := err != nil && c.isFailure(err)
+		cl.b.record(failed)
+		if !failed {
+			return cl.name, err
+		}
+		atomic.AddInt64(&cl.failures, 1)
+	}
+	return targets[len(targets)-1].name, err
+}
+
+func (c *client) Do(ctx context.Context, class pool.OpClass, f func(pool.Client) error) (string, error) {
+	return c.call(c.failover(class), func(e pool.Executor) error {
+		return e.Do(ctx, f)
+	})
+}
+
+func (c *client) Get(name, row, column string, attributes map[string]string) (rsp []*hbase.TCell, cluster string, err error) {
+	cluster, err = c.call(c.failover(pool.OpRead), func(e pool.Executor) (err error) {
+		rsp, err = e.Get(name, row, column, attributes)
+		return
+	})
+	return
+}
+
+func (c *client) GetRow(name, row string, attributes map[string]string) (rsp []*hbase.TRowResult_, cluster string, err error) {
+	cluster, err = c.call(c.failover(pool.OpRead), func(e pool.Executor) (err error) {
+		rsp, err = e.GetRow(name, row, attributes)
+		return
+	})
+	return
+}
+
+func (c *client) GetRowWithColumns(name, row string, columns []string, attributes map[string]string) (rsp []*hbase.TRowResult_, cluster string, err error) {
+	cluster, err = c.call(c.failover(pool.OpRead), func(e pool.Executor) (err error) {
+		rsp, err = e.GetRowWithColumns(name, row, columns, attributes)
+		return
+	})
+	return
+}
+
+func (c *client) GetRows(name string, rows []string, attributes map[string]string) (rsp []*hbase.TRowResult_, cluster string, err error) {
+	cluster, err = c.call(c.failover(pool.OpRead), func(e pool.Executor) (err error) {
+		rsp, err = e.GetRows(name, rows, attributes)
+		return
+	})
+	return
+}
+
+func (c *client) GetRowsWithColumns(name string, rows, columns []string, attributes map[string]string) (rsp []*hbase.TRowResult_, cluster string, err error) {
+	cluster, err = c.call(c.failover(pool.OpRead), func(e pool.Executor) (err error) {
+		rsp, err = e.GetRowsWithColumns(name, rows, columns, attributes)
+		return
+	})
+	return
+}
+
+func (c *client) MutateRow(name, row string, mutations []*hbase.Mutation, attributes map[string]string) (string, error) {
+	return c.call(c.failover(pool.OpWrite), func(e pool.Executor) error {
+		return e.MutateRow(name, row, mutations, attributes)
+	})
+}
+
+func (c *client) MutateRows(name string, rowBatches []*hbase.BatchMutation, attributes map[string]string) (string, error) {
+	return c.call(c.failover(pool.OpWrite), func(e pool.Executor) error {
+		return e.MutateRows(name, rowBatches, attributes)
+	})
+}
+
+func (c *client) DeleteAll(name, row, column string, attributes map[string]string) (string, error) {
+	return c.call(c.failover(pool.OpWrite), func(e pool.Executor) error {
+		return e.DeleteAll(name, row, column, attributes)
+	})
+}
+
+func (c *client) DeleteAllRow(name, row string, attributes map[string]string) (string, error) {
+	return c.call(c.failover(pool.OpWrite), func(e pool.Executor) error {
+		return e.DeleteAllRow(name, row, attributes)
+	})
+}
+
+func (c *client) CheckAndPut(name, row, column string, value []byte, mput *hbase.Mutation, attributes map[string]string) (rsp bool, cluster string, err error) {
+	// the put may have been applied by a cluster it failed on, so it is not
+	// sent to another one
+	cluster, err = c.call(false, func(e pool.Executor) (err error) {
+		rsp, err = e.CheckAndPut(name, row, column, value, mput, attributes)
+		return
+	})
+	return
+}
@@ -0,0 +1,125 @@
+package multicluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+	"github.com/popeyeio/gohbase/pool"
+)
+
+type fakeExecutor struct {
+	pool.Executor
+	err   error
+	calls int
+}
+
+func (e *fakeExecutor) GetRow(name, row string, attributes map[string]string) ([]*hbase.TRowResult_, error) {
+	e.calls++
+	return nil, e.err
+}
+
+func (e *fakeExecutor) MutateRow(name, row string, mutations []*hbase.Mutation, attributes map[string]string) error {
+	e.calls++
+	return e.err
+}
+
+func (e *fakeExecutor) CheckAndPut(name, row, column string, value []byte, mput *hbase.Mutation, attributes map[string]string) (bool, error) {
+	e.calls++
+	return e.err == nil, e.err
+}
+
+func TestClient_Failover(t *testing.T) {
+	t0 := time.Now()
+	now = func() time.Time { return t0 }
+	defer func() { now = time.Now }()
+
+	primary := &fakeExecutor{err: pool.ErrPoolFull}
+	secondary := &fakeExecutor{}
+	c := NewClient(Cluster{Name: "a", Executor: primary}, []Cluster{{Name: "b", Executor: secondary}},
+		WithFailureThreshold(2), WithCooldown(time.Second), WithStablePeriod(time.Minute))
+
+	for i := 0; i < 2; i++ {
+		if _, cluster, err := c.GetRow("t", "r", nil); err != nil || cluster != "b" {
+			t.Fatalf("expected the read to fail over, got %q, %v", cluster, err)
+		}
+	}
+	if s := c.Stats(); s[0].State != stateOpen || s[1].Failovers != 2 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+
+	if cluster, err := c.MutateRow("t", "r", nil, nil); err != ErrCircuitOpen || cluster != "a" {
+		t.Errorf("expected writes not to fail over, got %q, %v", cluster, err)
+	}
+
+	// a trial call recovers the primary, which only serves calls once stable
+	primary.err = nil
+	now = func() time.Time { return t0.Add(time.Second * 2) }
+	if _, cluster, _ := c.GetRow("t", "r", nil); cluster != "a" {
+		t.Errorf("expected a trial call to the primary, got %q", cluster)
+	}
+	if _, cluster, _ := c.GetRow("t", "r", nil); cluster != "b" {
+		t.Errorf("expected the secondary until the primary is stable, got %q", cluster)
+	}
+
+	now = func() time.Time { return t0.Add(time.Minute * 2) }
+	if _, cluster, _ := c.GetRow("t", "r", nil); cluster != "a" {
+		t.Errorf("expected to fail back to the primary, got %q", cluster)
+	}
+}
+
+func TestClient_CheckAndPutNoFailover(t *testing.T) {
+	primary := &fakeExecutor{err: pool.ErrPoolFull}
+	secondary := &fakeExecutor{}
+	c := NewClient(Cluster{Name: "a", Executor: primary}, []Cluster{{Name: "b", Executor: secondary}},
+		WithWriteFailover(true))
+
+	if ok, cluster, err := c.CheckAndPut("t", "r", "f:q", nil, nil, nil); ok || err != pool.ErrPoolFull || cluster != "a" {
+		t.Errorf("expected the check and put to fail on the primary, got %v, %q, %v", ok, cluster, err)
+	}
+	if secondary.calls != 0 {
+		t.Errorf("check and put is failed over %d times", secondary.calls)
+	}
+
+	if cluster, err := c.MutateRow("t", "r", nil, nil); err != nil || cluster != "b" {
+		t.Errorf("expected the write to fail over, got %q, %v", cluster, err)
+	}
+}
+
+func TestClient_Failback(t *testing.T) {
+	t0 := time.Now()
+	now = func() time.Time { return t0 }
+	defer func() { now = time.Now }()
+
+	primary := &fakeExecutor{err: pool.ErrPoolFull}
+	secondary := &fakeExecutor{}
+	c := NewClient(Cluster{Name: "a", Executor: primary}, []Cluster{{Name: "b", Executor: secondary}},
+		WithFailureThreshold(1), WithCooldown(time.Hour), WithStablePeriod(time.Minute))
+
+	c.GetRow("t", "r", nil)
+	primary.err = nil
+	now = func() time.Time { return t0.Add(time.Hour) }
+	if _, cluster, _ := c.GetRow("t", "r", nil); cluster != "a" {
+		t.Fatalf("expected a trial call to the primary, got %q", cluster)
+	}
+	recovered := now()
+
+	// the recovered primary serves no calls until the end of the stable period
+	now = func() time.Time { return recovered.Add(time.Minute - time.Millisecond) }
+	if _, cluster, _ := c.GetRow("t", "r", nil); cluster != "b" {
+		t.Errorf("expected the secondary within the stable period, got %q", cluster)
+	}
+	if s := c.Stats(); s[0].State != stateRecovered {
+		t.Errorf("expected the primary to be recovered, got %+v", s)
+	}
+
+	now = func() time.Time { return recovered.Add(time.Minute) }
+	for i := 0; i < 2; i++ {
+		if _, cluster, _ := c.GetRow("t", "r", nil); cluster != "a" {
+			t.Errorf("expected to fail back to the primary, got %q", cluster)
+		}
+	}
+	if s := c.Stats(); s[0].State != stateClosed || s[1].Calls != 2 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
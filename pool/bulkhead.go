@@ -0,0 +1,114 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrBulkheadFull = errors.New("[gohbase] bulkhead is full")
+)
+
+type tableKey struct{}
+type callerKey struct{}
+
+// ContextWithTable tags the clients borrowed with ctx as used for table, so
+// that they count against the bulkhead of table. Executor tags its calls.
+func ContextWithTable(ctx context.Context, table string) context.Context {
+	return context.WithValue(ctx, tableKey{}, table)
+}
+
+// ContextWithCallerTag tags the clients borrowed with ctx as used by caller,
+// so that they count against the bulkhead of caller.
+func ContextWithCallerTag(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// bulkhead caps the number of connections borrowed for a table or a caller.
+type bulkhead struct {
+	max int
+	sem chan struct{}
+}
+
+func bulkheadKeys(ctx context.Context) []string {
+	var keys []string
+	if table, _ := ctx.Value(tableKey{}).(string); table != "" {
+		keys = append(keys, "table:"+table)
+	}
+	if caller, _ := ctx.Value(callerKey{}).(string); caller != "" {
+		keys = append(keys, "caller:"+caller)
+	}
+	return keys
+}
+
+// enterBulkheads takes a slot in every bulkhead ctx is tagged with. It fails
+// with ErrBulkheadFull if one of them is full, or waits for a slot as long as
+// GetContext would wait for a connection if bulkhead waiting is enabled.
+func (p *pool) enterBulkheads(ctx context.Context, deadline time.Time) ([]*bulkhead, error) {
+	keys := bulkheadKeys(ctx)
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	cfg := p.conf()
+	var entered []*bulkhead
+	for _, key := range keys {
+		max, ok := cfg.bulkheads[key]
+		if !ok {
+			continue
+		}
+
+		p.Lock()
+		b := p.bulkheads[key]
+		if b == nil || b.max != max {
+			// borrowed clients keep releasing the bulkhead they entered
+			b = &bulkhead{max: max, sem: make(chan struct{}, max)}
+			p.bulkheads[key] = b
+		}
+		p.Unlock()
+
+		if err := p.enterBulkhead(ctx, b, cfg, deadline); err != nil {
+			cfg.logger.Warn("[gohbase] bulkhead is full", "bulkhead", key, "max", max, "error", err)
+			leaveBulkheads(entered)
+			return nil, err
+		}
+		entered = append(entered, b)
+	}
+	return entered, nil
+}
+
+func (p *pool) enterBulkhead(ctx context.Context, b *bulkhead, cfg *config, deadline time.Time) error {
+	select {
+	case b.sem <- struct{}{}:
+		return nil
+	default:
+	}
+	if !cfg.bulkheadWait {
+		return ErrBulkheadFull
+	}
+
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(deadline.Sub(now()))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case b.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timeout:
+		return ErrBulkheadFull
+	case <-p.closeChan:
+		return ErrPoolClosed
+	}
+}
+
+func leaveBulkheads(bs []*bulkhead) {
+	for _, b := range bs {
+		<-b.sem
+	}
+}
@@ -0,0 +1,113 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+)
+
+func TestPool_Bulkhead(t *testing.T) {
+	p := NewPool(WithTableBulkhead("t", 1)).(*pool)
+	defer p.Close()
+
+	for i := 0; i < 2; i++ {
+		p.active++
+		p.idleNodes.PushFront(&idleNode{cn: newMemoryConn(), t: now()})
+	}
+
+	ctx := ContextWithTable(context.Background(), "t")
+	c, err := p.GetContext(ctx)
+	if err != nil {
+		t.Fatalf("GetContext error - %v", err)
+	}
+	if _, err = p.GetContext(ctx); err != ErrBulkheadFull {
+		t.Fatalf("expected error %v, got %v", ErrBulkheadFull, err)
+	}
+	if ErrorClass(err) != ErrorClassRejected || IsRetryable(err) {
+		t.Errorf("bulkhead errors must be rejected and not retryable")
+	}
+
+	other, err := p.GetContext(context.Background())
+	if err != nil {
+		t.Fatalf("untagged clients must not count against the bulkhead - %v", err)
+	}
+	other.Close()
+
+	c.Close()
+	if c, err = p.GetContext(ctx); err != nil {
+		t.Fatalf("bulkhead is not released - %v", err)
+	}
+	c.Close()
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := NewRateLimiter(WithRate("", OpWrite, 1, 2), WithRate("t", OpRead, 1000, 1), WithRateWait(time.Millisecond*10))
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		err := l.Wait(ctx, "t", OpWrite)
+		if expected := i == 2; (err == ErrRateLimited) != expected {
+			t.Errorf("call %d: unexpected error %v", i, err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if err := l.Wait(ctx, "t", OpRead); err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+	}
+	if err := l.Wait(ctx, "", OpAdmin); err != nil {
+		t.Errorf("unlimited class must not be limited - %v", err)
+	}
+	if s := l.Stats(); s.Allowed != 3 || s.Delayed != 1 || s.Rejected != 1 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+
+	// a canceled wait returns its token
+	l = NewRateLimiter(WithRate("", OpRead, 1, 1), WithRateWait(time.Second))
+	if err := l.Wait(ctx, "t", OpRead); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := l.Wait(canceled, "t", OpRead); err != context.Canceled {
+		t.Errorf("expected error %v, got %v", context.Canceled, err)
+	}
+	b := l.(*rateLimiter).buckets[rateKey{class: OpRead}]
+	if b.tokens < -0.1 {
+		t.Errorf("canceled wait keeps its token - %v", b.tokens)
+	}
+}
+
+func TestPool_RateLimit(t *testing.T) {
+	l := NewRateLimiter(WithRate("t", OpRead, 1, 2))
+	p := NewPool(WithRateLimiter(l), WithInterceptors(func(call *Call, next Handler) error {
+		call.Result = []*hbase.TRowResult_{}
+		return nil
+	})).(*pool)
+	defer p.Close()
+
+	p.active++
+	p.idleNodes.PushFront(&idleNode{cn: newMemoryConn(), t: now()})
+
+	// every call made with a borrowed client takes a token
+	var errs []error
+	err := NewExecutor(p).Do(context.Background(), func(c Client) error {
+		for i := 0; i < 3; i++ {
+			_, err := c.GetRow("t", "r", nil)
+			errs = append(errs, err)
+		}
+		_, err := c.GetRow("u", "r", nil)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("a call on a table without a limit error - %v", err)
+	}
+	if errs[0] != nil || errs[1] != nil || errs[2] != ErrRateLimited {
+		t.Errorf("expected the third call to be limited, got %v", errs)
+	}
+	if s := l.Stats(); s.Allowed != 2 || s.Rejected != 1 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
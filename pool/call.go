@@ -0,0 +1,285 @@
+package pool
+
+import (
+	"github.com/popeyeio/gohbase/gen/hbase"
+)
+
+// The calls below are shared by the client and the executor, so that
+// interceptors see the same calls at both levels.
+
+func enableTableCall(name string) *Call {
+	return &Call{
+		Method: "EnableTable",
+		Table:  name,
+		Args:   []interface{}{name},
+	}
+}
+
+func disableTableCall(name string) *Call {
+	return &Call{
+		Method: "DisableTable",
+		Table:  name,
+		Args:   []interface{}{name},
+	}
+}
+
+func isTableEnabledCall(name string) *Call {
+	return &Call{
+		Method: "IsTableEnabled",
+		Table:  name,
+		Args:   []interface{}{name},
+	}
+}
+
+func getTableNamesCall() *Call {
+	return &Call{
+		Method: "GetTableNames",
+	}
+}
+
+func getColumnDescriptorsCall(name string) *Call {
+	return &Call{
+		Method: "GetColumnDescriptors",
+		Table:  name,
+		Args:   []interface{}{name},
+	}
+}
+
+func getTableRegionsCall(name string) *Call {
+	return &Call{
+		Method: "GetTableRegions",
+		Table:  name,
+		Args:   []interface{}{name},
+	}
+}
+
+func createTableCall(name string, cfs []*hbase.ColumnDescriptor) *Call {
+	return &Call{
+		Method: "CreateTable",
+		Table:  name,
+		Args:   []interface{}{name, cfs},
+	}
+}
+
+func deleteTableCall(name string) *Call {
+	return &Call{
+		Method: "DeleteTable",
+		Table:  name,
+		Args:   []interface{}{name},
+	}
+}
+
+func getCall(name, row, column string, attributes map[string]string) *Call {
+	return &Call{
+		Method:     "Get",
+		Table:      name,
+		Rows:       []string{row},
+		Attributes: attributes,
+		Args:       []interface{}{name, row, column},
+	}
+}
+
+func getVerCall(name, row, column string, numVersions int32, attributes map[string]string) *Call {
+	return &Call{
+		Method:     "GetVer",
+		Table:      name,
+		Rows:       []string{row},
+		Attributes: attributes,
+		Args:       []interface{}{name, row, column, numVersions},
+	}
+}
+
+func getRowCall(name, row string, attributes map[string]string) *Call {
+	return &Call{
+		Method:     "GetRow",
+		Table:      name,
+		Rows:       []string{row},
+		Attributes: attributes,
+		Args:       []interface{}{name, row},
+	}
+}
+
+func getRowWithColumnsCall(name, row string, columns []string, attributes map[string]string) *Call {
+	return &Call{
+		Method:     "GetRowWithColumns",
+		Table:      name,
+		Rows:       []string{row},
+		Attributes: attributes,
+		Args:       []interface{}{name, row, columns},
+	}
+}
+
+func getRowsCall(name string, rows []string, attributes map[string]string) *Call {
+	return &Call{
+		Method:     "GetRows",
+		Table:      name,
+		Rows:       rows,
+		Attributes: attributes,
+		Args:       []interface{}{name, rows},
+	}
+}
+
+func getRowsWithColumnsCall(name string, rows, columns []string, attributes map[string]string) *Call {
+	return &Call{
+		Method:     "GetRowsWithColumns",
+		Table:      name,
+		Rows:       rows,
+		Attributes: attributes,
+		Args:       []interface{}{name, rows, columns},
+	}
+}
+
+func mutateRowCall(name, row string, mutations []*hbase.Mutation, attributes map[string]string) *Call {
+	return &Call{
+		Method:     "MutateRow",
+		Table:      name,
+		Rows:       []string{row},
+		Attributes: attributes,
+		Args:       []interface{}{name, row, mutations},
+	}
+}
+
+func mutateRowsCall(name string, rowBatches []*hbase.BatchMutation, attributes map[string]string) *Call {
+	return &Call{
+		Method:     "MutateRows",
+		Table:      name,
+		Rows:       batchRows(rowBatches),
+		Attributes: attributes,
+		Args:       []interface{}{name, rowBatches},
+	}
+}
+
+func mutateRowsTsCall(name string, rowBatches []*hbase.BatchMutation, timestamp int64, attributes map[string]string) *Call {
+	return &Call{
+		Method:     "MutateRowsTs",
+		Table:      name,
+		Rows:       batchRows(rowBatches),
+		Attributes: attributes,
+		Args:       []interface{}{name, rowBatches, timestamp},
+	}
+}
+
+func deleteAllCall(name, row, column string, attributes map[string]string) *Call {
+	return &Call{
+		Method:     "DeleteAll",
+		Table:      name,
+		Rows:       []string{row},
+		Attributes: attributes,
+		Args:       []interface{}{name, row, column},
+	}
+}
+
+func deleteAllRowCall(name, row string, attributes map[string]string) *Call {
+	return &Call{
+		Method:     "DeleteAllRow",
+		Table:      name,
+		Rows:       []string{row},
+		Attributes: attributes,
+		Args:       []interface{}{name, row},
+	}
+}
+
+func checkAndPutCall(name, row, column string, value []byte, mput *hbase.Mutation, attributes map[string]string) *Call {
+	return &Call{
+		Method:     "CheckAndPut",
+		Table:      name,
+		Rows:       []string{row},
+		Attributes: attributes,
+		Args:       []interface{}{name, row, column, value, mput},
+	}
+}
+
+func atomicIncrementCall(name, row, column string, value int64) *Call {
+	return &Call{
+		Method: "AtomicIncrement",
+		Table:  name,
+		Rows:   []string{row},
+		Args:   []interface{}{name, row, column, value},
+	}
+}
+
+func incrementCall(increment *hbase.TIncrement) *Call {
+	call := &Call{
+		Method: "Increment",
+		Args:   []interface{}{increment},
+	}
+	if increment != nil {
+		call.Table = string(increment.Table)
+		call.Rows = []string{string(increment.Row)}
+	}
+	return call
+}
+
+func incrementRowsCall(increments []*hbase.TIncrement) *Call {
+	call := &Call{
+		Method: "IncrementRows",
+		Args:   []interface{}{increments},
+	}
+	for _, increment := range increments {
+		if increment == nil {
+			continue
+		}
+		if call.Table == "" {
+			call.Table = string(increment.Table)
+		}
+		call.Rows = append(call.Rows, string(increment.Row))
+	}
+	return call
+}
+
+func scannerOpenWithScanCall(name string, scan *hbase.TScan, attributes map[string]string) *Call {
+	return &Call{
+		Method:     "ScannerOpenWithScan",
+		Table:      name,
+		Attributes: attributes,
+		Args:       []interface{}{name, scan},
+	}
+}
+
+func scannerOpenCall(name, startRow string, columns []string, attributes map[string]string) *Call {
+	return &Call{
+		Method:     "ScannerOpen",
+		Table:      name,
+		Attributes: attributes,
+		Args:       []interface{}{name, startRow, columns},
+	}
+}
+
+func scannerOpenWithStopCall(name, startRow, stopRow string, columns []string, attributes map[string]string) *Call {
+	return &Call{
+		Method:     "ScannerOpenWithStop",
+		Table:      name,
+		Attributes: attributes,
+		Args:       []interface{}{name, startRow, stopRow, columns},
+	}
+}
+
+func scannerOpenWithPrefixCall(name, startAndPrefix string, columns []string, attributes map[string]string) *Call {
+	return &Call{
+		Method:     "ScannerOpenWithPrefix",
+		Table:      name,
+		Attributes: attributes,
+		Args:       []interface{}{name, startAndPrefix, columns},
+	}
+}
+
+func scannerGetCall(id hbase.ScannerID) *Call {
+	return &Call{
+		Method: "ScannerGet",
+		Args:   []interface{}{id},
+	}
+}
+
+func scannerGetListCall(id hbase.ScannerID, nbRows int32) *Call {
+	return &Call{
+		Method: "ScannerGetList",
+		Args:   []interface{}{id, nbRows},
+	}
+}
+
+func scannerCloseCall(id hbase.ScannerID) *Call {
+	return &Call{
+		Method: "ScannerClose",
+		Args:   []interface{}{id},
+	}
+}
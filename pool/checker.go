@@ -0,0 +1,181 @@
+package pool
+
+import (
+	"container/list"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+)
+
+var (
+	ErrConnNotOpen      = errors.New("[gohbase] connection is not open")
+	ErrConnUnexpectData = errors.New("[gohbase] connection has unexpected data")
+	ErrTableNotEnabled  = errors.New("[gohbase] table is not enabled")
+)
+
+const (
+	// probeTimeout is how long the socket checker waits for the socket.
+	probeTimeout = time.Millisecond
+)
+
+// HealthChecker checks an idle connection before it is reused.
+// lastUsed is the time the connection was returned to the pool.
+type HealthChecker func(hc *hbase.HbaseClient, lastUsed time.Time) error
+
+// NewSocketChecker returns a HealthChecker which probes the socket of the
+// connection, without sending any request. It fails if the socket is closed
+// by the peer, or if there is unexpected data to read.
+func NewSocketChecker() HealthChecker {
+	return func(hc *hbase.HbaseClient, _ time.Time) error {
+		if !hc.Transport.IsOpen() {
+			return ErrConnNotOpen
+		}
+
+		ct, ok := hc.Transport.(*countingTransport)
+		if !ok || ct.socket == nil {
+			return nil
+		}
+
+		c := ct.socket.Conn()
+		if c == nil {
+			return ErrConnNotOpen
+		}
+		return probe(c)
+	}
+}
+
+// probe reads from c with a tiny deadline. A timeout means the connection is
+// alive and idle, anything else means it is unusable.
+func probe(c net.Conn) error {
+	if err := c.SetReadDeadline(now().Add(probeTimeout)); err != nil {
+		return err
+	}
+	defer c.SetReadDeadline(time.Time{})
+
+	var b [1]byte
+	n, err := c.Read(b[:])
+	if n > 0 {
+		return ErrConnUnexpectData
+	}
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		return nil
+	}
+	if err == nil {
+		return ErrConnNotOpen
+	}
+	return err
+}
+
+// NewTableChecker returns a HealthChecker which sends a cheap request,
+// checking if the canary table is enabled.
+func NewTableChecker(table string) HealthChecker {
+	return func(hc *hbase.HbaseClient, _ time.Time) error {
+		enabled, err := hc.IsTableEnabled(hbase.Bytes(table))
+		if err != nil {
+			return err
+		}
+		if !enabled {
+			return ErrTableNotEnabled
+		}
+		return nil
+	}
+}
+
+// SkipRecentlyUsed returns a HealthChecker which skips checker if the
+// connection was used within d.
+func SkipRecentlyUsed(d time.Duration, checker HealthChecker) HealthChecker {
+	return func(hc *hbase.HbaseClient, lastUsed time.Time) error {
+		if now().Sub(lastUsed) < d {
+			return nil
+		}
+		return checker(hc, lastUsed)
+	}
+}
+
+// ChainCheckers returns a HealthChecker which runs checkers in order,
+// and fails on the first error.
+func ChainCheckers(checkers ...HealthChecker) HealthChecker {
+	return func(hc *hbase.HbaseClient, lastUsed time.Time) error {
+		for _, checker := range checkers {
+			if err := checker(hc, lastUsed); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// validateIdleNodes runs the health checker against every idle connection
+// in the background, and closes the unhealthy ones.
+func (p *pool) validateIdleNodes() {
+	cfg := p.conf()
+	if !cfg.backgroundValidation || cfg.healthChecker == nil {
+		return
+	}
+
+	p.Lock()
+	ins := make([]*idleNode, 0, p.idleNodes.Len())
+	for e := p.idleNodes.Front(); e != nil; e = e.Next() {
+		ins = append(ins, e.Value.(*idleNode))
+	}
+	p.Unlock()
+
+	for _, in := range ins {
+		p.Lock()
+		e := p.findIdleNode(in)
+		if e == nil {
+			// it has been taken meanwhile
+			p.Unlock()
+			continue
+		}
+		p.idleNodes.Remove(e)
+		p.Unlock()
+
+		if err := cfg.healthChecker(in.cn.hc, in.t); err != nil {
+			cfg.logger.Warn("[gohbase] health check failed", "instance", in.cn.addr(), "error", err)
+			in.cn.close()
+
+			p.Lock()
+			p.release()
+			p.Unlock()
+			continue
+		}
+
+		p.Lock()
+		if p.IsClosed() {
+			// the idle nodes have been closed meanwhile
+			p.release()
+			p.Unlock()
+			in.cn.close()
+			continue
+		}
+		p.insertIdleNode(in)
+		p.notify()
+		p.Unlock()
+	}
+}
+
+// findIdleNode returns the element of in, or nil if in is not idle.
+// It must be called with p locked.
+func (p *pool) findIdleNode(in *idleNode) *list.Element {
+	for e := p.idleNodes.Front(); e != nil; e = e.Next() {
+		if e.Value.(*idleNode) == in {
+			return e
+		}
+	}
+	return nil
+}
+
+// insertIdleNode inserts in into the idle nodes ordered from the most recently
+// used to the least. It must be called with p locked.
+func (p *pool) insertIdleNode(in *idleNode) {
+	for e := p.idleNodes.Front(); e != nil; e = e.Next() {
+		if e.Value.(*idleNode).t.Before(in.t) {
+			p.idleNodes.InsertBefore(in, e)
+			return
+		}
+	}
+	p.idleNodes.PushBack(in)
+}
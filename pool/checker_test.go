@@ -0,0 +1,64 @@
+package pool
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+	"github.com/popeyeio/gohbase/lib/thrift"
+)
+
+func TestSocketChecker(t *testing.T) {
+	local, remote := net.Pipe()
+	defer local.Close()
+
+	socket := thrift.NewTSocketFromConnTimeout(local, 0)
+	ct := newCountingTransport(thrift.NewTBufferedTransport(socket, 4096), socket)
+	hc := hbase.NewHbaseClientFactory(ct, thrift.NewTBinaryProtocolFactoryDefault())
+
+	checker := NewSocketChecker()
+	if err := checker(hc, now()); err != nil {
+		t.Errorf("alive connection check error - %v", err)
+	}
+
+	remote.Close()
+	if err := checker(hc, now()); err == nil {
+		t.Errorf("expected error for connection closed by peer")
+	}
+
+	skipped := SkipRecentlyUsed(time.Minute, checker)
+	if err := skipped(hc, now()); err != nil {
+		t.Errorf("recently used connection must not be checked - %v", err)
+	}
+	if err := skipped(hc, now().Add(-time.Hour)); err == nil {
+		t.Errorf("expected error for connection closed by peer")
+	}
+}
+
+func TestPool_ValidateIdleNodesClosed(t *testing.T) {
+	var p *pool
+	p = NewPool(
+		WithHealthChecker(func(*hbase.HbaseClient, time.Time) error {
+			// the pool is closed while the connection is checked
+			p.Close()
+			return nil
+		}),
+		WithBackgroundValidation(true),
+	).(*pool)
+
+	p.active = 1
+	p.put(newMemoryConn(), false)
+	p.validateIdleNodes()
+
+	if s := p.Stats(); s.Active != 0 || s.Idle != 0 {
+		t.Errorf("connection checked while closing is not released - %+v", s)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := p.Shutdown(ctx); err != nil {
+		t.Errorf("shutdown error - %v", err)
+	}
+}
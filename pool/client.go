@@ -1,7 +1,9 @@
 package pool
 
 import (
+	"context"
 	"errors"
+	"runtime"
 	"sync"
 	"sync/atomic"
 
@@ -15,11 +17,17 @@ var (
 type client struct {
 	sync.Mutex
 
-	p    *pool
-	hc   *hbase.HbaseClient
-	errs Errors
-
-	closed int32
+	p           *pool
+	conn        *conn
+	interceptor Interceptor
+	tracer      Tracer
+	propagate   bool
+	rateLimiter RateLimiter
+	errs        Errors
+	lease       *lease
+
+	closed    int32
+	abandoned int32
 }
 
 var _ Client = (*client)(nil)
@@ -29,412 +37,482 @@ func (c *client) Close() error {
 		return ErrClientClosed
 	}
 
+	if c.lease != nil {
+		runtime.SetFinalizer(c, nil)
+		if !c.p.untrack(c.lease) {
+			return ErrClientReclaimed
+		}
+	}
+
 	c.Lock()
 	defer c.Unlock()
 
-	if c.p == nil || c.hc == nil {
+	if c.p == nil || c.conn == nil {
 		return nil
 	}
-	return c.p.put(c.hc, c.errs.Len() > 0)
+	return c.p.put(c.conn, c.errs.Len() > 0 || atomic.LoadInt32(&c.abandoned) == 1)
 }
 
 func (c *client) IsClosed() bool {
+	if c.lease != nil && atomic.LoadInt32(&c.lease.reclaimed) == 1 {
+		return true
+	}
 	return atomic.LoadInt32(&c.closed) == 1
 }
 
-func (c *client) EnableTable(name string) (err error) {
-	if c.IsClosed() {
-		return ErrClientClosed
-	}
-
-	c.Lock()
-	defer c.Unlock()
-
-	err = c.hc.EnableTable(hbase.Bytes(name))
-	c.errs.Add(err)
-	return
+func (c *client) EnableTable(name string) error {
+	call := enableTableCall(name)
+	return c.invoke(call, func(call *Call) error {
+		return c.conn.hc.EnableTable(hbase.Bytes(name))
+	})
 }
 
-func (c *client) DisableTable(name string) (err error) {
-	if c.IsClosed() {
-		return ErrClientClosed
-	}
-
-	c.Lock()
-	defer c.Unlock()
-
-	err = c.hc.DisableTable(hbase.Bytes(name))
-	c.errs.Add(err)
-	return
+func (c *client) DisableTable(name string) error {
+	call := disableTableCall(name)
+	return c.invoke(call, func(call *Call) error {
+		return c.conn.hc.DisableTable(hbase.Bytes(name))
+	})
 }
 
 func (c *client) IsTableEnabled(name string) (rsp bool, err error) {
-	if c.IsClosed() {
-		return false, ErrClientClosed
-	}
-
-	c.Lock()
-	defer c.Unlock()
-
-	rsp, err = c.hc.IsTableEnabled(hbase.Bytes(name))
-	c.errs.Add(err)
+	call := isTableEnabledCall(name)
+	err = c.invoke(call, func(call *Call) (err error) {
+		call.Result, err = c.conn.hc.IsTableEnabled(hbase.Bytes(name))
+		return
+	})
+	rsp, _ = call.Result.(bool)
 	return
 }
 
-func (c *client) GetTableNames() ([]string, error) {
-	if c.IsClosed() {
-		return nil, ErrClientClosed
-	}
-
-	c.Lock()
-	defer c.Unlock()
-
-	res, err := c.hc.GetTableNames()
-	c.errs.Add(err)
+func (c *client) GetTableNames() (rsp []string, err error) {
+	call := getTableNamesCall()
+	err = c.invoke(call, func(call *Call) error {
+		res, err := c.conn.hc.GetTableNames()
+		if err != nil {
+			return err
+		}
+
+		names := make([]string, len(res))
+		for i := range res {
+			names[i] = string(res[i])
+		}
+		call.Result = names
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-
-	names := make([]string, len(res))
-	for i := range res {
-		names[i] = string(res[i])
-	}
-	return names, nil
+	rsp, _ = call.Result.([]string)
+	return
 }
 
 func (c *client) GetColumnDescriptors(name string) (rsp map[string]*hbase.ColumnDescriptor, err error) {
-	if c.IsClosed() {
-		return nil, ErrClientClosed
-	}
-
-	c.Lock()
-	defer c.Unlock()
-
-	rsp, err = c.hc.GetColumnDescriptors(hbase.Text(name))
-	c.errs.Add(err)
+	call := getColumnDescriptorsCall(name)
+	err = c.invoke(call, func(call *Call) (err error) {
+		call.Result, err = c.conn.hc.GetColumnDescriptors(hbase.Text(name))
+		return
+	})
+	rsp, _ = call.Result.(map[string]*hbase.ColumnDescriptor)
 	return
 }
 
 func (c *client) GetTableRegions(name string) (rsp []*hbase.TRegionInfo, err error) {
-	if c.IsClosed() {
-		return nil, ErrClientClosed
-	}
-
-	c.Lock()
-	defer c.Unlock()
-
-	rsp, err = c.hc.GetTableRegions(hbase.Text(name))
-	c.errs.Add(err)
+	call := getTableRegionsCall(name)
+	err = c.invoke(call, func(call *Call) (err error) {
+		call.Result, err = c.conn.hc.GetTableRegions(hbase.Text(name))
+		return
+	})
+	rsp, _ = call.Result.([]*hbase.TRegionInfo)
 	return
 }
 
-func (c *client) CreateTable(name string, cfs []*hbase.ColumnDescriptor) (err error) {
-	if c.IsClosed() {
-		return ErrClientClosed
-	}
-
-	c.Lock()
-	defer c.Unlock()
-
-	err = c.hc.CreateTable(hbase.Text(name), cfs)
-	c.errs.Add(err)
-	return
+func (c *client) CreateTable(name string, cfs []*hbase.ColumnDescriptor) error {
+	call := createTableCall(name, cfs)
+	return c.invoke(call, func(call *Call) error {
+		return c.conn.hc.CreateTable(hbase.Text(name), cfs)
+	})
 }
 
-func (c *client) DeleteTable(name string) (err error) {
-	if c.IsClosed() {
-		return ErrClientClosed
-	}
-
-	c.Lock()
-	defer c.Unlock()
-
-	err = c.hc.DeleteTable(hbase.Text(name))
-	c.errs.Add(err)
-	return
+func (c *client) DeleteTable(name string) error {
+	call := deleteTableCall(name)
+	return c.invoke(call, func(call *Call) error {
+		return c.conn.hc.DeleteTable(hbase.Text(name))
+	})
 }
 
 func (c *client) Get(name, row, column string, attributes map[string]string) (rsp []*hbase.TCell, err error) {
-	if c.IsClosed() {
-		return nil, ErrClientClosed
-	}
-
-	c.Lock()
-	defer c.Unlock()
-
-	n := hbase.Text(name)
-	r := hbase.Text(row)
-	col := hbase.Text(column)
-	attrs := make(map[string]hbase.Text)
-	for k, v := range attributes {
-		attrs[k] = hbase.Text(v)
-	}
+	call := getCall(name, row, column, attributes)
+	err = c.invoke(call, func(call *Call) (err error) {
+		n := hbase.Text(name)
+		r := hbase.Text(row)
+		col := hbase.Text(column)
+		attrs := toTextMap(call.Attributes)
+
+		call.Result, err = c.conn.hc.Get(n, r, col, attrs)
+		return
+	})
+	rsp, _ = call.Result.([]*hbase.TCell)
+	return
+}
 
-	rsp, err = c.hc.Get(n, r, col, attrs)
-	c.errs.Add(err)
+func (c *client) GetVer(name, row, column string, numVersions int32, attributes map[string]string) (rsp []*hbase.TCell, err error) {
+	call := getVerCall(name, row, column, numVersions, attributes)
+	err = c.invoke(call, func(call *Call) (err error) {
+		n := hbase.Text(name)
+		r := hbase.Text(row)
+		col := hbase.Text(column)
+		attrs := toTextMap(call.Attributes)
+
+		call.Result, err = c.conn.hc.GetVer(n, r, col, numVersions, attrs)
+		return
+	})
+	rsp, _ = call.Result.([]*hbase.TCell)
 	return
 }
 
 func (c *client) GetRow(name, row string, attributes map[string]string) (rsp []*hbase.TRowResult_, err error) {
-	if c.IsClosed() {
-		return nil, ErrClientClosed
-	}
-
-	c.Lock()
-	defer c.Unlock()
-
-	n := hbase.Text(name)
-	r := hbase.Text(row)
-	attrs := make(map[string]hbase.Text)
-	for k, v := range attributes {
-		attrs[k] = hbase.Text(v)
-	}
-
-	rsp, err = c.hc.GetRow(n, r, attrs)
-	c.errs.Add(err)
+	call := getRowCall(name, row, attributes)
+	err = c.invoke(call, func(call *Call) (err error) {
+		n := hbase.Text(name)
+		r := hbase.Text(row)
+		attrs := toTextMap(call.Attributes)
+
+		call.Result, err = c.conn.hc.GetRow(n, r, attrs)
+		return
+	})
+	rsp, _ = call.Result.([]*hbase.TRowResult_)
 	return
 }
 
 func (c *client) GetRowWithColumns(name, row string, columns []string, attributes map[string]string) (rsp []*hbase.TRowResult_, err error) {
-	if c.IsClosed() {
-		return nil, ErrClientClosed
-	}
-
-	c.Lock()
-	defer c.Unlock()
+	call := getRowWithColumnsCall(name, row, columns, attributes)
+	err = c.invoke(call, func(call *Call) (err error) {
+		n := hbase.Text(name)
+		r := hbase.Text(row)
+		cols := toByteSlices(columns)
+		attrs := toTextMap(call.Attributes)
+
+		call.Result, err = c.conn.hc.GetRowWithColumns(n, r, cols, attrs)
+		return
+	})
+	rsp, _ = call.Result.([]*hbase.TRowResult_)
+	return
+}
 
-	n := hbase.Text(name)
-	r := hbase.Text(row)
-	cols := make([][]byte, len(columns))
-	for i := range columns {
-		cols[i] = []byte(columns[i])
-	}
-	attrs := make(map[string]hbase.Text)
-	for k, v := range attributes {
-		attrs[k] = hbase.Text(v)
-	}
+func (c *client) GetRows(name string, rows []string, attributes map[string]string) (rsp []*hbase.TRowResult_, err error) {
+	call := getRowsCall(name, rows, attributes)
+	err = c.invoke(call, func(call *Call) (err error) {
+		n := hbase.Text(name)
+		rs := toByteSlices(rows)
+		attrs := toTextMap(call.Attributes)
+
+		call.Result, err = c.conn.hc.GetRows(n, rs, attrs)
+		return
+	})
+	rsp, _ = call.Result.([]*hbase.TRowResult_)
+	return
+}
 
-	rsp, err = c.hc.GetRowWithColumns(n, r, cols, attrs)
-	c.errs.Add(err)
+func (c *client) GetRowsWithColumns(name string, rows, columns []string, attributes map[string]string) (rsp []*hbase.TRowResult_, err error) {
+	call := getRowsWithColumnsCall(name, rows, columns, attributes)
+	err = c.invoke(call, func(call *Call) (err error) {
+		n := hbase.Text(name)
+		rs := toByteSlices(rows)
+		cols := toByteSlices(columns)
+		attrs := toTextMap(call.Attributes)
+
+		call.Result, err = c.conn.hc.GetRowsWithColumns(n, rs, cols, attrs)
+		return
+	})
+	rsp, _ = call.Result.([]*hbase.TRowResult_)
 	return
 }
 
-func (c *client) GetRows(name string, rows []string, attributes map[string]string) (rsp []*hbase.TRowResult_, err error) {
-	if c.IsClosed() {
-		return nil, ErrClientClosed
-	}
+func (c *client) MutateRow(name, row string, mutations []*hbase.Mutation, attributes map[string]string) error {
+	call := mutateRowCall(name, row, mutations, attributes)
+	return c.invoke(call, func(call *Call) error {
+		n := hbase.Text(name)
+		r := hbase.Text(row)
+		attrs := toTextMap(call.Attributes)
 
-	c.Lock()
-	defer c.Unlock()
+		return c.conn.hc.MutateRow(n, r, mutations, attrs)
+	})
+}
 
-	n := hbase.Text(name)
-	rs := make([][]byte, len(rows))
-	for i := range rows {
-		rs[i] = []byte(rows[i])
-	}
-	attrs := make(map[string]hbase.Text)
-	for k, v := range attributes {
-		attrs[k] = hbase.Text(v)
-	}
+func (c *client) MutateRows(name string, rowBatches []*hbase.BatchMutation, attributes map[string]string) error {
+	call := mutateRowsCall(name, rowBatches, attributes)
+	return c.invoke(call, func(call *Call) error {
+		n := hbase.Text(name)
+		attrs := toTextMap(call.Attributes)
 
-	rsp, err = c.hc.GetRows(n, rs, attrs)
-	c.errs.Add(err)
-	return
+		return c.conn.hc.MutateRows(n, rowBatches, attrs)
+	})
 }
 
-func (c *client) GetRowsWithColumns(name string, rows, columns []string, attributes map[string]string) (rsp []*hbase.TRowResult_, err error) {
-	if c.IsClosed() {
-		return nil, ErrClientClosed
-	}
+func (c *client) MutateRowsTs(name string, rowBatches []*hbase.BatchMutation, timestamp int64, attributes map[string]string) error {
+	call := mutateRowsTsCall(name, rowBatches, timestamp, attributes)
+	return c.invoke(call, func(call *Call) error {
+		n := hbase.Text(name)
+		attrs := toTextMap(call.Attributes)
 
-	c.Lock()
-	defer c.Unlock()
+		return c.conn.hc.MutateRowsTs(n, rowBatches, timestamp, attrs)
+	})
+}
 
-	n := hbase.Text(name)
-	rs := make([][]byte, len(rows))
-	for i := range rows {
-		rs[i] = []byte(rows[i])
-	}
-	cols := make([][]byte, len(columns))
-	for i := range columns {
-		cols[i] = []byte(columns[i])
-	}
-	attrs := make(map[string]hbase.Text)
-	for k, v := range attributes {
-		attrs[k] = hbase.Text(v)
-	}
+func (c *client) DeleteAll(name, row, column string, attributes map[string]string) error {
+	call := deleteAllCall(name, row, column, attributes)
+	return c.invoke(call, func(call *Call) error {
+		n := hbase.Text(name)
+		r := hbase.Text(row)
+		col := hbase.Text(column)
+		attrs := toTextMap(call.Attributes)
 
-	rsp, err = c.hc.GetRowsWithColumns(n, rs, cols, attrs)
-	c.errs.Add(err)
-	return
+		return c.conn.hc.DeleteAll(n, r, col, attrs)
+	})
 }
 
-func (c *client) MutateRow(name, row string, mutations []*hbase.Mutation, attributes map[string]string) (err error) {
-	if c.IsClosed() {
-		return ErrClientClosed
-	}
+func (c *client) DeleteAllRow(name, row string, attributes map[string]string) error {
+	call := deleteAllRowCall(name, row, attributes)
+	return c.invoke(call, func(call *Call) error {
+		n := hbase.Text(name)
+		r := hbase.Text(row)
+		attrs := toTextMap(call.Attributes)
 
-	c.Lock()
-	defer c.Unlock()
+		return c.conn.hc.DeleteAllRow(n, r, attrs)
+	})
+}
 
-	n := hbase.Text(name)
-	r := hbase.Text(row)
-	attrs := make(map[string]hbase.Text)
-	for k, v := range attributes {
-		attrs[k] = hbase.Text(v)
-	}
+func (c *client) CheckAndPut(name, row, column string, value []byte, mput *hbase.Mutation, attributes map[string]string) (rsp bool, err error) {
+	call := checkAndPutCall(name, row, column, value, mput, attributes)
+	err = c.invoke(call, func(call *Call) (err error) {
+		n := hbase.Text(name)
+		r := hbase.Text(row)
+		col := hbase.Text(column)
+		attrs := toTextMap(call.Attributes)
+
+		call.Result, err = c.conn.hc.CheckAndPut(n, r, col, hbase.Text(value), mput, attrs)
+		return
+	})
+	rsp, _ = call.Result.(bool)
+	return
+}
 
-	err = c.hc.MutateRow(n, r, mutations, attrs)
-	c.errs.Add(err)
+func (c *client) AtomicIncrement(name, row, column string, value int64) (rsp int64, err error) {
+	call := atomicIncrementCall(name, row, column, value)
+	err = c.invoke(call, func(call *Call) (err error) {
+		n := hbase.Text(name)
+		r := hbase.Text(row)
+		col := hbase.Text(column)
+
+		call.Result, err = c.conn.hc.AtomicIncrement(n, r, col, value)
+		return
+	})
+	rsp, _ = call.Result.(int64)
 	return
 }
 
-func (c *client) MutateRows(name string, rowBatches []*hbase.BatchMutation, attributes map[string]string) (err error) {
-	if c.IsClosed() {
-		return ErrClientClosed
-	}
+func (c *client) Increment(increment *hbase.TIncrement) error {
+	call := incrementCall(increment)
+	return c.invoke(call, func(call *Call) error {
+		return c.conn.hc.Increment(increment)
+	})
+}
 
-	c.Lock()
-	defer c.Unlock()
+func (c *client) IncrementRows(increments []*hbase.TIncrement) error {
+	call := incrementRowsCall(increments)
+	return c.invoke(call, func(call *Call) error {
+		return c.conn.hc.IncrementRows(increments)
+	})
+}
 
-	n := hbase.Text(name)
-	attrs := make(map[string]hbase.Text)
-	for k, v := range attributes {
-		attrs[k] = hbase.Text(v)
-	}
+func (c *client) ScannerOpenWithScan(name string, scan *hbase.TScan, attributes map[string]string) (rsp hbase.ScannerID, err error) {
+	call := scannerOpenWithScanCall(name, scan, attributes)
+	err = c.invoke(call, func(call *Call) (err error) {
+		n := hbase.Text(name)
+		attrs := toTextMap(call.Attributes)
+
+		call.Result, err = c.conn.hc.ScannerOpenWithScan(n, scan, attrs)
+		return
+	})
+	rsp, _ = call.Result.(hbase.ScannerID)
+	return
+}
 
-	err = c.hc.MutateRows(n, rowBatches, attrs)
-	c.errs.Add(err)
+func (c *client) ScannerOpen(name, startRow string, columns []string, attributes map[string]string) (rsp hbase.ScannerID, err error) {
+	call := scannerOpenCall(name, startRow, columns, attributes)
+	err = c.invoke(call, func(call *Call) (err error) {
+		n := hbase.Text(name)
+		startR := hbase.Text(startRow)
+		cols := toByteSlices(columns)
+		attrs := toTextMap(call.Attributes)
+
+		call.Result, err = c.conn.hc.ScannerOpen(n, startR, cols, attrs)
+		return
+	})
+	rsp, _ = call.Result.(hbase.ScannerID)
 	return
 }
 
-func (c *client) ScannerOpenWithScan(name string, scan *hbase.TScan, attributes map[string]string) (rsp hbase.ScannerID, err error) {
-	if c.IsClosed() {
-		return 0, ErrClientClosed
-	}
+func (c *client) ScannerOpenWithStop(name, startRow, stopRow string, columns []string, attributes map[string]string) (rsp hbase.ScannerID, err error) {
+	call := scannerOpenWithStopCall(name, startRow, stopRow, columns, attributes)
+	err = c.invoke(call, func(call *Call) (err error) {
+		n := hbase.Text(name)
+		startR := hbase.Text(startRow)
+		stopR := hbase.Text(stopRow)
+		cols := toByteSlices(columns)
+		attrs := toTextMap(call.Attributes)
+
+		call.Result, err = c.conn.hc.ScannerOpenWithStop(n, startR, stopR, cols, attrs)
+		return
+	})
+	rsp, _ = call.Result.(hbase.ScannerID)
+	return
+}
 
-	c.Lock()
-	defer c.Unlock()
+func (c *client) ScannerOpenWithPrefix(name, startAndPrefix string, columns []string, attributes map[string]string) (rsp hbase.ScannerID, err error) {
+	call := scannerOpenWithPrefixCall(name, startAndPrefix, columns, attributes)
+	err = c.invoke(call, func(call *Call) (err error) {
+		n := hbase.Text(name)
+		p := hbase.Text(startAndPrefix)
+		cols := toByteSlices(columns)
+		attrs := toTextMap(call.Attributes)
+
+		call.Result, err = c.conn.hc.ScannerOpenWithPrefix(n, p, cols, attrs)
+		return
+	})
+	rsp, _ = call.Result.(hbase.ScannerID)
+	return
+}
 
-	n := hbase.Text(name)
-	attrs := make(map[string]hbase.Text)
-	for k, v := range attributes {
-		attrs[k] = hbase.Text(v)
-	}
+func (c *client) ScannerGet(id hbase.ScannerID) (rsp []*hbase.TRowResult_, err error) {
+	call := scannerGetCall(id)
+	err = c.invoke(call, func(call *Call) (err error) {
+		call.Result, err = c.conn.hc.ScannerGet(id)
+		return
+	})
+	rsp, _ = call.Result.([]*hbase.TRowResult_)
+	return
+}
 
-	rsp, err = c.hc.ScannerOpenWithScan(n, scan, attrs)
-	c.errs.Add(err)
+func (c *client) ScannerGetList(id hbase.ScannerID, nbRows int32) (rsp []*hbase.TRowResult_, err error) {
+	call := scannerGetListCall(id, nbRows)
+	err = c.invoke(call, func(call *Call) (err error) {
+		call.Result, err = c.conn.hc.ScannerGetList(id, nbRows)
+		return
+	})
+	rsp, _ = call.Result.([]*hbase.TRowResult_)
 	return
 }
 
-func (c *client) ScannerOpen(name, startRow string, columns []string, attributes map[string]string) (rsp hbase.ScannerID, err error) {
+func (c *client) ScannerClose(id hbase.ScannerID) error {
+	call := scannerCloseCall(id)
+	return c.invoke(call, func(call *Call) error {
+		return c.conn.hc.ScannerClose(id)
+	})
+}
+
+// invoke runs the call through the interceptor chain of the pool,
+// and finally performs it with handler.
+func (c *client) invoke(call *Call, handler Handler) (err error) {
 	if c.IsClosed() {
-		return 0, ErrClientClosed
+		return ErrClientClosed
+	}
+	if c.rateLimiter != nil {
+		if err = c.rateLimiter.Wait(context.Background(), call.Table, ClassOf(call.Method)); err != nil {
+			return
+		}
 	}
 
-	c.Lock()
-	defer c.Unlock()
+	span := c.startSpan(call)
+	start := now()
+	defer func() {
+		endSpan(span, err)
+		if c.p != nil {
+			c.p.logSlowCall(call, c.conn, now().Sub(start), err)
+		}
+	}()
 
-	n := hbase.Text(name)
-	startR := hbase.Text(startRow)
-	cols := make([][]byte, len(columns))
-	for i := range columns {
-		cols[i] = []byte(columns[i])
-	}
-	attrs := make(map[string]hbase.Text)
-	for k, v := range attributes {
-		attrs[k] = hbase.Text(v)
+	if c.interceptor == nil {
+		return c.handle(call, handler, span)
 	}
-
-	rsp, err = c.hc.ScannerOpen(n, startR, cols, attrs)
-	c.errs.Add(err)
-	return
+	return c.interceptor(call, func(call *Call) error {
+		return c.handle(call, handler, span)
+	})
 }
 
-func (c *client) ScannerOpenWithStop(name, startRow, stopRow string, columns []string, attributes map[string]string) (rsp hbase.ScannerID, err error) {
+func (c *client) handle(call *Call, handler Handler, span Span) error {
 	if c.IsClosed() {
-		return 0, ErrClientClosed
+		return ErrClientClosed
 	}
 
 	c.Lock()
 	defer c.Unlock()
 
-	n := hbase.Text(name)
-	startR := hbase.Text(startRow)
-	stopR := hbase.Text(stopRow)
-	cols := make([][]byte, len(columns))
-	for i := range columns {
-		cols[i] = []byte(columns[i])
-	}
-	attrs := make(map[string]hbase.Text)
-	for k, v := range attributes {
-		attrs[k] = hbase.Text(v)
+	if c.propagate && c.tracer != nil {
+		attrs := make(map[string]string, len(call.Attributes))
+		for k, v := range call.Attributes {
+			attrs[k] = v
+		}
+		span.Inject(attrs)
+		call.Attributes = attrs
 	}
 
-	rsp, err = c.hc.ScannerOpenWithStop(n, startR, stopR, cols, attrs)
+	written, read := c.conn.ct.counts()
+	err := handler(call)
 	c.errs.Add(err)
-	return
-}
+	if c.p != nil {
+		c.p.observeCall(err, c.p.conf())
+	}
 
-func (c *client) ScannerOpenWithPrefix(name, startAndPrefix string, columns []string, attributes map[string]string) (rsp hbase.ScannerID, err error) {
-	if c.IsClosed() {
-		return 0, ErrClientClosed
+	if c.tracer != nil {
+		w, r := c.conn.ct.counts()
+		span.SetAttribute(AttrBytesSent, w-written)
+		span.SetAttribute(AttrBytesReceived, r-read)
 	}
+	return err
+}
 
-	c.Lock()
-	defer c.Unlock()
+func (c *client) startSpan(call *Call) Span {
+	if c.tracer == nil {
+		return noopSpan{}
+	}
 
-	n := hbase.Text(name)
-	p := hbase.Text(startAndPrefix)
-	cols := make([][]byte, len(columns))
-	for i := range columns {
-		cols[i] = []byte(columns[i])
+	span := c.tracer.StartSpan(spanCallPrefix + call.Method)
+	span.SetAttribute(AttrMethod, call.Method)
+	if call.Table != "" {
+		span.SetAttribute(AttrTable, call.Table)
 	}
-	attrs := make(map[string]hbase.Text)
-	for k, v := range attributes {
-		attrs[k] = hbase.Text(v)
+	if call.Rows != nil {
+		span.SetAttribute(AttrRowCount, len(call.Rows))
 	}
-
-	rsp, err = c.hc.ScannerOpenWithPrefix(n, p, cols, attrs)
-	c.errs.Add(err)
-	return
+	if c.conn != nil {
+		span.SetAttribute(AttrInstance, c.conn.addr())
+	}
+	return span
 }
 
-func (c *client) ScannerGet(id hbase.ScannerID) (rsp []*hbase.TRowResult_, err error) {
-	if c.IsClosed() {
-		return nil, ErrClientClosed
+func toTextMap(m map[string]string) map[string]hbase.Text {
+	res := make(map[string]hbase.Text, len(m))
+	for k, v := range m {
+		res[k] = hbase.Text(v)
 	}
-
-	c.Lock()
-	defer c.Unlock()
-
-	rsp, err = c.hc.ScannerGet(id)
-	c.errs.Add(err)
-	return
+	return res
 }
 
-func (c *client) ScannerGetList(id hbase.ScannerID, nbRows int32) (rsp []*hbase.TRowResult_, err error) {
-	if c.IsClosed() {
-		return nil, ErrClientClosed
+func toByteSlices(strs []string) [][]byte {
+	res := make([][]byte, len(strs))
+	for i := range strs {
+		res[i] = []byte(strs[i])
 	}
-
-	c.Lock()
-	defer c.Unlock()
-
-	rsp, err = c.hc.ScannerGetList(id, nbRows)
-	c.errs.Add(err)
-	return
+	return res
 }
 
-func (c *client) ScannerClose(id hbase.ScannerID) (err error) {
-	if c.IsClosed() {
-		return ErrClientClosed
+func batchRows(rowBatches []*hbase.BatchMutation) []string {
+	rows := make([]string, 0, len(rowBatches))
+	for _, batch := range rowBatches {
+		if batch != nil {
+			rows = append(rows, string(batch.Row))
+		}
 	}
-
-	c.Lock()
-	defer c.Unlock()
-
-	err = c.hc.ScannerClose(id)
-	c.errs.Add(err)
-	return
+	return rows
 }
@@ -0,0 +1,108 @@
+package pool
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Coalescer collapses identical concurrent reads into a single hbase call.
+// Get, GetRow and GetRowWithColumns calls with the same table, row, columns
+// and attributes which arrive while one of them is in flight wait for it and
+// share its result, including the error.
+//
+// Shared results are handed to every caller as is, so callers must not
+// modify them.
+type Coalescer interface {
+	// Interceptor returns the interceptor to install with
+	// WithExecutorInterceptors, so that duplicates wait for the call in
+	// flight before they borrow a connection.
+	Interceptor() Interceptor
+	Stats() CoalesceStats
+}
+
+// CoalesceStats contains statistics of a Coalescer.
+type CoalesceStats struct {
+	// Calls is the total number of coalescable calls.
+	Calls int64
+	// Saved is the total number of calls which shared the result of
+	// another call instead of reaching hbase.
+	Saved int64
+	// InFlight is the number of distinct calls in flight.
+	InFlight int
+}
+
+type coalescer struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+
+	calls int64
+	saved int64
+}
+
+var _ Coalescer = (*coalescer)(nil)
+
+// flight is an in-flight call shared by its duplicates.
+type flight struct {
+	done   chan struct{}
+	result interface{}
+	err    error
+}
+
+func NewCoalescer() Coalescer {
+	return &coalescer{
+		flights: make(map[string]*flight),
+	}
+}
+
+func (co *coalescer) Interceptor() Interceptor {
+	return co.intercept
+}
+
+func (co *coalescer) Stats() CoalesceStats {
+	co.mu.Lock()
+	inFlight := len(co.flights)
+	co.mu.Unlock()
+
+	return CoalesceStats{
+		Calls:    atomic.LoadInt64(&co.calls),
+		Saved:    atomic.LoadInt64(&co.saved),
+		InFlight: inFlight,
+	}
+}
+
+func (co *coalescer) intercept(call *Call, next Handler) error {
+	switch call.Method {
+	case "Get", "GetRow", "GetRowWithColumns":
+	default:
+		return next(call)
+	}
+	key, ok := CallKey(call)
+	if !ok {
+		return next(call)
+	}
+	atomic.AddInt64(&co.calls, 1)
+
+	co.mu.Lock()
+	if f, ok := co.flights[key]; ok {
+		co.mu.Unlock()
+		atomic.AddInt64(&co.saved, 1)
+
+		<-f.done
+		call.Result = f.result
+		return f.err
+	}
+	f := &flight{done: make(chan struct{})}
+	co.flights[key] = f
+	co.mu.Unlock()
+
+	defer func() {
+		co.mu.Lock()
+		delete(co.flights, key)
+		co.mu.Unlock()
+		close(f.done)
+	}()
+
+	f.err = next(call)
+	f.result = call.Result
+	return f.err
+}
@@ -0,0 +1,128 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+)
+
+func TestCoalescer(t *testing.T) {
+	co := NewCoalescer()
+	interceptor := co.Interceptor()
+
+	var calls int32
+	release := make(chan struct{})
+	handler := func(call *Call) error {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		call.Result = "result"
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]interface{}, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			call := &Call{
+				Method:     "GetRow",
+				Table:      "t",
+				Attributes: map[string]string{"a": "1", "b": "2"},
+				Args:       []interface{}{"t", "r"},
+			}
+			if err := interceptor(call, handler); err != nil {
+				t.Errorf("unexpected error - %v", err)
+			}
+			results[i] = call.Result
+		}(i)
+	}
+
+	for co.Stats().Calls < int64(len(results)) {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("expected 1 call to hbase, got %d", calls)
+	}
+	for _, result := range results {
+		if result != "result" {
+			t.Errorf("expected shared result, got %v", result)
+		}
+	}
+	if s := co.Stats(); s.Saved != 4 || s.InFlight != 0 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+func TestCallKey(t *testing.T) {
+	a, _ := CallKey(&Call{Method: "GetRowWithColumns", Args: []interface{}{"t", "r", []string{"f:a", "f:b"}}})
+	b, _ := CallKey(&Call{Method: "GetRowWithColumns", Args: []interface{}{"t", "r", []string{"f:a,f:b"}}})
+	if a == b {
+		t.Errorf("different columns must have different keys")
+	}
+	if _, ok := CallKey(&Call{Method: "GetVer", Args: []interface{}{"t", "r", "f:q", int32(2)}}); ok {
+		t.Errorf("a call with an argument other than strings must have no key")
+	}
+}
+
+type blockingClient struct {
+	Client
+	release chan struct{}
+}
+
+func (c *blockingClient) GetRow(name, row string, attributes map[string]string) ([]*hbase.TRowResult_, error) {
+	<-c.release
+	return []*hbase.TRowResult_{{Row: hbase.Text(row)}}, nil
+}
+
+func (c *blockingClient) Close() error {
+	return nil
+}
+
+type countingPool struct {
+	Pool
+	c    Client
+	gets int32
+}
+
+func (p *countingPool) GetContext(ctx context.Context) (Client, error) {
+	atomic.AddInt32(&p.gets, 1)
+	return p.c, nil
+}
+
+func TestCoalescer_Executor(t *testing.T) {
+	co := NewCoalescer()
+	c := &blockingClient{release: make(chan struct{})}
+	p := &countingPool{c: c}
+	e := NewExecutor(p, WithExecutorInterceptors(co.Interceptor()))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rsp, err := e.GetRow("t", "r", nil)
+			if err != nil || len(rsp) != 1 {
+				t.Errorf("unexpected result %v, %v", rsp, err)
+			}
+		}()
+	}
+
+	for co.Stats().Calls < 5 {
+		time.Sleep(time.Millisecond)
+	}
+	close(c.release)
+	wg.Wait()
+
+	// duplicates wait before they borrow a connection
+	if gets := atomic.LoadInt32(&p.gets); gets != 1 {
+		t.Errorf("expected 1 connection borrowed, got %d", gets)
+	}
+}
@@ -0,0 +1,120 @@
+package pool
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/popeyeio/gohbase/balancer"
+	"github.com/popeyeio/gohbase/discovery"
+	"github.com/popeyeio/gohbase/lib/thrift"
+)
+
+// config holds the settings of a pool. A config is never modified once it is
+// in use by a pool, Reconfigure replaces it with a modified copy instead.
+type config struct {
+	discovery            discovery.Discovery
+	balancer             balancer.Balancer
+	updatePickerInterval time.Duration
+
+	socketTimeout    time.Duration
+	transportFactory thrift.TTransportFactory
+	protocolFactory  thrift.TProtocolFactory
+
+	maxActive       int
+	maxIdle         int
+	idleTimeout     time.Duration
+	cleanUpInterval time.Duration
+	minIdle         int
+	maxLifetime     time.Duration
+	lifetimeJitter  time.Duration
+
+	isBlocked  bool
+	maxWait    time.Duration
+	maxWaiters int
+
+	healthChecker        HealthChecker
+	backgroundValidation bool
+
+	interceptors []Interceptor
+	interceptor  Interceptor
+
+	tracer         Tracer
+	propagateTrace bool
+
+	logger            *slog.Logger
+	slowCallThreshold time.Duration
+	maxRowKeyLen      int
+	hashRowKeys       bool
+
+	leakThreshold time.Duration
+	reclaimLeaks  bool
+
+	bulkheads    map[string]int
+	bulkheadWait bool
+
+	rateLimiter RateLimiter
+
+	reserved      int
+	shedWait      time.Duration
+	shedErrorRate float64
+}
+
+func newConfig() *config {
+	return &config{
+		discovery:        discovery.NewCustomDiscovery(),
+		balancer:         balancer.NewRRBalancer(),
+		transportFactory: thrift.NewTBufferedTransportFactory(4096),
+		protocolFactory:  thrift.NewTBinaryProtocolFactoryDefault(),
+		logger:           discardLogger,
+	}
+}
+
+func (cfg *config) setBulkhead(key string, max int) error {
+	if max < 0 {
+		return invalidOption("bulkhead "+key, max)
+	}
+
+	if cfg.bulkheads == nil {
+		cfg.bulkheads = make(map[string]int)
+	}
+	if max == 0 {
+		delete(cfg.bulkheads, key)
+	} else {
+		cfg.bulkheads[key] = max
+	}
+	return nil
+}
+
+func (cfg *config) clone() *config {
+	c := *cfg
+	c.interceptors = append([]Interceptor(nil), cfg.interceptors...)
+	c.bulkheads = make(map[string]int, len(cfg.bulkheads))
+	for k, v := range cfg.bulkheads {
+		c.bulkheads[k] = v
+	}
+	return &c
+}
+
+// apply applies opts to cfg, and returns the errors of the invalid options.
+func (cfg *config) apply(opts ...Option) error {
+	var errs Errors
+	for _, opt := range opts {
+		errs.Add(opt(cfg))
+	}
+	cfg.interceptor = ChainInterceptors(cfg.interceptors...)
+
+	if cfg.reserved > 0 && cfg.maxActive > 0 && cfg.reserved >= cfg.maxActive {
+		errs.Add(invalidOption("high priority reserve", cfg.reserved))
+		cfg.reserved = 0
+	}
+
+	if errs.Len() > 0 {
+		return errs
+	}
+	return nil
+}
+
+func invalidOption(name string, value interface{}) error {
+	return fmt.Errorf("[gohbase] invalid %s: %v", name, value)
+}
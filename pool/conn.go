@@ -0,0 +1,77 @@
+package pool
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+	"github.com/popeyeio/gohbase/instance"
+	"github.com/popeyeio/gohbase/lib/thrift"
+)
+
+// conn is a connection to an hbase thrift server.
+type conn struct {
+	hc  *hbase.HbaseClient
+	ins instance.Instance
+	ct  *countingTransport
+
+	// expiresAt is the time after which conn is recycled, zero means never.
+	expiresAt time.Time
+	// bulkheads are the bulkheads entered by the client which borrows conn.
+	bulkheads []*bulkhead
+}
+
+func (cn *conn) addr() string {
+	if cn.ins == nil {
+		return ""
+	}
+	return cn.ins.GetAddr()
+}
+
+func (cn *conn) expired(t time.Time) bool {
+	return !cn.expiresAt.IsZero() && !t.Before(cn.expiresAt)
+}
+
+func (cn *conn) close() error {
+	return cn.hc.Transport.Close()
+}
+
+// countingTransport counts the bytes written to and read from the underlying transport,
+// and keeps the socket beneath it for liveness probes.
+type countingTransport struct {
+	thrift.TTransport
+	socket *thrift.TSocket
+
+	written int64
+	read    int64
+}
+
+var _ thrift.TTransport = (*countingTransport)(nil)
+
+func newCountingTransport(trans thrift.TTransport, socket *thrift.TSocket) *countingTransport {
+	return &countingTransport{
+		TTransport: trans,
+		socket:     socket,
+	}
+}
+
+func (t *countingTransport) Read(b []byte) (int, error) {
+	n, err := t.TTransport.Read(b)
+	atomic.AddInt64(&t.read, int64(n))
+	return n, err
+}
+
+func (t *countingTransport) Write(b []byte) (int, error) {
+	n, err := t.TTransport.Write(b)
+	atomic.AddInt64(&t.written, int64(n))
+	return n, err
+}
+
+func (t *countingTransport) Flush(ctx context.Context) error {
+	return t.TTransport.Flush(ctx)
+}
+
+func (t *countingTransport) counts() (written, read int64) {
+	return atomic.LoadInt64(&t.written), atomic.LoadInt64(&t.read)
+}
@@ -1,7 +1,11 @@
 package pool
 
 import (
+	"net"
 	"strings"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+	"github.com/popeyeio/gohbase/lib/thrift"
 )
 
 type Errors []error
@@ -41,3 +45,68 @@ func (es *Errors) Add(errs ...error) {
 		}
 	}
 }
+
+const (
+	ErrorClassClientClosed    = "client_closed"
+	ErrorClassPoolClosed      = "pool_closed"
+	ErrorClassPoolFull        = "pool_full"
+	ErrorClassRejected        = "rejected"
+	ErrorClassTimeout         = "timeout"
+	ErrorClassTransport       = "transport"
+	ErrorClassProtocol        = "protocol"
+	ErrorClassApplication     = "application"
+	ErrorClassIO              = "io"
+	ErrorClassIllegalArgument = "illegal_argument"
+	ErrorClassAlreadyExists   = "already_exists"
+	ErrorClassUnknown         = "unknown"
+)
+
+// ErrorClass returns the class of err, which is one of the ErrorClass constants,
+// or an empty string if err is nil.
+func ErrorClass(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	switch e := err.(type) {
+	case Errors:
+		if e.Len() > 0 {
+			return ErrorClass(e[0])
+		}
+		return ErrorClassUnknown
+	case *hbase.IOError:
+		return ErrorClassIO
+	case *hbase.IllegalArgument:
+		return ErrorClassIllegalArgument
+	case *hbase.AlreadyExists:
+		return ErrorClassAlreadyExists
+	case thrift.TTransportException:
+		if e.TypeId() == thrift.TIMED_OUT {
+			return ErrorClassTimeout
+		}
+		return ErrorClassTransport
+	case thrift.TApplicationException:
+		return ErrorClassApplication
+	case thrift.TProtocolException:
+		return ErrorClassProtocol
+	case net.Error:
+		if e.Timeout() {
+			return ErrorClassTimeout
+		}
+		return ErrorClassTransport
+	}
+
+	switch err {
+	case ErrClientClosed:
+		return ErrorClassClientClosed
+	case ErrPoolClosed:
+		return ErrorClassPoolClosed
+	case ErrPoolFull, ErrTooManyWaiters:
+		return ErrorClassPoolFull
+	case ErrWaitTimeout:
+		return ErrorClassTimeout
+	case ErrBulkheadFull, ErrRateLimited, ErrLoadShed:
+		return ErrorClassRejected
+	}
+	return ErrorClassUnknown
+}
@@ -0,0 +1,400 @@
+package pool
+
+import (
+	"context"
+	"time"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+)
+
+const (
+	defaultMaxRetries   = 2
+	defaultRetryBackoff = time.Millisecond * 10
+	defaultMaxBackoff   = time.Second
+)
+
+// Executor is a client which can be shared freely across goroutines.
+// Every method borrows a client from the pool, performs the call and returns
+// the client, retrying the call on retryable errors. Calls which are not
+// idempotent, i.e. AtomicIncrement, Increment, IncrementRows and CheckAndPut,
+// are only retried if the pool is full, since they are not sent then.
+//
+// Executor gains the methods Client gains, so implementations outside of this
+// package should embed Executor to keep compiling.
+type Executor interface {
+	// Do borrows a client from the pool, calls f with it, and returns it.
+	// It is meant for sequences of calls which must be performed on the same
+	// connection, such as scanners. Do does not retry f.
+	Do(context.Context, func(Client) error) error
+
+	EnableTable(string) error
+	DisableTable(string) error
+	IsTableEnabled(string) (bool, error)
+	GetTableNames() ([]string, error)
+	GetColumnDescriptors(string) (map[string]*hbase.ColumnDescriptor, error)
+	GetTableRegions(string) ([]*hbase.TRegionInfo, error)
+	CreateTable(string, []*hbase.ColumnDescriptor) error
+	DeleteTable(string) error
+	Get(string, string, string, map[string]string) ([]*hbase.TCell, error)
+	GetVer(string, string, string, int32, map[string]string) ([]*hbase.TCell, error)
+	GetRow(string, string, map[string]string) ([]*hbase.TRowResult_, error)
+	GetRowWithColumns(string, string, []string, map[string]string) ([]*hbase.TRowResult_, error)
+	GetRows(string, []string, map[string]string) ([]*hbase.TRowResult_, error)
+	GetRowsWithColumns(string, []string, []string, map[string]string) ([]*hbase.TRowResult_, error)
+	MutateRow(string, string, []*hbase.Mutation, map[string]string) error
+	MutateRows(string, []*hbase.BatchMutation, map[string]string) error
+	MutateRowsTs(string, []*hbase.BatchMutation, int64, map[string]string) error
+	DeleteAll(string, string, string, map[string]string) error
+	DeleteAllRow(string, string, map[string]string) error
+	CheckAndPut(string, string, string, []byte, *hbase.Mutation, map[string]string) (bool, error)
+	AtomicIncrement(string, string, string, int64) (int64, error)
+	Increment(*hbase.TIncrement) error
+	IncrementRows([]*hbase.TIncrement) error
+}
+
+type executor struct {
+	p Pool
+
+	maxRetries   int
+	retryBackoff time.Duration
+	maxBackoff   time.Duration
+	retryable    func(error) bool
+	interceptor  Interceptor
+}
+
+var _ Executor = (*executor)(nil)
+
+type ExecutorOption func(*executor)
+
+// WithMaxRetries sets the max number of retries of a call, zero disables retries.
+func WithMaxRetries(n int) ExecutorOption {
+	return func(e *executor) {
+		if n >= 0 {
+			e.maxRetries = n
+		}
+	}
+}
+
+// WithRetryBackoff sets the backoff before the first retry, which is doubled
+// on every retry up to max.
+func WithRetryBackoff(backoff, max time.Duration) ExecutorOption {
+	return func(e *executor) {
+		if backoff >= 0 && max >= backoff {
+			e.retryBackoff = backoff
+			e.maxBackoff = max
+		}
+	}
+}
+
+// WithRetryable sets the function which decides if an error is retryable,
+// IsRetryable by default.
+func WithRetryable(retryable func(error) bool) ExecutorOption {
+	return func(e *executor) {
+		if retryable != nil {
+			e.retryable = retryable
+		}
+	}
+}
+
+// WithExecutorInterceptors appends interceptors to the chain which every
+// Executor call goes through before a client is borrowed, so that they may
+// serve, share or hold calls back without holding a connection. The chain
+// runs once per call, outside of the retries.
+func WithExecutorInterceptors(interceptors ...Interceptor) ExecutorOption {
+	return func(e *executor) {
+		e.interceptor = ChainInterceptors(append([]Interceptor{e.interceptor}, interceptors...)...)
+	}
+}
+
+func NewExecutor(p Pool, opts ...ExecutorOption) Executor {
+	e := &executor{
+		p:            p,
+		maxRetries:   defaultMaxRetries,
+		retryBackoff: defaultRetryBackoff,
+		maxBackoff:   defaultMaxBackoff,
+		retryable:    IsRetryable,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// IsRetryable reports whether a call failed with err may succeed if retried,
+// i.e. the call failed because of the connection or the pool rather than the
+// request itself. Note that a call which failed with a transport error or
+// timed out may have been applied, so the executor does not retry such errors
+// for calls which are not idempotent.
+func IsRetryable(err error) bool {
+	switch ErrorClass(err) {
+	case ErrorClassTransport, ErrorClassTimeout, ErrorClassPoolFull:
+		return true
+	}
+	return false
+}
+
+func (e *executor) Do(ctx context.Context, f func(Client) error) error {
+	c, err := e.p.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	return f(c)
+}
+
+// invoke runs call through the interceptors of the executor, and finally
+// performs it with f on a borrowed client, retried by retry.
+func (e *executor) invoke(call *Call, retry func(*Call, func(Client) error) error, f func(Client, *Call) error) error {
+	handler := func(call *Call) error {
+		return retry(call, func(c Client) error {
+			return f(c, call)
+		})
+	}
+	if e.interceptor == nil {
+		return handler(call)
+	}
+	return e.interceptor(call, handler)
+}
+
+// retry calls f for call until it succeeds, fails with an unretryable error,
+// or runs out of retries.
+func (e *executor) retry(call *Call, f func(Client) error) error {
+	return e.retryIf(call, e.retryable, f)
+}
+
+// retryUnsent is retry for calls which are not idempotent, which are only
+// retried if they have not been sent because the pool is full.
+func (e *executor) retryUnsent(call *Call, f func(Client) error) error {
+	return e.retryIf(call, func(err error) bool {
+		return ErrorClass(err) == ErrorClassPoolFull && e.retryable(err)
+	}, f)
+}
+
+func (e *executor) retryIf(call *Call, retryable func(error) bool, f func(Client) error) (err error) {
+	ctx := context.Background()
+	if call.Table != "" {
+		ctx = ContextWithTable(ctx, call.Table)
+	}
+	backoff := e.retryBackoff
+
+	for i := 0; ; i++ {
+		if err = e.Do(ctx, f); err == nil || i >= e.maxRetries || !retryable(err) {
+			return
+		}
+
+		if err := e.backoff(ctx, backoff+jitter(backoff)); err != nil {
+			return err
+		}
+		if backoff *= 2; backoff > e.maxBackoff {
+			backoff = e.maxBackoff
+		}
+	}
+}
+
+// backoff waits d before a retry, and fails once ctx is done or the pool is
+// closed, so that retries do not outlive the pool.
+func (e *executor) backoff(ctx context.Context, d time.Duration) error {
+	var closing <-chan struct{}
+	if p, ok := e.p.(interface{ closing() <-chan struct{} }); ok {
+		closing = p.closing()
+	}
+
+	timer := newTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-closing:
+		return ErrPoolClosed
+	}
+}
+
+func (e *executor) EnableTable(name string) error {
+	return e.invoke(enableTableCall(name), e.retry, func(c Client, call *Call) error {
+		return c.EnableTable(name)
+	})
+}
+
+func (e *executor) DisableTable(name string) error {
+	return e.invoke(disableTableCall(name), e.retry, func(c Client, call *Call) error {
+		return c.DisableTable(name)
+	})
+}
+
+func (e *executor) IsTableEnabled(name string) (rsp bool, err error) {
+	call := isTableEnabledCall(name)
+	err = e.invoke(call, e.retry, func(c Client, call *Call) (err error) {
+		call.Result, err = c.IsTableEnabled(name)
+		return
+	})
+	rsp, _ = call.Result.(bool)
+	return
+}
+
+func (e *executor) GetTableNames() (rsp []string, err error) {
+	call := getTableNamesCall()
+	err = e.invoke(call, e.retry, func(c Client, call *Call) (err error) {
+		call.Result, err = c.GetTableNames()
+		return
+	})
+	rsp, _ = call.Result.([]string)
+	return
+}
+
+func (e *executor) GetColumnDescriptors(name string) (rsp map[string]*hbase.ColumnDescriptor, err error) {
+	call := getColumnDescriptorsCall(name)
+	err = e.invoke(call, e.retry, func(c Client, call *Call) (err error) {
+		call.Result, err = c.GetColumnDescriptors(name)
+		return
+	})
+	rsp, _ = call.Result.(map[string]*hbase.ColumnDescriptor)
+	return
+}
+
+func (e *executor) GetTableRegions(name string) (rsp []*hbase.TRegionInfo, err error) {
+	call := getTableRegionsCall(name)
+	err = e.invoke(call, e.retry, func(c Client, call *Call) (err error) {
+		call.Result, err = c.GetTableRegions(name)
+		return
+	})
+	rsp, _ = call.Result.([]*hbase.TRegionInfo)
+	return
+}
+
+func (e *executor) CreateTable(name string, cfs []*hbase.ColumnDescriptor) error {
+	return e.invoke(createTableCall(name, cfs), e.retry, func(c Client, call *Call) error {
+		return c.CreateTable(name, cfs)
+	})
+}
+
+func (e *executor) DeleteTable(name string) error {
+	return e.invoke(deleteTableCall(name), e.retry, func(c Client, call *Call) error {
+		return c.DeleteTable(name)
+	})
+}
+
+func (e *executor) Get(name, row, column string, attributes map[string]string) (rsp []*hbase.TCell, err error) {
+	call := getCall(name, row, column, attributes)
+	err = e.invoke(call, e.retry, func(c Client, call *Call) (err error) {
+		call.Result, err = c.Get(name, row, column, call.Attributes)
+		return
+	})
+	rsp, _ = call.Result.([]*hbase.TCell)
+	return
+}
+
+func (e *executor) GetVer(name, row, column string, numVersions int32, attributes map[string]string) (rsp []*hbase.TCell, err error) {
+	call := getVerCall(name, row, column, numVersions, attributes)
+	err = e.invoke(call, e.retry, func(c Client, call *Call) (err error) {
+		call.Result, err = c.GetVer(name, row, column, numVersions, call.Attributes)
+		return
+	})
+	rsp, _ = call.Result.([]*hbase.TCell)
+	return
+}
+
+func (e *executor) GetRow(name, row string, attributes map[string]string) (rsp []*hbase.TRowResult_, err error) {
+	call := getRowCall(name, row, attributes)
+	err = e.invoke(call, e.retry, func(c Client, call *Call) (err error) {
+		call.Result, err = c.GetRow(name, row, call.Attributes)
+		return
+	})
+	rsp, _ = call.Result.([]*hbase.TRowResult_)
+	return
+}
+
+func (e *executor) GetRowWithColumns(name, row string, columns []string, attributes map[string]string) (rsp []*hbase.TRowResult_, err error) {
+	call := getRowWithColumnsCall(name, row, columns, attributes)
+	err = e.invoke(call, e.retry, func(c Client, call *Call) (err error) {
+		call.Result, err = c.GetRowWithColumns(name, row, columns, call.Attributes)
+		return
+	})
+	rsp, _ = call.Result.([]*hbase.TRowResult_)
+	return
+}
+
+func (e *executor) GetRows(name string, rows []string, attributes map[string]string) (rsp []*hbase.TRowResult_, err error) {
+	call := getRowsCall(name, rows, attributes)
+	err = e.invoke(call, e.retry, func(c Client, call *Call) (err error) {
+		call.Result, err = c.GetRows(name, rows, call.Attributes)
+		return
+	})
+	rsp, _ = call.Result.([]*hbase.TRowResult_)
+	return
+}
+
+func (e *executor) GetRowsWithColumns(name string, rows, columns []string, attributes map[string]string) (rsp []*hbase.TRowResult_, err error) {
+	call := getRowsWithColumnsCall(name, rows, columns, attributes)
+	err = e.invoke(call, e.retry, func(c Client, call *Call) (err error) {
+		call.Result, err = c.GetRowsWithColumns(name, rows, columns, call.Attributes)
+		return
+	})
+	rsp, _ = call.Result.([]*hbase.TRowResult_)
+	return
+}
+
+func (e *executor) MutateRow(name, row string, mutations []*hbase.Mutation, attributes map[string]string) error {
+	return e.invoke(mutateRowCall(name, row, mutations, attributes), e.retry, func(c Client, call *Call) error {
+		return c.MutateRow(name, row, mutations, call.Attributes)
+	})
+}
+
+func (e *executor) MutateRows(name string, rowBatches []*hbase.BatchMutation, attributes map[string]string) error {
+	return e.invoke(mutateRowsCall(name, rowBatches, attributes), e.retry, func(c Client, call *Call) error {
+		return c.MutateRows(name, rowBatches, call.Attributes)
+	})
+}
+
+func (e *executor) MutateRowsTs(name string, rowBatches []*hbase.BatchMutation, timestamp int64, attributes map[string]string) error {
+	return e.invoke(mutateRowsTsCall(name, rowBatches, timestamp, attributes), e.retry, func(c Client, call *Call) error {
+		return c.MutateRowsTs(name, rowBatches, timestamp, call.Attributes)
+	})
+}
+
+func (e *executor) DeleteAll(name, row, column string, attributes map[string]string) error {
+	return e.invoke(deleteAllCall(name, row, column, attributes), e.retry, func(c Client, call *Call) error {
+		return c.DeleteAll(name, row, column, call.Attributes)
+	})
+}
+
+func (e *executor) DeleteAllRow(name, row string, attributes map[string]string) error {
+	return e.invoke(deleteAllRowCall(name, row, attributes), e.retry, func(c Client, call *Call) error {
+		return c.DeleteAllRow(name, row, call.Attributes)
+	})
+}
+
+func (e *executor) CheckAndPut(name, row, column string, value []byte, mput *hbase.Mutation, attributes map[string]string) (rsp bool, err error) {
+	call := checkAndPutCall(name, row, column, value, mput, attributes)
+	err = e.invoke(call, e.retryUnsent, func(c Client, call *Call) (err error) {
+		call.Result, err = c.CheckAndPut(name, row, column, value, mput, call.Attributes)
+		return
+	})
+	rsp, _ = call.Result.(bool)
+	return
+}
+
+func (e *executor) AtomicIncrement(name, row, column string, value int64) (rsp int64, err error) {
+	call := atomicIncrementCall(name, row, column, value)
+	err = e.invoke(call, e.retryUnsent, func(c Client, call *Call) (err error) {
+		call.Result, err = c.AtomicIncrement(name, row, column, value)
+		return
+	})
+	rsp, _ = call.Result.(int64)
+	return
+}
+
+func (e *executor) Increment(increment *hbase.TIncrement) error {
+	return e.invoke(incrementCall(increment), e.retryUnsent, func(c Client, call *Call) error {
+		return c.Increment(increment)
+	})
+}
+
+func (e *executor) IncrementRows(increments []*hbase.TIncrement) error {
+	return e.invoke(incrementRowsCall(increments), e.retryUnsent, func(c Client, call *Call) error {
+		return c.IncrementRows(increments)
+	})
+}
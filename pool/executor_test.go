@@ -0,0 +1,135 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+	"github.com/popeyeio/gohbase/lib/thrift"
+)
+
+type retryClient struct {
+	Client
+	err   error
+	calls int
+}
+
+func (c *retryClient) GetRow(name, row string, attributes map[string]string) ([]*hbase.TRowResult_, error) {
+	c.calls++
+	return nil, c.err
+}
+
+func (c *retryClient) AtomicIncrement(name, row, column string, value int64) (int64, error) {
+	c.calls++
+	return 1, c.err
+}
+
+func (c *retryClient) Close() error {
+	return nil
+}
+
+// retryPool fails the first Gets with errs, then returns its client.
+type retryPool struct {
+	Pool
+	errs   []error
+	client *retryClient
+	gets   int
+	closed chan struct{}
+}
+
+func (p *retryPool) closing() <-chan struct{} {
+	return p.closed
+}
+
+func (p *retryPool) GetContext(ctx context.Context) (Client, error) {
+	p.gets++
+	if p.gets <= len(p.errs) {
+		return nil, p.errs[p.gets-1]
+	}
+	return p.client, nil
+}
+
+func TestExecutor_Retry(t *testing.T) {
+	defer func() {
+		newTimer = time.NewTimer
+	}()
+	var sleeps []time.Duration
+	newTimer = func(d time.Duration) *time.Timer {
+		sleeps = append(sleeps, d)
+		return time.NewTimer(0)
+	}
+
+	errTransport := thrift.NewTTransportException(thrift.NOT_OPEN, "not open")
+	p := &retryPool{client: &retryClient{err: errTransport}}
+	e := NewExecutor(p, WithMaxRetries(4), WithRetryBackoff(time.Millisecond*10, time.Millisecond*25))
+
+	if _, err := e.GetRow("t", "r", nil); err != errTransport {
+		t.Fatalf("expected error %v, got %v", errTransport, err)
+	}
+	if p.client.calls != 5 {
+		t.Errorf("expected 5 calls, got %d", p.client.calls)
+	}
+
+	// the backoff is doubled up to max, plus a jitter of up to the backoff
+	backoffs := []time.Duration{10, 20, 25, 25}
+	if len(sleeps) != len(backoffs) {
+		t.Fatalf("expected %d backoffs, got %v", len(backoffs), sleeps)
+	}
+	for i, backoff := range backoffs {
+		backoff *= time.Millisecond
+		if sleeps[i] < backoff || sleeps[i] >= backoff*2 {
+			t.Errorf("backoff %d is %v, expected %v plus jitter", i, sleeps[i], backoff)
+		}
+	}
+}
+
+func TestExecutor_RetryClosed(t *testing.T) {
+	errTransport := thrift.NewTTransportException(thrift.NOT_OPEN, "not open")
+	p := &retryPool{client: &retryClient{err: errTransport}, closed: make(chan struct{})}
+	e := NewExecutor(p, WithMaxRetries(4), WithRetryBackoff(time.Hour, time.Hour))
+
+	// the backoff is cut short once the pool is closed, and no more retries are made
+	close(p.closed)
+	if _, err := e.GetRow("t", "r", nil); err != ErrPoolClosed {
+		t.Errorf("expected error %v, got %v", ErrPoolClosed, err)
+	}
+	if p.client.calls != 1 {
+		t.Errorf("expected 1 call, got %d", p.client.calls)
+	}
+}
+
+func TestExecutor_RetryUnretryable(t *testing.T) {
+	p := &retryPool{client: &retryClient{err: &hbase.IOError{Message: "io"}}}
+	e := NewExecutor(p, WithRetryBackoff(0, 0))
+
+	if _, err := e.GetRow("t", "r", nil); ErrorClass(err) != ErrorClassIO {
+		t.Errorf("expected io error, got %v", err)
+	}
+	if p.client.calls != 1 {
+		t.Errorf("unretryable error is retried %d times", p.client.calls-1)
+	}
+}
+
+func TestExecutor_RetryNotIdempotent(t *testing.T) {
+	errTransport := thrift.NewTTransportException(thrift.TIMED_OUT, "timed out")
+	p := &retryPool{client: &retryClient{err: errTransport}}
+	e := NewExecutor(p, WithRetryBackoff(0, 0))
+
+	if _, err := e.AtomicIncrement("t", "r", "f:q", 1); err != errTransport {
+		t.Errorf("expected error %v, got %v", errTransport, err)
+	}
+	if p.client.calls != 1 {
+		t.Errorf("increment which may have been applied is retried %d times", p.client.calls-1)
+	}
+
+	// a call which has not been sent is retried
+	p = &retryPool{errs: []error{ErrPoolFull, ErrPoolFull}, client: &retryClient{}}
+	e = NewExecutor(p, WithRetryBackoff(0, 0))
+	if _, err := e.AtomicIncrement("t", "r", "f:q", 1); err != nil {
+		t.Errorf("AtomicIncrement error - %v", err)
+	}
+	if p.gets != 3 || p.client.calls != 1 {
+		t.Errorf("expected 3 gets and 1 call, got %d and %d", p.gets, p.client.calls)
+	}
+}
@@ -0,0 +1,453 @@
+package pool
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+)
+
+const (
+	defaultHedgePercentile = 0.95
+	defaultHedgeMinDelay   = time.Millisecond
+	defaultHedgeMaxDelay   = time.Millisecond * 100
+	defaultHedgeBudget     = 0.05
+	defaultHedgeBurst      = 10
+
+	hedgeSamples    = 1024
+	hedgeMinSamples = 100
+	hedgeRefresh    = 64
+)
+
+// Hedger performs point reads which are hedged: if the first attempt has not
+// answered within a delay, a second attempt is sent to another instance, the
+// first answer wins and the loser is abandoned with its connection closed.
+type Hedger interface {
+	Get(context.Context, string, string, string, map[string]string, ...HedgeCallOption) ([]*hbase.TCell, error)
+	GetRow(context.Context, string, string, map[string]string, ...HedgeCallOption) ([]*hbase.TRowResult_, error)
+	GetRowWithColumns(context.Context, string, string, []string, map[string]string, ...HedgeCallOption) ([]*hbase.TRowResult_, error)
+	Stats() HedgeStats
+}
+
+// HedgeStats contains statistics of a Hedger.
+type HedgeStats struct {
+	// Calls is the total number of calls.
+	Calls int64
+	// Hedged is the total number of hedge attempts sent.
+	Hedged int64
+	// HedgeWins is the total number of calls answered by the hedge attempt.
+	HedgeWins int64
+	// OverBudget is the total number of hedges skipped due to the budget.
+	OverBudget int64
+	// Delay is the current delay before a hedge attempt is sent.
+	Delay time.Duration
+}
+
+type hedger struct {
+	p Pool
+
+	percentile float64
+	minDelay   time.Duration
+	maxDelay   time.Duration
+	budget     float64
+	burst      float64
+
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+	fresh   int
+	delay   time.Duration
+	tokens  float64
+
+	calls      int64
+	hedged     int64
+	hedgeWins  int64
+	overBudget int64
+}
+
+var _ Hedger = (*hedger)(nil)
+
+type HedgeOption func(*hedger)
+
+// WithHedgePercentile sets the percentile of the observed latencies used as
+// the hedge delay, 0.95 by default.
+func WithHedgePercentile(percentile float64) HedgeOption {
+	return func(h *hedger) {
+		if percentile > 0 && percentile < 1 {
+			h.percentile = percentile
+		}
+	}
+}
+
+// WithHedgeDelay bounds the hedge delay. The max delay is used until enough
+// latencies have been observed.
+func WithHedgeDelay(min, max time.Duration) HedgeOption {
+	return func(h *hedger) {
+		if min >= 0 && max >= min {
+			h.minDelay = min
+			h.maxDelay = max
+		}
+	}
+}
+
+// WithHedgeBudget limits the hedge attempts to ratio of the calls, with at
+// most burst hedges in a row, so that hedging cannot multiply the load.
+func WithHedgeBudget(ratio float64, burst int) HedgeOption {
+	return func(h *hedger) {
+		if ratio >= 0 && ratio <= 1 && burst > 0 {
+			h.budget = ratio
+			h.burst = float64(burst)
+		}
+	}
+}
+
+func NewHedger(p Pool, opts ...HedgeOption) Hedger {
+	h := &hedger{
+		p:          p,
+		percentile: defaultHedgePercentile,
+		minDelay:   defaultHedgeMinDelay,
+		maxDelay:   defaultHedgeMaxDelay,
+		budget:     defaultHedgeBudget,
+		burst:      defaultHedgeBurst,
+		samples:    make([]time.Duration, 0, hedgeSamples),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.delay = h.maxDelay
+	h.tokens = h.burst
+	return h
+}
+
+type hedgeCall struct {
+	delay    time.Duration
+	disabled bool
+}
+
+type HedgeCallOption func(*hedgeCall)
+
+// HedgeAfter overrides the hedge delay of a single call.
+func HedgeAfter(delay time.Duration) HedgeCallOption {
+	return func(hc *hedgeCall) {
+		if delay >= 0 {
+			hc.delay = delay
+		}
+	}
+}
+
+// NoHedge disables hedging of a single call.
+func NoHedge() HedgeCallOption {
+	return func(hc *hedgeCall) {
+		hc.disabled = true
+	}
+}
+
+func (h *hedger) Get(ctx context.Context, name, row, column string, attributes map[string]string, opts ...HedgeCallOption) (rsp []*hbase.TCell, err error) {
+	res, err := h.do(ctx, opts, func(c Client) (interface{}, error) {
+		return c.Get(name, row, column, attributes)
+	})
+	rsp, _ = res.([]*hbase.TCell)
+	return
+}
+
+func (h *hedger) GetRow(ctx context.Context, name, row string, attributes map[string]string, opts ...HedgeCallOption) (rsp []*hbase.TRowResult_, err error) {
+	res, err := h.do(ctx, opts, func(c Client) (interface{}, error) {
+		return c.GetRow(name, row, attributes)
+	})
+	rsp, _ = res.([]*hbase.TRowResult_)
+	return
+}
+
+func (h *hedger) GetRowWithColumns(ctx context.Context, name, row string, columns []string, attributes map[string]string, opts ...HedgeCallOption) (rsp []*hbase.TRowResult_, err error) {
+	res, err := h.do(ctx, opts, func(c Client) (interface{}, error) {
+		return c.GetRowWithColumns(name, row, columns, attributes)
+	})
+	rsp, _ = res.([]*hbase.TRowResult_)
+	return
+}
+
+func (h *hedger) Stats() HedgeStats {
+	h.mu.Lock()
+	delay := h.delay
+	h.mu.Unlock()
+
+	return HedgeStats{
+		Calls:      atomic.LoadInt64(&h.calls),
+		Hedged:     atomic.LoadInt64(&h.hedged),
+		HedgeWins:  atomic.LoadInt64(&h.hedgeWins),
+		OverBudget: atomic.LoadInt64(&h.overBudget),
+		Delay:      delay,
+	}
+}
+
+// attempt is a single try of a hedged call.
+type attempt struct {
+	hedge  bool
+	start  time.Time
+	end    time.Time
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	c         Client
+	abandoned bool
+
+	res  interface{}
+	err  error
+	done chan struct{}
+}
+
+func (h *hedger) do(ctx context.Context, opts []HedgeCallOption, f func(Client) (interface{}, error)) (interface{}, error) {
+	hc := &hedgeCall{delay: -1}
+	for _, opt := range opts {
+		opt(hc)
+	}
+	if hc.delay < 0 {
+		hc.delay = h.currentDelay()
+	}
+
+	atomic.AddInt64(&h.calls, 1)
+	h.earn()
+
+	results := make(chan *attempt, 2)
+	run := func(a *attempt, c Client) {
+		a.res, a.err = f(c)
+		a.end = now()
+		close(a.done)
+		results <- a
+	}
+
+	c, err := h.p.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	primary := &attempt{c: c, start: now(), done: make(chan struct{})}
+	go run(primary, c)
+	attempts := []*attempt{primary}
+	defer h.observeAttempt(primary)
+
+	var timer <-chan time.Time
+	if !hc.disabled {
+		t := time.NewTimer(hc.delay)
+		defer t.Stop()
+		timer = t.C
+	}
+
+	var winner, failed *attempt
+	for pending := 1; winner == nil && pending > 0; {
+		select {
+		case a := <-results:
+			pending--
+			if a.err == nil {
+				winner = a
+			} else if failed == nil || !a.hedge {
+				failed = a
+			}
+		case <-timer:
+			timer = nil
+			if !h.spend() {
+				atomic.AddInt64(&h.overBudget, 1)
+				continue
+			}
+			attempts = append(attempts, h.launchHedge(withExcludedInstance(ctx, clientAddr(primary.c)), run, results))
+			pending++
+		case <-ctx.Done():
+			for _, a := range attempts {
+				abandon(a)
+			}
+			return nil, ctx.Err()
+		}
+	}
+	if winner == nil {
+		winner = failed
+	}
+
+	if winner.hedge && winner.err == nil {
+		atomic.AddInt64(&h.hedgeWins, 1)
+	}
+	for _, a := range attempts {
+		if a != winner {
+			abandon(a)
+		}
+	}
+	if winner.c != nil {
+		winner.c.Close()
+	}
+	return winner.res, winner.err
+}
+
+// launchHedge borrows a client for a hedge attempt and runs it in the
+// background, so that waiting for a connection does not hold the primary
+// attempt up. An attempt which cannot borrow a client fails with the error.
+func (h *hedger) launchHedge(ctx context.Context, run func(*attempt, Client), results chan<- *attempt) *attempt {
+	ctx, cancel := context.WithCancel(ctx)
+	a := &attempt{hedge: true, start: now(), cancel: cancel, done: make(chan struct{})}
+
+	go func() {
+		c, err := h.p.GetContext(ctx)
+		cancel()
+		if err == nil && !a.acquire(c) {
+			c.Close()
+			err = context.Canceled
+		}
+		if err != nil {
+			a.err = err
+			close(a.done)
+			results <- a
+			return
+		}
+
+		atomic.AddInt64(&h.hedged, 1)
+		run(a, c)
+	}()
+	return a
+}
+
+// acquire sets the client of a, and reports false if a has been abandoned
+// while it was waiting for the client.
+func (a *attempt) acquire(c Client) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.abandoned {
+		return false
+	}
+	a.c = c
+	return true
+}
+
+// observeAttempt records the latency of the primary attempt, or the time it
+// has taken so far if it is abandoned, so that the slow primaries beaten by
+// a hedge are not left out of the delay.
+func (h *hedger) observeAttempt(a *attempt) {
+	select {
+	case <-a.done:
+		h.observe(a.end.Sub(a.start))
+	default:
+		h.observe(now().Sub(a.start))
+	}
+}
+
+// abandon closes the connection of an attempt which is still in flight, so
+// that it is not returned to the pool with a pending response, and closes the
+// client once the attempt returns. An attempt still waiting for a client
+// stops waiting.
+func abandon(a *attempt) {
+	a.mu.Lock()
+	a.abandoned = true
+	c := a.c
+	a.mu.Unlock()
+
+	if a.cancel != nil {
+		a.cancel()
+	}
+	if c == nil {
+		return
+	}
+
+	select {
+	case <-a.done:
+		c.Close()
+		return
+	default:
+	}
+
+	if c, ok := c.(*client); ok {
+		atomic.StoreInt32(&c.abandoned, 1)
+		if c.conn != nil && c.conn.ct != nil && c.conn.ct.socket != nil {
+			c.conn.ct.socket.Interrupt()
+		}
+	}
+	go func() {
+		<-a.done
+		c.Close()
+	}()
+}
+
+func clientAddr(c Client) string {
+	if c, ok := c.(*client); ok && c.conn != nil {
+		return c.conn.addr()
+	}
+	return ""
+}
+
+// earn adds the budget of a call to the hedge tokens.
+func (h *hedger) earn() {
+	h.mu.Lock()
+	h.tokens += h.budget
+	if h.tokens > h.burst {
+		h.tokens = h.burst
+	}
+	h.mu.Unlock()
+}
+
+// spend takes a hedge token, and reports whether the budget allows a hedge.
+func (h *hedger) spend() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.tokens < 1 {
+		return false
+	}
+	h.tokens--
+	return true
+}
+
+func (h *hedger) currentDelay() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.delay
+}
+
+// observe records the latency of a primary attempt, and refreshes the delay
+// from the percentile of the recent latencies every once in a while.
+func (h *hedger) observe(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.samples) < hedgeSamples {
+		h.samples = append(h.samples, d)
+	} else {
+		h.samples[h.next] = d
+		h.next = (h.next + 1) % hedgeSamples
+	}
+
+	h.fresh++
+	if len(h.samples) < hedgeMinSamples || h.fresh < hedgeRefresh {
+		return
+	}
+	h.fresh = 0
+
+	sorted := make([]time.Duration, len(h.samples))
+	copy(sorted, h.samples)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+
+	delay := sorted[int(float64(len(sorted)-1)*h.percentile)]
+	if delay < h.minDelay {
+		delay = h.minDelay
+	}
+	if delay > h.maxDelay {
+		delay = h.maxDelay
+	}
+	h.delay = delay
+}
+
+type excludeKey struct{}
+
+// withExcludedInstance asks the pool to borrow a client connected to any
+// instance other than addr, if there is one.
+func withExcludedInstance(ctx context.Context, addr string) context.Context {
+	if addr == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, excludeKey{}, addr)
+}
+
+func excludedInstance(ctx context.Context) string {
+	addr, _ := ctx.Value(excludeKey{}).(string)
+	return addr
+}
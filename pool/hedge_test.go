@@ -0,0 +1,115 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+)
+
+type hedgeClient struct {
+	Client
+	delay  time.Duration
+	closed int32
+}
+
+func (c *hedgeClient) GetRow(name, row string, attributes map[string]string) ([]*hbase.TRowResult_, error) {
+	time.Sleep(c.delay)
+	return []*hbase.TRowResult_{{Row: hbase.Text(row)}}, nil
+}
+
+func (c *hedgeClient) Close() error {
+	atomic.StoreInt32(&c.closed, 1)
+	return nil
+}
+
+type hedgePool struct {
+	Pool
+	delays []time.Duration
+	// block makes the Gets after the first wait until ctx is done.
+	block bool
+
+	sync.Mutex
+	clients []*hedgeClient
+}
+
+func (p *hedgePool) GetContext(ctx context.Context) (Client, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	i := len(p.clients)
+	if p.block && i > 0 {
+		p.Unlock()
+		<-ctx.Done()
+		p.Lock()
+		return nil, ctx.Err()
+	}
+	if i >= len(p.delays) {
+		i = len(p.delays) - 1
+	}
+	c := &hedgeClient{delay: p.delays[i]}
+	p.clients = append(p.clients, c)
+	return c, nil
+}
+
+func TestHedger_GetRow(t *testing.T) {
+	p := &hedgePool{delays: []time.Duration{time.Second, 0}}
+	h := NewHedger(p, WithHedgeDelay(0, time.Millisecond*10))
+
+	rsp, err := h.GetRow(context.Background(), "t", "r", nil)
+	if err != nil || len(rsp) != 1 || string(rsp[0].Row) != "r" {
+		t.Fatalf("unexpected result %v, %v", rsp, err)
+	}
+	if s := h.Stats(); s.Hedged != 1 || s.HedgeWins != 1 {
+		t.Errorf("expected the hedge to win, got %+v", s)
+	}
+	if len(p.clients) != 2 || atomic.LoadInt32(&p.clients[1].closed) != 1 {
+		t.Errorf("expected the winner to be closed")
+	}
+
+	// the primary beaten by the hedge is observed as well
+	h.(*hedger).mu.Lock()
+	samples := len(h.(*hedger).samples)
+	h.(*hedger).mu.Unlock()
+	if samples != 1 {
+		t.Errorf("expected the primary latency to be observed, got %d samples", samples)
+	}
+}
+
+func TestHedger_Budget(t *testing.T) {
+	p := &hedgePool{delays: []time.Duration{time.Millisecond * 20, time.Millisecond * 20}}
+	h := NewHedger(p, WithHedgeDelay(0, time.Millisecond), WithHedgeBudget(0, 1))
+
+	if _, err := h.GetRow(context.Background(), "t", "r", nil); err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+	if _, err := h.GetRow(context.Background(), "t", "r", nil, NoHedge()); err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+	if _, err := h.GetRow(context.Background(), "t", "r", nil); err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+	if s := h.Stats(); s.Hedged != 1 || s.OverBudget != 1 {
+		t.Errorf("expected one hedge and one over budget, got %+v", s)
+	}
+}
+
+func TestHedger_HedgeWaits(t *testing.T) {
+	p := &hedgePool{delays: []time.Duration{time.Millisecond * 50}, block: true}
+	h := NewHedger(p, WithHedgeDelay(0, time.Millisecond))
+
+	// the primary answers while the hedge is still waiting for a client
+	start := now()
+	if _, err := h.GetRow(context.Background(), "t", "r", nil); err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+	if d := now().Sub(start); d > time.Millisecond*500 {
+		t.Errorf("the hedge waiting for a client holds the primary up for %v", d)
+	}
+	if s := h.Stats(); s.Hedged != 0 || s.HedgeWins != 0 {
+		t.Errorf("expected no hedge sent, got %+v", s)
+	}
+}
@@ -1,15 +1,27 @@
 package pool
 
 import (
+	"context"
+
 	"github.com/popeyeio/gohbase/gen/hbase"
 )
 
+// Pool is implemented by this package, and gains methods as the pool gains
+// features. Implementations outside of it, such as fakes in tests, should
+// embed Pool to keep compiling.
 type Pool interface {
 	Get() (Client, error)
+	GetContext(context.Context) (Client, error)
 	Close() error
+	Shutdown(context.Context) error
 	IsClosed() bool
+	Stats() Stats
+	Reconfigure(...Option) error
 }
 
+// Client is implemented by this package, and gains methods as the thrift api
+// gets covered. Implementations outside of it, such as fakes in tests, should
+// embed Client to keep compiling.
 type Client interface {
 	Close() error
 	IsClosed() bool
@@ -23,12 +35,20 @@ type Client interface {
 	CreateTable(string, []*hbase.ColumnDescriptor) error
 	DeleteTable(string) error
 	Get(string, string, string, map[string]string) ([]*hbase.TCell, error)
+	GetVer(string, string, string, int32, map[string]string) ([]*hbase.TCell, error)
 	GetRow(string, string, map[string]string) ([]*hbase.TRowResult_, error)
 	GetRowWithColumns(string, string, []string, map[string]string) ([]*hbase.TRowResult_, error)
 	GetRows(string, []string, map[string]string) ([]*hbase.TRowResult_, error)
 	GetRowsWithColumns(string, []string, []string, map[string]string) ([]*hbase.TRowResult_, error)
 	MutateRow(string, string, []*hbase.Mutation, map[string]string) error
 	MutateRows(string, []*hbase.BatchMutation, map[string]string) error
+	MutateRowsTs(string, []*hbase.BatchMutation, int64, map[string]string) error
+	DeleteAll(string, string, string, map[string]string) error
+	DeleteAllRow(string, string, map[string]string) error
+	CheckAndPut(string, string, string, []byte, *hbase.Mutation, map[string]string) (bool, error)
+	AtomicIncrement(string, string, string, int64) (int64, error)
+	Increment(*hbase.TIncrement) error
+	IncrementRows([]*hbase.TIncrement) error
 	ScannerOpenWithScan(string, *hbase.TScan, map[string]string) (hbase.ScannerID, error)
 	ScannerOpen(string, string, []string, map[string]string) (hbase.ScannerID, error)
 	ScannerOpenWithStop(string, string, string, []string, map[string]string) (hbase.ScannerID, error)
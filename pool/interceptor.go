@@ -0,0 +1,106 @@
+package pool
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Call describes a single Client invocation.
+//
+// Method is the name of the Client method, e.g. "GetRow". Table and Rows are
+// empty for calls which are not bound to a table or a row, e.g. "ScannerGet".
+// Args holds every argument of the method except the attributes, in the
+// order of the method signature. Attributes may be modified by interceptors
+// before the call reaches hbase. Result holds the value returned by the
+// method once the call completes, and is typed the same as the method result.
+type Call struct {
+	Method     string
+	Table      string
+	Rows       []string
+	Attributes map[string]string
+	Args       []interface{}
+	Result     interface{}
+}
+
+// CallKey identifies a call by its method, arguments and attributes, so that
+// calls with equal keys are the same call. It reports false if an argument is
+// neither a string nor a slice of strings.
+func CallKey(call *Call) (string, bool) {
+	var b strings.Builder
+	write := func(s string) {
+		b.WriteString(strconv.Itoa(len(s)))
+		b.WriteByte(':')
+		b.WriteString(s)
+	}
+
+	write(call.Method)
+	for _, arg := range call.Args {
+		switch arg := arg.(type) {
+		case string:
+			write(arg)
+		case []string:
+			b.WriteString(strconv.Itoa(len(arg)))
+			b.WriteByte('[')
+			for _, s := range arg {
+				write(s)
+			}
+		default:
+			return "", false
+		}
+	}
+
+	keys := make([]string, 0, len(call.Attributes))
+	for k := range call.Attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	b.WriteString(strconv.Itoa(len(keys)))
+	b.WriteByte('{')
+	for _, k := range keys {
+		write(k)
+		write(call.Attributes[k])
+	}
+	return b.String(), true
+}
+
+// Handler performs a Call.
+type Handler func(*Call) error
+
+// Interceptor intercepts every Client or Executor call, according to where it
+// is installed. It may inspect or modify the call, invoke the next handler, or
+// short-circuit by setting call.Result and returning without invoking it.
+type Interceptor func(call *Call, next Handler) error
+
+// ChainInterceptors creates a single interceptor out of a chain of many.
+// The first interceptor is the outermost one, and the last is the innermost
+// wrapper around the real call.
+func ChainInterceptors(interceptors ...Interceptor) Interceptor {
+	chain := make([]Interceptor, 0, len(interceptors))
+	for _, interceptor := range interceptors {
+		if interceptor != nil {
+			chain = append(chain, interceptor)
+		}
+	}
+
+	switch len(chain) {
+	case 0:
+		return nil
+	case 1:
+		return chain[0]
+	}
+
+	return func(call *Call, next Handler) error {
+		return chain[0](call, chainHandler(chain, 1, next))
+	}
+}
+
+func chainHandler(chain []Interceptor, i int, next Handler) Handler {
+	if i == len(chain) {
+		return next
+	}
+
+	return func(call *Call) error {
+		return chain[i](call, chainHandler(chain, i+1, next))
+	}
+}
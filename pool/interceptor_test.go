@@ -0,0 +1,63 @@
+package pool
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestChainInterceptors(t *testing.T) {
+	var order []string
+	newInterceptor := func(name string) Interceptor {
+		return func(call *Call, next Handler) error {
+			order = append(order, name+":before")
+			err := next(call)
+			order = append(order, name+":after")
+			return err
+		}
+	}
+
+	interceptor := ChainInterceptors(newInterceptor("a"), nil, newInterceptor("b"))
+	err := interceptor(&Call{Method: "GetRow"}, func(*Call) error {
+		order = append(order, "call")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("interceptor error - %v", err)
+	}
+
+	expected := []string{"a:before", "b:before", "call", "b:after", "a:after"}
+	if !reflect.DeepEqual(order, expected) {
+		t.Errorf("expected order %v, got %v", expected, order)
+	}
+}
+
+func TestClient_Interceptor(t *testing.T) {
+	errShortCircuit := errors.New("short circuit")
+	c := &client{
+		interceptor: ChainInterceptors(
+			func(call *Call, next Handler) error {
+				call.Attributes = map[string]string{"k": "v"}
+				return next(call)
+			},
+			func(call *Call, next Handler) error {
+				if call.Method != "IsTableEnabled" || call.Table != "t" || call.Attributes["k"] != "v" {
+					return errShortCircuit
+				}
+				call.Result = true
+				return nil
+			},
+		),
+	}
+
+	enabled, err := c.IsTableEnabled("t")
+	if err != nil || !enabled {
+		t.Errorf("expected short-circuit result, got %v, %v", enabled, err)
+	}
+	if err = c.EnableTable("t"); err != errShortCircuit {
+		t.Errorf("expected error %v, got %v", errShortCircuit, err)
+	}
+	if c.errs.Len() != 0 {
+		t.Errorf("short-circuit errors must not break the client - %v", c.errs)
+	}
+}
@@ -0,0 +1,142 @@
+package pool
+
+import (
+	"errors"
+	"runtime"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrClientReclaimed = errors.New("[gohbase] client is reclaimed")
+)
+
+const (
+	// minLeakCheckInterval is the floor of the interval leaks are checked at.
+	minLeakCheckInterval = time.Millisecond * 10
+)
+
+// lease records a borrowed client for leak detection. It must not refer to
+// the client, so that the client can be garbage collected and finalized.
+type lease struct {
+	cn       *conn
+	t        time.Time
+	stack    []byte
+	reported bool
+
+	reclaimed int32
+}
+
+// track starts tracking c if leak detection is enabled.
+func (p *pool) track(c *client, cfg *config) {
+	if cfg.leakThreshold <= 0 {
+		return
+	}
+
+	l := &lease{
+		cn:    c.conn,
+		t:     now(),
+		stack: debug.Stack(),
+	}
+
+	p.Lock()
+	p.leases[l] = struct{}{}
+	p.Unlock()
+
+	c.lease = l
+	runtime.SetFinalizer(c, finalizeClient)
+}
+
+// untrack stops tracking l, and reports whether l was still tracked,
+// i.e. it was not reclaimed.
+func (p *pool) untrack(l *lease) bool {
+	p.Lock()
+	defer p.Unlock()
+
+	if _, ok := p.leases[l]; !ok {
+		return false
+	}
+	delete(p.leases, l)
+	return true
+}
+
+func (p *pool) asyncDetectLeaks(cfg *config, stop <-chan struct{}) {
+	if cfg.leakThreshold <= 0 {
+		return
+	}
+
+	p.runEvery(leakCheckInterval(cfg.leakThreshold), stop, p.detectLeaks)
+}
+
+// leakCheckInterval checks for leaks several times per threshold, so that a
+// leak is reported soon after it exceeds the threshold.
+func leakCheckInterval(threshold time.Duration) time.Duration {
+	if interval := threshold / 4; interval > minLeakCheckInterval {
+		return interval
+	}
+	return minLeakCheckInterval
+}
+
+func (p *pool) detectLeaks() {
+	var leaked []*lease
+
+	p.Lock()
+	cfg := p.conf()
+	for l := range p.leases {
+		if l.reported || cfg.leakThreshold <= 0 || now().Sub(l.t) < cfg.leakThreshold {
+			continue
+		}
+
+		l.reported = true
+		if cfg.reclaimLeaks {
+			delete(p.leases, l)
+		}
+		leaked = append(leaked, l)
+	}
+	p.Unlock()
+
+	for _, l := range leaked {
+		atomic.AddInt64(&p.counters.leaked, 1)
+		cfg.logger.Warn("[gohbase] client leaked",
+			"instance", l.cn.addr(),
+			"borrowed_at", l.t,
+			"held", now().Sub(l.t),
+			"stack", string(l.stack),
+		)
+
+		if cfg.reclaimLeaks {
+			p.reclaim(l)
+			atomic.AddInt64(&p.counters.reclaimed, 1)
+		}
+	}
+}
+
+// reclaim closes the connection of a leased client which is no longer tracked,
+// and releases its slot.
+func (p *pool) reclaim(l *lease) {
+	atomic.StoreInt32(&l.reclaimed, 1)
+	l.cn.close()
+
+	p.Lock()
+	delete(p.borrowed, l.cn)
+	leaveBulkheads(l.cn.bulkheads)
+	l.cn.bulkheads = nil
+	p.release()
+	p.Unlock()
+}
+
+func finalizeClient(c *client) {
+	if c.IsClosed() || !c.p.untrack(c.lease) {
+		return
+	}
+
+	p := c.p
+	atomic.AddInt64(&p.counters.finalized, 1)
+	p.conf().logger.Warn("[gohbase] client garbage collected without being closed",
+		"instance", c.lease.cn.addr(),
+		"borrowed_at", c.lease.t,
+		"stack", string(c.lease.stack),
+	)
+	p.reclaim(c.lease)
+}
@@ -0,0 +1,73 @@
+package pool
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+	"github.com/popeyeio/gohbase/lib/thrift"
+)
+
+func TestPool_DetectLeaks(t *testing.T) {
+	p := NewPool(WithLeakDetection(time.Hour, true)).(*pool)
+	defer p.Close()
+
+	p.active = 1
+	c := p.newClient(newMemoryConn(), p.conf())
+
+	p.detectLeaks()
+	if s := p.Stats(); s.Leaked != 0 {
+		t.Fatalf("client reported as leaked before threshold - %+v", s)
+	}
+
+	c.lease.t = now().Add(-2 * time.Hour)
+	p.detectLeaks()
+	if s := p.Stats(); s.Leaked != 1 || s.Reclaimed != 1 || s.Active != 0 {
+		t.Errorf("leaked client is not reclaimed - %+v", s)
+	}
+	if !c.IsClosed() {
+		t.Errorf("reclaimed client is not closed")
+	}
+	if err := c.Close(); err != ErrClientReclaimed {
+		t.Errorf("expected error %v, got %v", ErrClientReclaimed, err)
+	}
+}
+
+func TestPool_FinalizeLeakedClient(t *testing.T) {
+	p := NewPool(WithLeakDetection(time.Hour, false)).(*pool)
+	defer p.Close()
+
+	p.active = 1
+	func() {
+		p.newClient(newMemoryConn(), p.conf())
+	}()
+
+	for deadline := now().Add(time.Second * 5); p.Stats().Finalized == 0; {
+		if now().After(deadline) {
+			t.Fatalf("client is not finalized - %+v", p.Stats())
+		}
+		runtime.GC()
+		time.Sleep(time.Millisecond * 10)
+	}
+	if s := p.Stats(); s.Finalized != 1 || s.Active != 0 || s.Leaked != 0 {
+		t.Errorf("finalized client is not reclaimed - %+v", s)
+	}
+}
+
+func TestLeakCheckInterval(t *testing.T) {
+	if d := leakCheckInterval(time.Minute); d != time.Second*15 {
+		t.Errorf("expected leaks checked 4 times per threshold, got every %v", d)
+	}
+	if d := leakCheckInterval(time.Millisecond); d != minLeakCheckInterval {
+		t.Errorf("expected the floor interval, got %v", d)
+	}
+}
+
+func newMemoryConn() *conn {
+	ct := newCountingTransport(thrift.NewTMemoryBuffer(), nil)
+	return &conn{
+		hc: hbase.NewHbaseClientFactory(ct, thrift.NewTBinaryProtocolFactoryDefault()),
+		ct: ct,
+	}
+}
@@ -0,0 +1,135 @@
+package pool
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+)
+
+const (
+	// maxLoggedRows is the max number of row keys logged for a single call.
+	maxLoggedRows = 10
+)
+
+var (
+	discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+)
+
+// logSlowCall logs the call if it took longer than the slow call threshold.
+func (p *pool) logSlowCall(call *Call, cn *conn, d time.Duration, err error) {
+	cfg := p.conf()
+	if cfg.slowCallThreshold <= 0 || d < cfg.slowCallThreshold {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", call.Method),
+		slog.Duration("duration", d),
+	}
+	if call.Table != "" {
+		attrs = append(attrs, slog.String("table", call.Table))
+	}
+	if len(call.Rows) > 0 {
+		attrs = append(attrs, slog.Int("row_count", len(call.Rows)), slog.Any("rows", p.formatRows(call.Rows)))
+	}
+	if scan := p.scanParams(call); len(scan) > 0 {
+		attrs = append(attrs, slog.Any("scan", slog.GroupValue(scan...)))
+	}
+	if cn != nil {
+		attrs = append(attrs, slog.String("instance", cn.addr()))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+
+	cfg.logger.LogAttrs(context.Background(), slog.LevelWarn, "[gohbase] slow call", attrs...)
+}
+
+func (p *pool) formatRows(rows []string) []string {
+	n := len(rows)
+	if n > maxLoggedRows {
+		n = maxLoggedRows
+	}
+
+	res := make([]string, n)
+	for i := 0; i < n; i++ {
+		res[i] = p.formatRow(rows[i])
+	}
+	return res
+}
+
+// formatRow formats a row key for logging, hashing or truncating it
+// according to the options of the pool.
+func (p *pool) formatRow(row string) string {
+	cfg := p.conf()
+	if cfg.hashRowKeys {
+		sum := sha256.Sum256([]byte(row))
+		return hex.EncodeToString(sum[:8])
+	}
+
+	if cfg.maxRowKeyLen > 0 && len(row) > cfg.maxRowKeyLen {
+		return strconv.Quote(row[:cfg.maxRowKeyLen]) + "..."
+	}
+	return strconv.Quote(row)
+}
+
+func (p *pool) scanParams(call *Call) []slog.Attr {
+	var attrs []slog.Attr
+
+	switch call.Method {
+	case "ScannerOpenWithScan":
+		if scan, _ := arg(call, 1).(*hbase.TScan); scan != nil {
+			attrs = append(attrs,
+				slog.String("start_row", p.formatRow(string(scan.StartRow))),
+				slog.String("stop_row", p.formatRow(string(scan.StopRow))),
+			)
+			if len(scan.FilterString) > 0 {
+				attrs = append(attrs, slog.String("filter", string(scan.FilterString)))
+			}
+			if scan.Caching != nil {
+				attrs = append(attrs, slog.Int("caching", int(*scan.Caching)))
+			}
+			if scan.BatchSize != nil {
+				attrs = append(attrs, slog.Int("batch_size", int(*scan.BatchSize)))
+			}
+			if scan.Reversed != nil && *scan.Reversed {
+				attrs = append(attrs, slog.Bool("reversed", true))
+			}
+		}
+	case "ScannerOpen":
+		if start, ok := arg(call, 1).(string); ok {
+			attrs = append(attrs, slog.String("start_row", p.formatRow(start)))
+		}
+	case "ScannerOpenWithStop":
+		if start, ok := arg(call, 1).(string); ok {
+			attrs = append(attrs, slog.String("start_row", p.formatRow(start)))
+		}
+		if stop, ok := arg(call, 2).(string); ok {
+			attrs = append(attrs, slog.String("stop_row", p.formatRow(stop)))
+		}
+	case "ScannerOpenWithPrefix":
+		if prefix, ok := arg(call, 1).(string); ok {
+			attrs = append(attrs, slog.String("prefix", p.formatRow(prefix)))
+		}
+	case "ScannerGetList":
+		if nbRows, ok := arg(call, 1).(int32); ok {
+			attrs = append(attrs, slog.Int("nb_rows", int(nbRows)))
+		}
+	}
+	return attrs
+}
+
+// arg returns the i-th argument of the call, or nil if there is none, since
+// interceptors may have rewritten the arguments.
+func arg(call *Call, i int) interface{} {
+	if i >= len(call.Args) {
+		return nil
+	}
+	return call.Args[i]
+}
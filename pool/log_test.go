@@ -0,0 +1,95 @@
+package pool
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPool_LogSlowCall(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewPool(
+		WithAddrs("127.0.0.1:1"),
+		WithLogger(slog.New(slog.NewJSONHandler(&buf, nil))),
+		WithSlowCallThreshold(time.Second),
+	).(*pool)
+	defer p.Close()
+
+	rows := make([]string, maxLoggedRows+2)
+	for i := range rows {
+		rows[i] = fmt.Sprintf("row%d", i)
+	}
+	call := &Call{Method: "GetRows", Table: "t", Rows: rows}
+
+	p.logSlowCall(call, nil, time.Millisecond, nil)
+	if buf.Len() != 0 {
+		t.Fatalf("fast call is logged - %s", buf.String())
+	}
+
+	p.logSlowCall(call, nil, time.Second*2, nil)
+	var record struct {
+		Msg      string
+		Method   string
+		Table    string
+		RowCount int `json:"row_count"`
+		Rows     []string
+	}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("invalid log record %q - %v", buf.String(), err)
+	}
+	if record.Msg != "[gohbase] slow call" || record.Method != "GetRows" || record.Table != "t" {
+		t.Errorf("unexpected log record - %+v", record)
+	}
+	if record.RowCount != len(rows) || len(record.Rows) != maxLoggedRows || record.Rows[0] != `"row0"` {
+		t.Errorf("unexpected logged rows - %+v", record)
+	}
+}
+
+func TestPool_FormatRow(t *testing.T) {
+	p := NewPool(WithRowKeyRedaction(4, false)).(*pool)
+	defer p.Close()
+
+	if row := p.formatRow("abc"); row != `"abc"` {
+		t.Errorf("expected short row as is, got %s", row)
+	}
+	if row := p.formatRow("abcdefgh"); row != `"abcd"...` {
+		t.Errorf("expected truncated row, got %s", row)
+	}
+
+	if err := p.Reconfigure(WithRowKeyRedaction(4, true)); err != nil {
+		t.Fatalf("Reconfigure error - %v", err)
+	}
+	hashed := p.formatRow("abcdefgh")
+	if len(hashed) != 16 || strings.Contains(hashed, "abcd") {
+		t.Errorf("expected hashed row, got %s", hashed)
+	}
+	if p.formatRow("abcdefgh") != hashed || p.formatRow("abcdefgi") == hashed {
+		t.Errorf("row hashes must be stable and distinct")
+	}
+}
+
+func TestPool_ScanParams(t *testing.T) {
+	p := NewPool().(*pool)
+	defer p.Close()
+
+	attrs := p.scanParams(&Call{Method: "ScannerOpenWithStop", Args: []interface{}{"t", "a", "b"}})
+	if len(attrs) != 2 || attrs[0].Value.String() != `"a"` || attrs[1].Value.String() != `"b"` {
+		t.Errorf("unexpected scan params - %v", attrs)
+	}
+
+	// arguments rewritten by an interceptor must not panic
+	for _, call := range []*Call{
+		{Method: "ScannerOpen", Args: []interface{}{"t"}},
+		{Method: "ScannerOpenWithStop", Args: []interface{}{"t", []byte("a"), nil}},
+		{Method: "ScannerOpenWithPrefix"},
+		{Method: "ScannerGetList", Args: []interface{}{1, 10}},
+	} {
+		if attrs := p.scanParams(call); len(attrs) != 0 {
+			t.Errorf("unexpected scan params for %s - %v", call.Method, attrs)
+		}
+	}
+}
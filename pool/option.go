@@ -1,21 +1,23 @@
 package pool
 
 import (
+	"log/slog"
 	"time"
 
 	"github.com/popeyeio/gohbase/balancer"
 	"github.com/popeyeio/gohbase/discovery"
-	"github.com/popeyeio/gohbase/gen/hbase"
 	"github.com/popeyeio/gohbase/instance"
 	"github.com/popeyeio/gohbase/lib/thrift"
 )
 
-type Option func(*pool)
+// Option configures a pool. An invalid option leaves the pool unchanged, and
+// returns an error which is logged by NewPool and returned by Reconfigure.
+type Option func(*config) error
 
 func WithAddrs(addrs ...string) Option {
-	return func(p *pool) {
+	return func(cfg *config) error {
 		if len(addrs) == 0 {
-			return
+			return invalidOption("addrs", addrs)
 		}
 
 		instances := make([]instance.Instance, len(addrs))
@@ -23,102 +25,371 @@ func WithAddrs(addrs ...string) Option {
 			instances[i] = instance.NewCustomInstance(addr)
 		}
 
-		p.discovery = discovery.NewCustomDiscovery(instances...)
+		cfg.discovery = discovery.NewCustomDiscovery(instances...)
+		return nil
 	}
 }
 
 func WithInstances(instances ...instance.Instance) Option {
-	return func(p *pool) {
+	return func(cfg *config) error {
 		if len(instances) == 0 {
-			return
+			return invalidOption("instances", instances)
 		}
 
-		p.discovery = discovery.NewCustomDiscovery(instances...)
+		cfg.discovery = discovery.NewCustomDiscovery(instances...)
+		return nil
 	}
 }
 
 func WithDiscovery(sd discovery.Discovery) Option {
-	return func(p *pool) {
-		if sd != nil {
-			p.discovery = sd
+	return func(cfg *config) error {
+		if sd == nil {
+			return invalidOption("discovery", sd)
 		}
+
+		cfg.discovery = sd
+		return nil
 	}
 }
 
 func WithBalancer(lb balancer.Balancer) Option {
-	return func(p *pool) {
-		if lb != nil {
-			p.balancer = lb
+	return func(cfg *config) error {
+		if lb == nil {
+			return invalidOption("balancer", lb)
 		}
+
+		cfg.balancer = lb
+		return nil
 	}
 }
 
 func WithUpdatePickerInterval(interval time.Duration) Option {
-	return func(p *pool) {
-		if interval >= 0 {
-			p.updatePickerInterval = interval
+	return func(cfg *config) error {
+		if interval < 0 {
+			return invalidOption("update picker interval", interval)
 		}
+
+		cfg.updatePickerInterval = interval
+		return nil
 	}
 }
 
 func WithSocketTimeout(timeout time.Duration) Option {
-	return func(p *pool) {
-		if timeout >= 0 {
-			p.socketTimeout = timeout
+	return func(cfg *config) error {
+		if timeout < 0 {
+			return invalidOption("socket timeout", timeout)
 		}
+
+		cfg.socketTimeout = timeout
+		return nil
 	}
 }
 
 func WithTransportFactory(factory thrift.TTransportFactory) Option {
-	return func(p *pool) {
-		if factory != nil {
-			p.transportFactory = factory
+	return func(cfg *config) error {
+		if factory == nil {
+			return invalidOption("transport factory", factory)
+		}
+
+		cfg.transportFactory = factory
+		return nil
+	}
+}
+
+func WithProtocolFactory(factory thrift.TProtocolFactory) Option {
+	return func(cfg *config) error {
+		if factory == nil {
+			return invalidOption("protocol factory", factory)
 		}
+
+		cfg.protocolFactory = factory
+		return nil
 	}
 }
 
 func WithMaxActive(maxActive int) Option {
-	return func(p *pool) {
-		if maxActive >= 0 {
-			p.maxActive = maxActive
+	return func(cfg *config) error {
+		if maxActive < 0 {
+			return invalidOption("max active", maxActive)
 		}
+
+		cfg.maxActive = maxActive
+		return nil
 	}
 }
 
 func WithMaxIdle(maxIdle int) Option {
-	return func(p *pool) {
-		if maxIdle >= 0 {
-			p.maxIdle = maxIdle
+	return func(cfg *config) error {
+		if maxIdle < 0 {
+			return invalidOption("max idle", maxIdle)
 		}
+
+		cfg.maxIdle = maxIdle
+		return nil
 	}
 }
 
 func WithIdleTimeout(timeout time.Duration) Option {
-	return func(p *pool) {
-		if timeout >= 0 {
-			p.idleTimeout = timeout
+	return func(cfg *config) error {
+		if timeout < 0 {
+			return invalidOption("idle timeout", timeout)
 		}
+
+		cfg.idleTimeout = timeout
+		return nil
 	}
 }
 
 func WithCleanUpInterval(interval time.Duration) Option {
-	return func(p *pool) {
-		if interval >= 0 {
-			p.cleanUpInterval = interval
+	return func(cfg *config) error {
+		if interval < 0 {
+			return invalidOption("clean up interval", interval)
 		}
+
+		cfg.cleanUpInterval = interval
+		return nil
 	}
 }
 
 func WithBlockMode(isBlocked bool) Option {
-	return func(p *pool) {
-		p.isBlocked = isBlocked
+	return func(cfg *config) error {
+		cfg.isBlocked = isBlocked
+		return nil
+	}
+}
+
+func WithHealthChecker(checker HealthChecker) Option {
+	return func(cfg *config) error {
+		if checker == nil {
+			return invalidOption("health checker", checker)
+		}
+
+		cfg.healthChecker = checker
+		return nil
+	}
+}
+
+// WithBackgroundValidation validates idle connections with the health checker
+// in the clean up goroutine, instead of on every Get.
+func WithBackgroundValidation(enabled bool) Option {
+	return func(cfg *config) error {
+		cfg.backgroundValidation = enabled
+		return nil
+	}
+}
+
+// WithInterceptors appends interceptors to the chain which every Client call
+// runs through. Interceptors run in the order they are given, across all
+// WithInterceptors options.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(cfg *config) error {
+		for _, interceptor := range interceptors {
+			if interceptor != nil {
+				cfg.interceptors = append(cfg.interceptors, interceptor)
+			}
+		}
+		return nil
+	}
+}
+
+func WithTracer(tracer Tracer) Option {
+	return func(cfg *config) error {
+		if tracer == nil {
+			return invalidOption("tracer", tracer)
+		}
+
+		cfg.tracer = tracer
+		return nil
+	}
+}
+
+// WithTracePropagation enables injecting the span context of every call
+// into the attributes sent to hbase, so that server side coprocessors can
+// correlate it.
+func WithTracePropagation(enabled bool) Option {
+	return func(cfg *config) error {
+		cfg.propagateTrace = enabled
+		return nil
+	}
+}
+
+func WithLogger(logger *slog.Logger) Option {
+	return func(cfg *config) error {
+		if logger == nil {
+			return invalidOption("logger", logger)
+		}
+
+		cfg.logger = logger
+		return nil
+	}
+}
+
+// WithSlowCallThreshold logs every call which takes longer than threshold.
+// Zero disables the slow call log.
+func WithSlowCallThreshold(threshold time.Duration) Option {
+	return func(cfg *config) error {
+		if threshold < 0 {
+			return invalidOption("slow call threshold", threshold)
+		}
+
+		cfg.slowCallThreshold = threshold
+		return nil
 	}
 }
 
-func WithHealthChecker(checker func(*hbase.HbaseClient, time.Time) error) Option {
-	return func(p *pool) {
-		if checker != nil {
-			p.healthChecker = checker
+// WithRowKeyRedaction truncates row keys longer than maxLen bytes in logs,
+// or replaces them with a short hash if hashed is true.
+func WithRowKeyRedaction(maxLen int, hashed bool) Option {
+	return func(cfg *config) error {
+		if maxLen < 0 {
+			return invalidOption("max row key length", maxLen)
 		}
+
+		cfg.maxRowKeyLen = maxLen
+		cfg.hashRowKeys = hashed
+		return nil
+	}
+}
+
+// WithLeakDetection reports every client which is borrowed for longer than
+// threshold, along with the stack trace where it was borrowed. If reclaim is
+// true, such clients are closed by force and their slots are released.
+func WithLeakDetection(threshold time.Duration, reclaim bool) Option {
+	return func(cfg *config) error {
+		if threshold < 0 {
+			return invalidOption("leak threshold", threshold)
+		}
+
+		cfg.leakThreshold = threshold
+		cfg.reclaimLeaks = reclaim
+		return nil
+	}
+}
+
+// WithMaxWait limits the time Get waits for a connection in block mode.
+// Zero means no limit.
+func WithMaxWait(d time.Duration) Option {
+	return func(cfg *config) error {
+		if d < 0 {
+			return invalidOption("max wait", d)
+		}
+
+		cfg.maxWait = d
+		return nil
+	}
+}
+
+// WithMaxWaiters limits the number of goroutines waiting for a connection
+// in block mode, beyond which Get fails fast with ErrTooManyWaiters.
+// Zero means no limit.
+func WithMaxWaiters(n int) Option {
+	return func(cfg *config) error {
+		if n < 0 {
+			return invalidOption("max waiters", n)
+		}
+
+		cfg.maxWaiters = n
+		return nil
+	}
+}
+
+// WithMinIdle dials min idle connections in the background when the pool is
+// created, and keeps at least min idle connections in the clean up goroutine.
+func WithMinIdle(min int) Option {
+	return func(cfg *config) error {
+		if min < 0 {
+			return invalidOption("min idle", min)
+		}
+
+		cfg.minIdle = min
+		return nil
+	}
+}
+
+// WithMaxLifetime recycles connections once they have been open for lifetime,
+// minus a random jitter so that connections are not recycled all at once.
+// Zero means connections are never recycled. jitter must be less than lifetime.
+func WithMaxLifetime(lifetime, jitter time.Duration) Option {
+	return func(cfg *config) error {
+		if lifetime < 0 {
+			return invalidOption("max lifetime", lifetime)
+		}
+		if jitter < 0 || (jitter > 0 && jitter >= lifetime) {
+			return invalidOption("max lifetime jitter", jitter)
+		}
+
+		cfg.maxLifetime = lifetime
+		cfg.lifetimeJitter = jitter
+		return nil
+	}
+}
+
+// WithTableBulkhead caps the number of connections borrowed for table at max,
+// zero removes the cap. Clients are borrowed for a table by ContextWithTable.
+func WithTableBulkhead(table string, max int) Option {
+	return func(cfg *config) error {
+		return cfg.setBulkhead("table:"+table, max)
+	}
+}
+
+// WithCallerBulkhead caps the number of connections borrowed by caller at max,
+// zero removes the cap. Clients are borrowed by a caller by ContextWithCallerTag.
+func WithCallerBulkhead(caller string, max int) Option {
+	return func(cfg *config) error {
+		return cfg.setBulkhead("caller:"+caller, max)
+	}
+}
+
+// WithBulkheadWait makes GetContext wait for a full bulkhead like it waits for
+// a full pool, rather than failing with ErrBulkheadFull at once.
+func WithBulkheadWait(wait bool) Option {
+	return func(cfg *config) error {
+		cfg.bulkheadWait = wait
+		return nil
+	}
+}
+
+// WithRateLimiter limits the rate of the calls made by the clients of the pool with l.
+func WithRateLimiter(l RateLimiter) Option {
+	return func(cfg *config) error {
+		if l == nil {
+			return invalidOption("rate limiter", l)
+		}
+		cfg.rateLimiter = l
+		return nil
+	}
+}
+
+// WithHighPriorityReserve reserves n of the max active connections for
+// requests of PriorityHigh, so that lower priorities may only borrow up to
+// max active minus n connections. n must be less than max active.
+func WithHighPriorityReserve(n int) Option {
+	return func(cfg *config) error {
+		if n < 0 {
+			return invalidOption("high priority reserve", n)
+		}
+
+		cfg.reserved = n
+		return nil
+	}
+}
+
+// WithLoadShedding rejects requests of PriorityLow with ErrLoadShed at once
+// while the moving average of the time to acquire a connection exceeds wait,
+// or the moving average rate of retryable call errors exceeds errorRate.
+// Zero disables the respective threshold. The averages decay over time, so
+// that requests are no longer shed once the pool has been quiet for a while.
+func WithLoadShedding(wait time.Duration, errorRate float64) Option {
+	return func(cfg *config) error {
+		if wait < 0 {
+			return invalidOption("load shedding wait", wait)
+		}
+		if errorRate < 0 || errorRate > 1 {
+			return invalidOption("load shedding error rate", errorRate)
+		}
+
+		cfg.shedWait = wait
+		cfg.shedErrorRate = errorRate
+		return nil
 	}
 }
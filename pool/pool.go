@@ -2,19 +2,21 @@ package pool
 
 import (
 	"container/list"
+	"context"
 	"errors"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/popeyeio/gohbase/balancer"
-	"github.com/popeyeio/gohbase/discovery"
 	"github.com/popeyeio/gohbase/gen/hbase"
+	"github.com/popeyeio/gohbase/instance"
 	"github.com/popeyeio/gohbase/lib/thrift"
 )
 
 var (
-	now = time.Now
+	now      = time.Now
+	newTimer = time.NewTimer
 
 	ErrPoolFull   = errors.New("[gohbase] pool is full")
 	ErrPoolClosed = errors.New("[gohbase] pool is closed")
@@ -23,97 +25,177 @@ var (
 type pool struct {
 	sync.Mutex
 
-	discovery            discovery.Discovery
-	balancer             balancer.Balancer
-	picker               balancer.Picker
-	updatePickerInterval time.Duration
+	// cfg holds the current *config.
+	cfg atomic.Value
 
-	socketTimeout    time.Duration
-	transportFactory thrift.TTransportFactory
-	protocolFactory  thrift.TProtocolFactory
+	picker    balancer.Picker
+	instances []instance.Instance
 
-	active          int
-	maxActive       int
-	maxIdle         int
-	idleTimeout     time.Duration
-	cleanUpInterval time.Duration
-	idleNodes       *list.List
-
-	isBlocked bool
-	cond      *sync.Cond
+	active    int
+	idleNodes *list.List
+	waiters   *list.List
 
 	closed    int32
 	closeChan chan struct{}
+	stopChan  chan struct{}
+	drainChan chan struct{}
+	drained   bool
+	borrowed  map[*conn]struct{}
+	wg        sync.WaitGroup
+
+	leases    map[*lease]struct{}
+	bulkheads map[string]*bulkhead
+	shedder   shedder
 
-	healthChecker func(*hbase.HbaseClient, time.Time) error
+	counters counters
 }
 
 var _ Pool = (*pool)(nil)
 
 type idleNode struct {
-	hc *hbase.HbaseClient
+	cn *conn
 	t  time.Time
 }
 
 func NewPool(opts ...Option) Pool {
 	p := &pool{
-		discovery:        discovery.NewCustomDiscovery(),
-		balancer:         balancer.NewRRBalancer(),
-		transportFactory: thrift.NewTBufferedTransportFactory(4096),
-		protocolFactory:  thrift.NewTBinaryProtocolFactoryDefault(),
-		idleNodes:        list.New(),
-		closeChan:        make(chan struct{}),
+		idleNodes: list.New(),
+		waiters:   list.New(),
+		closeChan: make(chan struct{}),
+		drainChan: make(chan struct{}),
+		borrowed:  make(map[*conn]struct{}),
+		leases:    make(map[*lease]struct{}),
+		bulkheads: make(map[string]*bulkhead),
 	}
-	for _, opt := range opts {
-		opt(p)
+
+	cfg := newConfig()
+	err := cfg.apply(opts...)
+	p.cfg.Store(cfg)
+	if err != nil {
+		cfg.logger.Error("[gohbase] invalid options are ignored", "error", err)
 	}
 
-	p.asyncUpdatePicker()
-	p.asyncCleanUp()
+	p.updatePicker()
+	p.warmUp()
+
+	p.Lock()
+	p.startBackground()
+	p.Unlock()
 	return p
 }
 
 func (p *pool) Get() (Client, error) {
+	return p.GetContext(context.Background())
+}
+
+func (p *pool) GetContext(ctx context.Context) (Client, error) {
 	if p.IsClosed() {
 		return nil, ErrPoolClosed
 	}
 
+	cfg := p.conf()
+	priority := priorityOf(ctx)
+	if err := p.shed(priority, cfg); err != nil {
+		return nil, err
+	}
+
 	p.cleanUpIdleNodes(false)
 
+	start := now()
+	var deadline time.Time
+	if cfg.maxWait > 0 {
+		deadline = start.Add(cfg.maxWait)
+	}
+
+	bs, err := p.enterBulkheads(ctx, deadline)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := p.get(ctx, deadline, priority)
+	if err != nil {
+		leaveBulkheads(bs)
+		return nil, err
+	}
+	p.observeAcquire(now().Sub(start), cfg)
+
+	p.Lock()
+	c.conn.bulkheads = bs
+	p.Unlock()
+	return c, nil
+}
+
+func (p *pool) get(ctx context.Context, deadline time.Time, priority Priority) (*client, error) {
+	exclude := excludedInstance(ctx)
+
+	var (
+		w   *waiter
+		err error
+	)
+	defer func() {
+		if w != nil {
+			p.Lock()
+			p.dequeue(w)
+			p.Unlock()
+		}
+	}()
+
 	p.Lock()
 
+	// queue behind the waiters which arrived earlier with the same or a higher priority
+	if p.conf().isBlocked && p.waitersAhead(priority) {
+		if w, err = p.wait(ctx, deadline, priority, w); err != nil {
+			p.Unlock()
+			return nil, err
+		}
+	}
+
 	for {
 		if p.IsClosed() {
 			p.Unlock()
 			return nil, ErrPoolClosed
 		}
 
-		for i, n := 0, p.idleNodes.Len(); i < n; i++ {
-			e := p.idleNodes.Front()
+		cfg := p.conf()
+		admitted := p.admit(priority, cfg)
+
+		for i, n := 0, p.idleNodes.Len(); admitted && i < n; i++ {
+			e := p.frontIdleNode(exclude)
 			if e == nil {
 				break
 			}
 
 			p.idleNodes.Remove(e)
-			checker := p.healthChecker
 			p.Unlock()
 
 			in := e.Value.(*idleNode)
-			if checker == nil || checker(in.hc, in.t) == nil {
-				return &client{p: p, hc: in.hc}, nil
+			if in.cn.expired(now()) {
+				in.cn.close()
+				p.Lock()
+				p.release()
+				continue
+			}
+			if cfg.healthChecker == nil || cfg.backgroundValidation {
+				return p.newClient(in.cn, cfg), nil
+			}
+			err := cfg.healthChecker(in.cn.hc, in.t)
+			if err == nil {
+				return p.newClient(in.cn, cfg), nil
 			}
 
-			in.hc.Transport.Close()
+			cfg.logger.Warn("[gohbase] health check failed", "instance", in.cn.addr(), "error", err)
+
+			in.cn.close()
 			p.Lock()
 			p.release()
 		}
 
-		if p.maxActive == 0 || p.active < p.maxActive {
+		if admitted && (cfg.maxActive == 0 || p.active < cfg.maxActive) {
 			p.active += 1
 			picker := p.picker
 			p.Unlock()
 
-			hc, err := p.getHbaseClient(picker)
+			cn, err := p.dial(picker, cfg, exclude)
 			if err != nil {
 				p.Lock()
 				p.release()
@@ -121,15 +203,19 @@ func (p *pool) Get() (Client, error) {
 				return nil, err
 			}
 
-			return &client{p: p, hc: hc}, nil
+			return p.newClient(cn, cfg), nil
 		}
 
-		if !p.isBlocked {
+		if !cfg.isBlocked {
 			p.Unlock()
+			cfg.logger.Warn("[gohbase] pool is full", "max_active", cfg.maxActive)
 			return nil, ErrPoolFull
 		}
 
-		p.wait()
+		if w, err = p.wait(ctx, deadline, priority, w); err != nil {
+			p.Unlock()
+			return nil, err
+		}
 	}
 }
 
@@ -140,6 +226,10 @@ func (p *pool) Close() error {
 
 	close(p.closeChan)
 	p.cleanUpIdleNodes(true)
+
+	p.Lock()
+	p.checkDrained()
+	p.Unlock()
 	return nil
 }
 
@@ -147,128 +237,247 @@ func (p *pool) IsClosed() bool {
 	return atomic.LoadInt32(&p.closed) == 1
 }
 
-func (p *pool) getHbaseClient(picker balancer.Picker) (*hbase.HbaseClient, error) {
+// closing returns a channel closed once the pool is closed.
+func (p *pool) closing() <-chan struct{} {
+	return p.closeChan
+}
+
+func (p *pool) conf() *config {
+	return p.cfg.Load().(*config)
+}
+
+func (p *pool) newClient(cn *conn, cfg *config) *client {
+	c := &client{
+		p:           p,
+		conn:        cn,
+		interceptor: cfg.interceptor,
+		tracer:      cfg.tracer,
+		propagate:   cfg.propagateTrace,
+		rateLimiter: cfg.rateLimiter,
+	}
+	p.Lock()
+	p.borrowed[cn] = struct{}{}
+	p.Unlock()
+
+	p.track(c, cfg)
+	return c
+}
+
+// frontIdleNode returns the most recently used idle node which is not
+// connected to the exclude instance. It must be called with p locked.
+func (p *pool) frontIdleNode(exclude string) *list.Element {
+	for e := p.idleNodes.Front(); e != nil; e = e.Next() {
+		if exclude == "" || e.Value.(*idleNode).cn.addr() != exclude {
+			return e
+		}
+	}
+	return nil
+}
+
+// dial connects to an instance picked by picker, avoiding the exclude instance
+// unless it is the only one the picker returns.
+func (p *pool) dial(picker balancer.Picker, cfg *config, exclude string) (*conn, error) {
 	ins, err := picker.Pick()
 	if err != nil {
+		cfg.logger.Error("[gohbase] pick instance failed", "balancer", cfg.balancer.Name(), "error", err)
 		return nil, err
 	}
+	if exclude != "" {
+		p.Lock()
+		n := len(p.instances)
+		p.Unlock()
 
-	socket, err := thrift.NewTSocketTimeout(ins.GetAddr(), p.socketTimeout)
+		for i := 1; i < n && ins.GetAddr() == exclude; i++ {
+			if ins, err = picker.Pick(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return p.dialInstance(ins, cfg)
+}
+
+func (p *pool) dialInstance(ins instance.Instance, cfg *config) (cn *conn, err error) {
+	span := p.startSpan(spanPoolDial)
+	span.SetAttribute(AttrInstance, ins.GetAddr())
+	defer func() {
+		endSpan(span, err)
+	}()
+
+	socket, err := thrift.NewTSocketTimeout(ins.GetAddr(), cfg.socketTimeout)
 	if err != nil {
+		cfg.logger.Warn("[gohbase] dial failed", "instance", ins.GetAddr(), "error", err)
 		return nil, err
 	}
 
-	transport, err := p.transportFactory.GetTransport(socket)
+	transport, err := cfg.transportFactory.GetTransport(socket)
 	if err != nil {
+		cfg.logger.Warn("[gohbase] dial failed", "instance", ins.GetAddr(), "error", err)
 		return nil, err
 	}
+	ct := newCountingTransport(transport, socket)
 
-	if err = transport.Open(); err != nil {
+	if err = ct.Open(); err != nil {
+		cfg.logger.Warn("[gohbase] dial failed", "instance", ins.GetAddr(), "error", err)
 		return nil, err
 	}
 
-	return hbase.NewHbaseClientFactory(transport, p.protocolFactory), nil
+	cn = &conn{
+		hc:  hbase.NewHbaseClientFactory(ct, cfg.protocolFactory),
+		ins: ins,
+		ct:  ct,
+	}
+	if cfg.maxLifetime > 0 {
+		cn.expiresAt = now().Add(cfg.maxLifetime - jitter(cfg.lifetimeJitter))
+	}
+	return cn, nil
 }
 
-func (p *pool) asyncUpdatePicker() {
-	p.updatePicker()
-
-	if p.updatePickerInterval == 0 {
-		return
+// startBackground starts the background goroutines of the pool, and stops
+// the ones started before. It must be called with p locked.
+func (p *pool) startBackground() {
+	if p.stopChan != nil {
+		close(p.stopChan)
 	}
+	p.stopChan = make(chan struct{})
 
+	cfg := p.conf()
+	p.asyncUpdatePicker(cfg, p.stopChan)
+	p.asyncCleanUp(cfg, p.stopChan)
+	p.asyncDetectLeaks(cfg, p.stopChan)
+}
+
+// runEvery runs f every interval in a background goroutine, until the pool
+// is closed or stop is closed.
+func (p *pool) runEvery(interval time.Duration, stop <-chan struct{}, f func()) {
+	p.wg.Add(1)
 	go func() {
-		ticker := time.NewTicker(p.updatePickerInterval)
+		defer p.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
 		for {
 			select {
 			case <-ticker.C:
-				p.updatePicker()
+				f()
+			case <-stop:
+				return
 			case <-p.closeChan:
-				ticker.Stop()
+				return
 			}
 		}
 	}()
 }
 
+func (p *pool) asyncUpdatePicker(cfg *config, stop <-chan struct{}) {
+	if cfg.updatePickerInterval == 0 {
+		return
+	}
+
+	p.runEvery(cfg.updatePickerInterval, stop, func() {
+		p.updatePicker()
+	})
+}
+
 func (p *pool) updatePicker() error {
-	instances, err := p.discovery.Discover()
+	cfg := p.conf()
+
+	instances, err := cfg.discovery.Discover()
 	if err != nil {
+		cfg.logger.Warn("[gohbase] discover instances failed", "error", err)
 		return err
 	}
+	if len(instances) == 0 {
+		cfg.logger.Warn("[gohbase] no instance discovered")
+	}
 
 	p.Lock()
-	p.picker = p.balancer.NewPicker(instances)
+	p.instances = instances
+	p.picker = p.conf().balancer.NewPicker(instances)
 	p.Unlock()
 	return nil
 }
 
-func (p *pool) asyncCleanUp() {
-	if p.cleanUpInterval == 0 {
+func (p *pool) asyncCleanUp(cfg *config, stop <-chan struct{}) {
+	interval := cfg.cleanUpInterval
+	if interval == 0 && (cfg.minIdle > 0 || cfg.maxLifetime > 0 || cfg.backgroundValidation) {
+		interval = defaultCleanUpInterval
+	}
+	if interval == 0 {
 		return
 	}
 
-	go func() {
-		ticker := time.NewTicker(p.cleanUpInterval)
-		for {
-			select {
-			case <-ticker.C:
-				p.cleanUpIdleNodes(false)
-			case <-p.closeChan:
-				ticker.Stop()
-			}
-		}
-	}()
+	p.runEvery(interval, stop, func() {
+		p.cleanUpIdleNodes(false)
+		p.validateIdleNodes()
+		p.ensureMinIdle()
+	})
 }
 
 func (p *pool) cleanUpIdleNodes(force bool) {
 	p.Lock()
-	defer p.Unlock()
 
-	if !force && p.idleTimeout == 0 {
+	cfg := p.conf()
+	if !force && cfg.idleTimeout == 0 && cfg.maxLifetime == 0 {
+		p.Unlock()
 		return
 	}
 
-	for i, n := 0, p.idleNodes.Len(); i < n; i++ {
-		e := p.idleNodes.Back()
-		if e == nil {
-			return
-		}
+	t := now()
+	var cns []*conn
+	for e := p.idleNodes.Back(); e != nil; {
+		prev := e.Prev()
 
 		in := e.Value.(*idleNode)
-		if !force && in.t.Add(p.idleTimeout).After(now()) {
-			return
+		if force || in.cn.expired(t) ||
+			(cfg.idleTimeout > 0 && !in.t.Add(cfg.idleTimeout).After(t) && p.idleNodes.Len() > cfg.minIdle) {
+			p.idleNodes.Remove(e)
+			p.release()
+			cns = append(cns, in.cn)
 		}
 
-		p.idleNodes.Remove(e)
-		p.release()
-		p.Unlock()
+		e = prev
+	}
+	p.Unlock()
 
-		in.hc.Transport.Close()
-		p.Lock()
+	for _, cn := range cns {
+		cn.close()
 	}
 }
 
-func (p *pool) put(hc *hbase.HbaseClient, forceClose bool) error {
+func (p *pool) put(cn *conn, forceClose bool) error {
+	p.Lock()
+	delete(p.borrowed, cn)
+	leaveBulkheads(cn.bulkheads)
+	cn.bulkheads = nil
+
 	if p.IsClosed() {
-		_ = hc.Transport.Close()
+		p.release()
+		p.Unlock()
+		_ = cn.close()
 		return ErrPoolClosed
 	}
 
-	p.Lock()
+	cfg := p.conf()
+	if cfg.maxActive > 0 && p.active > cfg.maxActive {
+		// the pool has been shrunk
+		forceClose = true
+	}
 
-	if !forceClose {
-		p.idleNodes.PushFront(&idleNode{hc: hc, t: now()})
-		if p.maxIdle > 0 && p.idleNodes.Len() > p.maxIdle {
-			hc = p.idleNodes.Remove(p.idleNodes.Back()).(*idleNode).hc
+	if !forceClose && !cn.expired(now()) {
+		p.idleNodes.PushFront(&idleNode{cn: cn, t: now()})
+		if cfg.maxIdle > 0 && p.idleNodes.Len() > cfg.maxIdle {
+			cn = p.idleNodes.Remove(p.idleNodes.Back()).(*idleNode).cn
 		} else {
-			hc = nil
+			cn = nil
 		}
 	}
 
-	if hc != nil {
+	if cn != nil {
 		p.release()
 		p.Unlock()
-		return hc.Transport.Close()
+		return cn.close()
 	}
 
 	p.notify()
@@ -279,17 +488,5 @@ func (p *pool) put(hc *hbase.HbaseClient, forceClose bool) error {
 func (p *pool) release() {
 	p.active -= 1
 	p.notify()
-}
-
-func (p *pool) notify() {
-	if p.cond != nil {
-		p.cond.Signal()
-	}
-}
-
-func (p *pool) wait() {
-	if p.cond == nil {
-		p.cond = sync.NewCond(&p.Mutex)
-	}
-	p.cond.Wait()
+	p.checkDrained()
 }
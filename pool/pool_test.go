@@ -1,6 +1,7 @@
 package pool
 
 import (
+	"context"
 	"sync"
 	"testing"
 	"time"
@@ -54,6 +55,75 @@ func TestPool_Close(t *testing.T) {
 	}
 }
 
+func TestPool_Shutdown(t *testing.T) {
+	p := NewPool(WithMaxActive(1), WithBlockMode(true)).(*pool)
+	p.active = 1
+	c := p.newClient(newMemoryConn(), p.conf())
+
+	waitErr := make(chan error, 1)
+	go func() {
+		_, err := p.Get()
+		waitErr <- err
+	}()
+	for p.Stats().Waiting == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- p.Shutdown(context.Background())
+	}()
+
+	if err := <-waitErr; err != ErrPoolClosed {
+		t.Errorf("expected waiter error %v, got %v", ErrPoolClosed, err)
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("shutdown before client is closed - %v", err)
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	c.Close()
+	if err := <-done; err != nil {
+		t.Errorf("shutdown error - %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if err := p.Shutdown(ctx); err != nil {
+		t.Errorf("shutdown drained pool error - %v", err)
+	}
+}
+
+func TestPool_Reconfigure(t *testing.T) {
+	p := NewPool(WithMaxActive(1), WithMaxIdle(2), WithBlockMode(true)).(*pool)
+	defer p.Close()
+
+	if err := p.Reconfigure(WithMaxIdle(1), WithMaxActive(-1)); err == nil {
+		t.Errorf("expected error for invalid option")
+	}
+	if cfg := p.conf(); cfg.maxIdle != 2 || cfg.maxActive != 1 {
+		t.Errorf("invalid options must not be applied - %+v", cfg)
+	}
+
+	p.active = 1
+	waitErr := make(chan error, 1)
+	go func() {
+		_, err := p.Get()
+		waitErr <- err
+	}()
+	for p.Stats().Waiting == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := p.Reconfigure(WithBlockMode(false)); err != nil {
+		t.Fatalf("Reconfigure error - %v", err)
+	}
+	if err := <-waitErr; err != ErrPoolFull {
+		t.Errorf("expected waiter error %v, got %v", ErrPoolFull, err)
+	}
+}
+
 func TestPool_IsClosed(t *testing.T) {
 	p := newPool()
 	p.Close()
@@ -76,3 +146,22 @@ func newPool() Pool {
 	}
 	return NewPool(opts...)
 }
+
+func TestOptions_InvalidValue(t *testing.T) {
+	for _, c := range []struct {
+		opt      Option
+		expected string
+	}{
+		{WithLoadShedding(time.Second, 2), "[gohbase] invalid load shedding error rate: 2"},
+		{WithLoadShedding(-time.Second, 0.5), "[gohbase] invalid load shedding wait: -1s"},
+		{WithMaxLifetime(time.Minute, time.Minute), "[gohbase] invalid max lifetime jitter: 1m0s"},
+		{WithMaxLifetime(0, time.Second), "[gohbase] invalid max lifetime jitter: 1s"},
+	} {
+		if err := c.opt(&config{}); err == nil || err.Error() != c.expected {
+			t.Errorf("expected error %q, got %v", c.expected, err)
+		}
+	}
+	if err := WithMaxLifetime(time.Minute, time.Second)(&config{}); err != nil {
+		t.Errorf("valid max lifetime error - %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrLoadShed = errors.New("[gohbase] request is shed")
+)
+
+// Priority is the priority of a request for a connection. Waiters are served
+// in the order of priority, and in FIFO order among the same priority.
+type Priority int
+
+const (
+	PriorityLow Priority = iota - 1
+	PriorityNormal
+	PriorityHigh
+)
+
+const (
+	// shedAlpha is the smoothing factor of the moving averages used by load shedding.
+	shedAlpha = 0.1
+	// shedDecay is the period over which the moving averages decay by
+	// shedAlpha, so that shedding stops while no request is observed.
+	shedDecay = time.Second
+)
+
+type priorityKey struct{}
+
+// ContextWithPriority sets the priority of the clients borrowed with ctx,
+// PriorityNormal by default.
+func ContextWithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+func priorityOf(ctx context.Context) Priority {
+	if priority, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return priority
+	}
+	return PriorityNormal
+}
+
+// admit reports whether a request of priority may borrow a connection, given
+// the connections reserved for high priority. Connections being dialed or
+// checked count as borrowed. It must be called with p locked.
+func (p *pool) admit(priority Priority, cfg *config) bool {
+	if priority >= PriorityHigh || cfg.reserved == 0 || cfg.maxActive == 0 {
+		return true
+	}
+	return p.active-p.idleNodes.Len() < cfg.maxActive-cfg.reserved
+}
+
+// waitersAhead reports whether there are waiters which go before a request
+// of priority. It must be called with p locked.
+func (p *pool) waitersAhead(priority Priority) bool {
+	e := p.waiters.Front()
+	return e != nil && e.Value.(*waiter).priority >= priority
+}
+
+// pushWaiter queues w behind the waiters of the same or a higher priority.
+// It must be called with p locked.
+func (p *pool) pushWaiter(w *waiter) {
+	for e := p.waiters.Back(); e != nil; e = e.Prev() {
+		if e.Value.(*waiter).priority >= w.priority {
+			w.e = p.waiters.InsertAfter(w, e)
+			return
+		}
+	}
+	w.e = p.waiters.PushFront(w)
+}
+
+// shedder tracks the moving averages of the time spent acquiring connections
+// and of the rate of failed calls, to shed low priority requests when the
+// pool or the cluster is overloaded.
+type shedder struct {
+	sync.Mutex
+
+	wait    float64
+	errRate float64
+	last    time.Time
+}
+
+// decay decays the moving averages for the time elapsed since the last
+// decay. It must be called with s locked.
+func (s *shedder) decay(t time.Time) {
+	if !s.last.IsZero() && t.After(s.last) {
+		f := math.Pow(1-shedAlpha, float64(t.Sub(s.last))/float64(shedDecay))
+		s.wait *= f
+		s.errRate *= f
+	}
+	s.last = t
+}
+
+// shed rejects a request of priority early if the pool is overloaded.
+func (p *pool) shed(priority Priority, cfg *config) error {
+	if priority > PriorityLow || (cfg.shedWait == 0 && cfg.shedErrorRate == 0) {
+		return nil
+	}
+
+	p.shedder.Lock()
+	p.shedder.decay(now())
+	wait, errRate := p.shedder.wait, p.shedder.errRate
+	p.shedder.Unlock()
+
+	if (cfg.shedWait > 0 && time.Duration(wait) > cfg.shedWait) ||
+		(cfg.shedErrorRate > 0 && errRate > cfg.shedErrorRate) {
+		atomic.AddInt64(&p.counters.shed, 1)
+		cfg.logger.Debug("[gohbase] request is shed", "wait", time.Duration(wait), "error_rate", errRate)
+		return ErrLoadShed
+	}
+	return nil
+}
+
+func (p *pool) observeAcquire(d time.Duration, cfg *config) {
+	if cfg.shedWait == 0 {
+		return
+	}
+
+	p.shedder.Lock()
+	p.shedder.decay(now())
+	p.shedder.wait += shedAlpha * (float64(d) - p.shedder.wait)
+	p.shedder.Unlock()
+}
+
+func (p *pool) observeCall(err error, cfg *config) {
+	if cfg.shedErrorRate == 0 {
+		return
+	}
+
+	var failed float64
+	if IsRetryable(err) {
+		failed = 1
+	}
+	p.shedder.Lock()
+	p.shedder.decay(now())
+	p.shedder.errRate += shedAlpha * (failed - p.shedder.errRate)
+	p.shedder.Unlock()
+}
@@ -0,0 +1,125 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPool_Priority(t *testing.T) {
+	p := NewPool(WithMaxActive(2), WithBlockMode(true), WithHighPriorityReserve(1)).(*pool)
+	defer p.Close()
+
+	for i := 0; i < 2; i++ {
+		p.active++
+		p.idleNodes.PushFront(&idleNode{cn: newMemoryConn(), t: now()})
+	}
+
+	low := ContextWithPriority(context.Background(), PriorityLow)
+	high := ContextWithPriority(context.Background(), PriorityHigh)
+
+	c, err := p.GetContext(low)
+	if err != nil {
+		t.Fatalf("GetContext error - %v", err)
+	}
+
+	// the second connection is reserved for high priority
+	order := make(chan Priority, 2)
+	for _, ctx := range []context.Context{low, high} {
+		ctx := ctx
+		go func() {
+			c, err := p.GetContext(ctx)
+			if err != nil {
+				t.Errorf("GetContext error - %v", err)
+				return
+			}
+			order <- priorityOf(ctx)
+			if priorityOf(ctx) == PriorityHigh {
+				time.Sleep(time.Millisecond * 10)
+			}
+			c.Close()
+		}()
+		for p.Stats().Waiting == 0 && ctx == low {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	if first := <-order; first != PriorityHigh {
+		t.Errorf("expected high priority to be served first, got %v", first)
+	}
+	c.Close()
+	if second := <-order; second != PriorityLow {
+		t.Errorf("expected low priority to be served next, got %v", second)
+	}
+}
+
+func TestPool_LoadShedding(t *testing.T) {
+	p := NewPool(WithLoadShedding(0, 0.5)).(*pool)
+	defer p.Close()
+
+	cfg := p.conf()
+	for i := 0; i < 20; i++ {
+		p.observeCall(ErrPoolFull, cfg)
+	}
+
+	if _, err := p.GetContext(ContextWithPriority(context.Background(), PriorityLow)); err != ErrLoadShed {
+		t.Errorf("expected error %v, got %v", ErrLoadShed, err)
+	}
+	if err := p.shed(PriorityNormal, cfg); err != nil {
+		t.Errorf("normal priority must not be shed - %v", err)
+	}
+	if s := p.Stats(); s.Shed != 1 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+func TestPool_HighPriorityReserveDialing(t *testing.T) {
+	p := NewPool(WithMaxActive(2), WithHighPriorityReserve(1)).(*pool)
+	defer p.Close()
+
+	// a connection being dialed counts against the capacity of low priority
+	p.active = 1
+	if p.admit(PriorityNormal, p.conf()) {
+		t.Errorf("normal priority is admitted while a dial takes the last unreserved connection")
+	}
+	if !p.admit(PriorityHigh, p.conf()) {
+		t.Errorf("high priority must be admitted to the reserve")
+	}
+
+	if err := p.Reconfigure(WithHighPriorityReserve(2)); err == nil {
+		t.Errorf("expected error of a reserve of the whole pool")
+	}
+	if err := p.Reconfigure(WithMaxActive(1)); err == nil {
+		t.Errorf("expected error of a max active within the reserve")
+	}
+	if err := p.Reconfigure(WithMaxActive(0), WithHighPriorityReserve(2)); err != nil {
+		t.Errorf("Reconfigure error - %v", err)
+	}
+}
+
+func TestPool_LoadSheddingDecay(t *testing.T) {
+	defer func() {
+		now = time.Now
+	}()
+	t0 := time.Now()
+	now = func() time.Time {
+		return t0
+	}
+
+	p := NewPool(WithLoadShedding(time.Millisecond, 0)).(*pool)
+	defer p.Close()
+
+	cfg := p.conf()
+	for i := 0; i < 20; i++ {
+		p.observeAcquire(time.Millisecond*10, cfg)
+	}
+	if err := p.shed(PriorityLow, cfg); err != ErrLoadShed {
+		t.Fatalf("expected error %v, got %v", ErrLoadShed, err)
+	}
+
+	// shed requests are not observed, so the average decays with time alone
+	t0 = t0.Add(shedDecay * 30)
+	if err := p.shed(PriorityLow, cfg); err != nil {
+		t.Errorf("requests are still shed after the pool is quiet - %v", err)
+	}
+}
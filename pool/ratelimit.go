@@ -0,0 +1,219 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrRateLimited = errors.New("[gohbase] rate limited")
+)
+
+// OpClass is a class of Client methods rate limited together.
+type OpClass int
+
+const (
+	OpRead OpClass = iota
+	OpWrite
+	OpScan
+	OpAdmin
+)
+
+func (c OpClass) String() string {
+	switch c {
+	case OpRead:
+		return "read"
+	case OpWrite:
+		return "write"
+	case OpScan:
+		return "scan"
+	case OpAdmin:
+		return "admin"
+	}
+	return "unknown"
+}
+
+// ClassOf returns the class of a Client method.
+func ClassOf(method string) OpClass {
+	switch method {
+	case "Get", "GetVer", "GetRow", "GetRowWithColumns", "GetRows", "GetRowsWithColumns":
+		return OpRead
+	case "MutateRow", "MutateRows", "MutateRowsTs", "DeleteAll", "DeleteAllRow", "CheckAndPut",
+		"AtomicIncrement", "Increment", "IncrementRows":
+		return OpWrite
+	case "ScannerOpenWithScan", "ScannerOpen", "ScannerOpenWithStop", "ScannerOpenWithPrefix",
+		"ScannerGet", "ScannerGetList", "ScannerClose":
+		return OpScan
+	}
+	return OpAdmin
+}
+
+// RateLimiter limits the rate of calls with token buckets per table and
+// operation class, installed in a pool with WithRateLimiter.
+//
+// Every Client call takes a token before it is made, from the bucket of the
+// table of the call and the class of its method if there is one, or else from
+// the bucket of the class shared by every table. So every call made in
+// Executor.Do, and every retry of an Executor call, takes a token of its own.
+type RateLimiter interface {
+	// Wait takes a token for a call of class on table, waiting for it up to
+	// the max wait of the limiter or until ctx is done.
+	Wait(ctx context.Context, table string, class OpClass) error
+	Stats() RateLimitStats
+}
+
+// RateLimitStats contains statistics of a RateLimiter.
+type RateLimitStats struct {
+	// Allowed is the total number of calls allowed at once.
+	Allowed int64
+	// Delayed is the total number of calls which waited for a token.
+	Delayed int64
+	// Rejected is the total number of calls rejected with ErrRateLimited.
+	Rejected int64
+}
+
+type rateLimiter struct {
+	buckets map[rateKey]*tokenBucket
+	maxWait time.Duration
+
+	allowed  int64
+	delayed  int64
+	rejected int64
+}
+
+var _ RateLimiter = (*rateLimiter)(nil)
+
+type rateKey struct {
+	table string
+	class OpClass
+}
+
+type RateLimitOption func(*rateLimiter)
+
+// WithRate limits the calls of class on table to rate per second, with bursts
+// of up to burst calls. An empty table sets the limit shared by every table
+// without a limit of its own.
+func WithRate(table string, class OpClass, rate float64, burst int) RateLimitOption {
+	return func(l *rateLimiter) {
+		if rate > 0 && burst > 0 {
+			l.buckets[rateKey{table: table, class: class}] = newTokenBucket(rate, burst)
+		}
+	}
+}
+
+// WithRateWait makes limited calls wait up to maxWait for a token rather than
+// failing with ErrRateLimited at once.
+func WithRateWait(maxWait time.Duration) RateLimitOption {
+	return func(l *rateLimiter) {
+		if maxWait >= 0 {
+			l.maxWait = maxWait
+		}
+	}
+}
+
+func NewRateLimiter(opts ...RateLimitOption) RateLimiter {
+	l := &rateLimiter{
+		buckets: make(map[rateKey]*tokenBucket),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+func (l *rateLimiter) Stats() RateLimitStats {
+	return RateLimitStats{
+		Allowed:  atomic.LoadInt64(&l.allowed),
+		Delayed:  atomic.LoadInt64(&l.delayed),
+		Rejected: atomic.LoadInt64(&l.rejected),
+	}
+}
+
+func (l *rateLimiter) Wait(ctx context.Context, table string, class OpClass) error {
+	b, ok := l.buckets[rateKey{table: table, class: class}]
+	if !ok {
+		b, ok = l.buckets[rateKey{class: class}]
+	}
+	if !ok {
+		return nil
+	}
+
+	d, ok := b.reserve(l.maxWait)
+	switch {
+	case !ok:
+		atomic.AddInt64(&l.rejected, 1)
+		return ErrRateLimited
+	case d == 0:
+		atomic.AddInt64(&l.allowed, 1)
+		return nil
+	}
+
+	atomic.AddInt64(&l.delayed, 1)
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		b.refund()
+		return ctx.Err()
+	}
+}
+
+type tokenBucket struct {
+	sync.Mutex
+
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   now(),
+	}
+}
+
+// reserve takes a token, and returns how long to wait until the token is
+// available. It takes nothing and reports false if that is longer than maxWait.
+func (b *tokenBucket) reserve(maxWait time.Duration) (time.Duration, bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	t := now()
+	b.tokens += t.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = t
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+
+	d := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	if d > maxWait {
+		return 0, false
+	}
+	b.tokens--
+	return d, true
+}
+
+// refund returns a token taken by reserve which is not used.
+func (b *tokenBucket) refund() {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.tokens++; b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
@@ -0,0 +1,78 @@
+package pool
+
+// Reconfigure applies opts to a live pool. If any option is invalid, none of
+// them is applied and the errors are returned.
+//
+// Surplus idle connections are closed at once, and surplus borrowed ones are
+// closed when they are returned. Waiters are woken up if the capacity grows.
+// The picker is rebuilt, so that a new balancer or discovery takes effect,
+// and the background goroutines are restarted with the new intervals.
+// Transport settings such as the socket timeout only apply to new connections.
+func (p *pool) Reconfigure(opts ...Option) error {
+	if p.IsClosed() {
+		return ErrPoolClosed
+	}
+
+	p.Lock()
+
+	old := p.conf()
+	cfg := old.clone()
+	if err := cfg.apply(opts...); err != nil {
+		p.Unlock()
+		return err
+	}
+	p.cfg.Store(cfg)
+
+	// close surplus idle connections
+	var cns []*conn
+	for e := p.idleNodes.Back(); e != nil; e = p.idleNodes.Back() {
+		if (cfg.maxIdle == 0 || p.idleNodes.Len() <= cfg.maxIdle) &&
+			(cfg.maxActive == 0 || p.active <= cfg.maxActive) {
+			break
+		}
+
+		p.idleNodes.Remove(e)
+		p.release()
+		cns = append(cns, e.Value.(*idleNode).cn)
+	}
+
+	// wake up waiters for the grown capacity, each passes it on to the next
+	if p.capacity() != 0 {
+		p.notify()
+	}
+
+	if cfg.updatePickerInterval != old.updatePickerInterval ||
+		cfg.cleanUpInterval != old.cleanUpInterval ||
+		cfg.leakThreshold != old.leakThreshold ||
+		cfg.minIdle != old.minIdle ||
+		cfg.maxLifetime != old.maxLifetime ||
+		cfg.backgroundValidation != old.backgroundValidation {
+		p.startBackground()
+	}
+	p.Unlock()
+
+	for _, cn := range cns {
+		cn.close()
+	}
+
+	p.updatePicker()
+	if cfg.minIdle > old.minIdle {
+		p.warmUp()
+	}
+	return nil
+}
+
+// capacity returns the number of clients which may be got without waiting,
+// or -1 if it is unlimited. It must be called with p locked.
+func (p *pool) capacity() int {
+	cfg := p.conf()
+	if cfg.maxActive == 0 || !cfg.isBlocked {
+		return -1
+	}
+
+	n := p.idleNodes.Len()
+	if p.active < cfg.maxActive {
+		n += cfg.maxActive - p.active
+	}
+	return n
+}
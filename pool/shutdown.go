@@ -0,0 +1,47 @@
+package pool
+
+import (
+	"context"
+)
+
+// Shutdown gracefully shuts down the pool. It closes the pool at once, so that
+// Get fails and blocked waiters are woken up with ErrPoolClosed, then waits
+// for all the borrowed clients to be closed. If ctx is done before that,
+// the connections of the borrowed clients are closed by force, and the error
+// of ctx is returned. Shutdown returns after all the background goroutines
+// of the pool are terminated.
+func (p *pool) Shutdown(ctx context.Context) (err error) {
+	p.Close()
+
+	select {
+	case <-p.drainChan:
+	case <-ctx.Done():
+		err = ctx.Err()
+
+		p.Lock()
+		cns := make([]*conn, 0, len(p.borrowed))
+		for cn := range p.borrowed {
+			cns = append(cns, cn)
+		}
+		p.Unlock()
+
+		for _, cn := range cns {
+			cn.close()
+		}
+		p.conf().logger.Warn("[gohbase] shutdown before all clients are closed", "borrowed", len(cns), "error", err)
+	}
+
+	p.wg.Wait()
+	return
+}
+
+// checkDrained closes drainChan once the pool is closed and all the
+// connections are released. It must be called with p locked.
+func (p *pool) checkDrained() {
+	if p.drained || !p.IsClosed() || p.active > 0 {
+		return
+	}
+
+	p.drained = true
+	close(p.drainChan)
+}
@@ -0,0 +1,70 @@
+package pool
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats contains statistics of a pool.
+type Stats struct {
+	// Active is the number of open connections, both borrowed and idle.
+	Active int
+	// Idle is the number of idle connections.
+	Idle int
+	// Borrowed is the number of clients which are not closed yet.
+	Borrowed int
+
+	// Leaked is the total number of clients reported as leaked.
+	Leaked int64
+	// Reclaimed is the total number of leaked clients reclaimed by force.
+	Reclaimed int64
+	// Finalized is the total number of clients garbage collected without being closed.
+	Finalized int64
+
+	// Waiting is the number of goroutines waiting for a connection.
+	Waiting int
+	// WaitCount is the total number of waits for a connection.
+	WaitCount int64
+	// WaitDuration is the total time spent waiting for a connection.
+	WaitDuration time.Duration
+	// WaitTimeouts is the total number of waits which exceeded the max wait duration.
+	WaitTimeouts int64
+	// WaitRejected is the total number of waits rejected due to too many waiters.
+	WaitRejected int64
+	// Shed is the total number of low priority requests shed.
+	Shed int64
+}
+
+type counters struct {
+	leaked    int64
+	reclaimed int64
+	finalized int64
+
+	waitCount    int64
+	waitDuration int64
+	waitTimeouts int64
+	waitRejected int64
+
+	shed int64
+}
+
+func (p *pool) Stats() Stats {
+	p.Lock()
+	s := Stats{
+		Active:  p.active,
+		Idle:    p.idleNodes.Len(),
+		Waiting: p.waiters.Len(),
+	}
+	p.Unlock()
+
+	s.Borrowed = s.Active - s.Idle
+	s.Leaked = atomic.LoadInt64(&p.counters.leaked)
+	s.Reclaimed = atomic.LoadInt64(&p.counters.reclaimed)
+	s.Finalized = atomic.LoadInt64(&p.counters.finalized)
+	s.WaitCount = atomic.LoadInt64(&p.counters.waitCount)
+	s.WaitDuration = time.Duration(atomic.LoadInt64(&p.counters.waitDuration))
+	s.WaitTimeouts = atomic.LoadInt64(&p.counters.waitTimeouts)
+	s.WaitRejected = atomic.LoadInt64(&p.counters.waitRejected)
+	s.Shed = atomic.LoadInt64(&p.counters.shed)
+	return s
+}
@@ -0,0 +1,67 @@
+package pool
+
+// Tracer creates spans for client calls, pool waits and connection dials.
+// It is kept minimal so that it can be adapted to OpenTelemetry or any other
+// tracing library.
+type Tracer interface {
+	StartSpan(name string) Span
+}
+
+// Span is a single traced operation started by a Tracer.
+type Span interface {
+	SetAttribute(key string, value interface{})
+	SetError(err error)
+	// Inject writes the span context into carrier, which is sent to hbase
+	// as the attributes of the call.
+	Inject(carrier map[string]string)
+	End()
+}
+
+const (
+	spanCallPrefix = "hbase."
+	spanPoolWait   = "gohbase.pool.wait"
+	spanPoolDial   = "gohbase.pool.dial"
+)
+
+const (
+	AttrMethod        = "hbase.method"
+	AttrTable         = "hbase.table"
+	AttrRowCount      = "hbase.row_count"
+	AttrInstance      = "hbase.instance"
+	AttrBytesSent     = "hbase.bytes_sent"
+	AttrBytesReceived = "hbase.bytes_received"
+	AttrErrorClass    = "hbase.error_class"
+)
+
+type noopSpan struct {
+}
+
+var _ Span = noopSpan{}
+
+func (noopSpan) SetAttribute(string, interface{}) {
+}
+
+func (noopSpan) SetError(error) {
+}
+
+func (noopSpan) Inject(map[string]string) {
+}
+
+func (noopSpan) End() {
+}
+
+func (p *pool) startSpan(name string) Span {
+	tracer := p.conf().tracer
+	if tracer == nil {
+		return noopSpan{}
+	}
+	return tracer.StartSpan(name)
+}
+
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.SetAttribute(AttrErrorClass, ErrorClass(err))
+		span.SetError(err)
+	}
+	span.End()
+}
@@ -0,0 +1,177 @@
+package pool
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/popeyeio/gohbase/instance"
+)
+
+const traceHeader = "traceparent"
+
+// recordingTracer records the spans it starts.
+type recordingTracer struct {
+	sync.Mutex
+	spans []*recordingSpan
+}
+
+var _ Tracer = (*recordingTracer)(nil)
+
+func (t *recordingTracer) StartSpan(name string) Span {
+	s := &recordingSpan{name: name, attrs: make(map[string]interface{})}
+	t.Lock()
+	t.spans = append(t.spans, s)
+	t.Unlock()
+	return s
+}
+
+func (t *recordingTracer) span(name string) *recordingSpan {
+	t.Lock()
+	defer t.Unlock()
+	for _, s := range t.spans {
+		if s.name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+type recordingSpan struct {
+	name  string
+	attrs map[string]interface{}
+	err   error
+	ended bool
+}
+
+var _ Span = (*recordingSpan)(nil)
+
+func (s *recordingSpan) SetAttribute(key string, value interface{}) {
+	s.attrs[key] = value
+}
+
+func (s *recordingSpan) SetError(err error) {
+	s.err = err
+}
+
+func (s *recordingSpan) Inject(carrier map[string]string) {
+	carrier[traceHeader] = s.name
+}
+
+func (s *recordingSpan) End() {
+	s.ended = true
+}
+
+func TestClient_Trace(t *testing.T) {
+	var attrs map[string]string
+	tracer := &recordingTracer{}
+	p := NewPool(
+		WithTracer(tracer),
+		WithTracePropagation(true),
+		WithInterceptors(func(call *Call, next Handler) error {
+			err := next(call)
+			attrs = call.Attributes
+			return err
+		}),
+	).(*pool)
+	defer p.Close()
+
+	p.active = 1
+	c := p.newClient(newMemoryConn(), p.conf())
+	defer c.Close()
+
+	// the memory connection has nothing to read, so the call fails
+	_, err := c.GetRow("t", "r", map[string]string{"k": "v"})
+	if err == nil {
+		t.Fatalf("expected error from memory connection")
+	}
+
+	s := tracer.span(spanCallPrefix + "GetRow")
+	if s == nil {
+		t.Fatalf("no span for GetRow in %v", tracer.spans)
+	}
+	if !s.ended || s.err != err {
+		t.Errorf("expected ended span with error %v, got %v, %v", err, s.ended, s.err)
+	}
+	if s.attrs[AttrMethod] != "GetRow" || s.attrs[AttrTable] != "t" || s.attrs[AttrRowCount] != 1 {
+		t.Errorf("unexpected span attributes - %v", s.attrs)
+	}
+	if s.attrs[AttrErrorClass] != ErrorClass(err) {
+		t.Errorf("expected error class %v, got %v", ErrorClass(err), s.attrs[AttrErrorClass])
+	}
+	if sent, _ := s.attrs[AttrBytesSent].(int64); sent == 0 {
+		t.Errorf("expected bytes sent, got %v", s.attrs[AttrBytesSent])
+	}
+	if attrs["k"] != "v" || attrs[traceHeader] != s.name {
+		t.Errorf("span is not injected into attributes - %v", attrs)
+	}
+}
+
+func TestClient_TraceNoPropagation(t *testing.T) {
+	var attrs map[string]string
+	p := NewPool(
+		WithTracer(&recordingTracer{}),
+		WithInterceptors(func(call *Call, next Handler) error {
+			err := next(call)
+			attrs = call.Attributes
+			return err
+		}),
+	).(*pool)
+	defer p.Close()
+
+	p.active = 1
+	c := p.newClient(newMemoryConn(), p.conf())
+	defer c.Close()
+
+	c.GetRow("t", "r", map[string]string{"k": "v"})
+	if _, ok := attrs[traceHeader]; ok || attrs["k"] != "v" {
+		t.Errorf("span must not be injected without propagation - %v", attrs)
+	}
+}
+
+func TestPool_TraceWait(t *testing.T) {
+	tracer := &recordingTracer{}
+	p := NewPool(
+		WithTracer(tracer),
+		WithMaxActive(1),
+		WithBlockMode(true),
+		WithMaxWait(time.Millisecond*10),
+	).(*pool)
+	defer p.Close()
+
+	p.active = 1
+	if _, err := p.Get(); err != ErrWaitTimeout {
+		t.Fatalf("expected error %v, got %v", ErrWaitTimeout, err)
+	}
+
+	s := tracer.span(spanPoolWait)
+	if s == nil {
+		t.Fatalf("no wait span in %v", tracer.spans)
+	}
+	if !s.ended || s.err != ErrWaitTimeout {
+		t.Errorf("expected ended span with error %v, got %v, %v", ErrWaitTimeout, s.ended, s.err)
+	}
+}
+
+func TestPool_TraceDial(t *testing.T) {
+	tracer := &recordingTracer{}
+	p := NewPool(WithTracer(tracer), WithSocketTimeout(time.Second)).(*pool)
+	defer p.Close()
+
+	// nothing listens on port 1
+	addr := "127.0.0.1:1"
+	if _, err := p.dialInstance(instance.NewCustomInstance(addr), p.conf()); err == nil {
+		t.Fatalf("expected dial error")
+	}
+
+	s := tracer.span(spanPoolDial)
+	if s == nil {
+		t.Fatalf("no dial span in %v", tracer.spans)
+	}
+	if !s.ended || s.err == nil || s.attrs[AttrInstance] != addr {
+		t.Errorf("unexpected dial span - %+v", s)
+	}
+	if s.attrs[AttrErrorClass] == nil {
+		t.Errorf("dial span has no error class - %v", s.attrs)
+	}
+}
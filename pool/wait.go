@@ -0,0 +1,98 @@
+package pool
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrWaitTimeout    = errors.New("[gohbase] wait for connection timeout")
+	ErrTooManyWaiters = errors.New("[gohbase] too many waiters")
+)
+
+// waiter is a goroutine waiting in block mode for a connection.
+type waiter struct {
+	ready    chan struct{}
+	e        *list.Element
+	priority Priority
+}
+
+// wait waits in the order of priority until a connection may be available, ctx is done,
+// deadline is exceeded or the pool is closed. If w is nil, a waiter is queued
+// and returned, and it keeps its place in the queue until it is dequeued, so
+// that it is served before the requests which arrive after it is notified.
+// It must be called with p locked, and returns with p locked.
+func (p *pool) wait(ctx context.Context, deadline time.Time, priority Priority, w *waiter) (*waiter, error) {
+	cfg := p.conf()
+	if w == nil {
+		if cfg.maxWaiters > 0 && p.waiters.Len() >= cfg.maxWaiters {
+			atomic.AddInt64(&p.counters.waitRejected, 1)
+			cfg.logger.Warn("[gohbase] too many waiters", "waiters", p.waiters.Len())
+			return nil, ErrTooManyWaiters
+		}
+
+		w = &waiter{ready: make(chan struct{}, 1), priority: priority}
+		p.pushWaiter(w)
+	}
+	cfg.logger.Debug("[gohbase] waiting for connection", "active", p.active, "max_active", cfg.maxActive, "waiters", p.waiters.Len())
+	p.Unlock()
+
+	span := p.startSpan(spanPoolWait)
+	start := now()
+
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(deadline.Sub(start))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var err error
+	select {
+	case <-w.ready:
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timeout:
+		err = ErrWaitTimeout
+	case <-p.closeChan:
+		err = ErrPoolClosed
+	}
+
+	d := now().Sub(start)
+	atomic.AddInt64(&p.counters.waitCount, 1)
+	atomic.AddInt64(&p.counters.waitDuration, int64(d))
+	if err == ErrWaitTimeout {
+		atomic.AddInt64(&p.counters.waitTimeouts, 1)
+	}
+	endSpan(span, err)
+
+	p.Lock()
+	return w, err
+}
+
+// dequeue removes w once it is served or gives up, and passes the notification
+// on to the next waiter if a connection may still be available. It must be
+// called with p locked.
+func (p *pool) dequeue(w *waiter) {
+	p.waiters.Remove(w.e)
+	if p.capacity() != 0 {
+		p.notify()
+	}
+}
+
+// notify wakes up the first waiter, which stays queued until it is served.
+// It must be called with p locked.
+func (p *pool) notify() {
+	e := p.waiters.Front()
+	if e == nil {
+		return
+	}
+
+	select {
+	case e.Value.(*waiter).ready <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,84 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPool_Wait(t *testing.T) {
+	p := NewPool(
+		WithMaxActive(1),
+		WithBlockMode(true),
+		WithMaxWait(time.Millisecond*50),
+		WithMaxWaiters(1),
+	).(*pool)
+	defer p.Close()
+
+	p.active = 1
+	if _, err := p.Get(); err != ErrWaitTimeout {
+		t.Errorf("expected error %v, got %v", ErrWaitTimeout, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := p.GetContext(ctx); err != context.Canceled {
+		t.Errorf("expected error %v, got %v", context.Canceled, err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		c, err := p.Get()
+		if err == nil {
+			err = c.Close()
+		}
+		done <- err
+	}()
+
+	for p.Stats().Waiting == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := p.Get(); err != ErrTooManyWaiters {
+		t.Errorf("expected error %v, got %v", ErrTooManyWaiters, err)
+	}
+
+	p.put(newMemoryConn(), false)
+	if err := <-done; err != nil {
+		t.Errorf("waiter error - %v", err)
+	}
+
+	if s := p.Stats(); s.WaitCount != 3 || s.WaitTimeouts != 1 || s.WaitRejected != 1 {
+		t.Errorf("unexpected wait stats - %+v", s)
+	}
+}
+
+func TestPool_WaitHandoff(t *testing.T) {
+	p := NewPool(
+		WithMaxActive(1),
+		WithBlockMode(true),
+		WithMaxWait(time.Millisecond*50),
+	).(*pool)
+	defer p.Close()
+
+	p.active = 1
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.GetContext(context.Background())
+		done <- err
+	}()
+	for p.Stats().Waiting == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the notified waiter is served before a request which arrives after it
+	p.put(newMemoryConn(), false)
+	if _, err := p.Get(); err != ErrWaitTimeout {
+		t.Errorf("expected error %v, got %v", ErrWaitTimeout, err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("waiter error - %v", err)
+	}
+	if s := p.Stats(); s.Waiting != 0 {
+		t.Errorf("waiters are not dequeued - %+v", s)
+	}
+}
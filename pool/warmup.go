@@ -0,0 +1,99 @@
+package pool
+
+import (
+	"sync"
+	"time"
+
+	"github.com/popeyeio/gohbase/instance"
+
+	"github.com/valyala/fastrand"
+)
+
+const (
+	// defaultCleanUpInterval is used to maintain min idle connections,
+	// recycle expired connections and validate idle connections in the
+	// background if no clean up interval is given.
+	defaultCleanUpInterval = time.Minute
+)
+
+// warmUp ensures min idle connections in a background goroutine, so that
+// slow or unreachable instances do not block the caller.
+func (p *pool) warmUp() {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.ensureMinIdle()
+	}()
+}
+
+// ensureMinIdle dials connections until there are at least minIdle idle ones,
+// spreading them across instances.
+func (p *pool) ensureMinIdle() {
+	cfg := p.conf()
+	if cfg.minIdle <= 0 || p.IsClosed() {
+		return
+	}
+
+	p.Lock()
+	n := cfg.minIdle - p.idleNodes.Len()
+	if cfg.maxActive > 0 && n > cfg.maxActive-p.active {
+		n = cfg.maxActive - p.active
+	}
+	if n <= 0 || len(p.instances) == 0 {
+		p.Unlock()
+		return
+	}
+
+	p.active += n
+	instances := p.spreadInstances(n)
+	p.Unlock()
+
+	var wg sync.WaitGroup
+	for _, ins := range instances {
+		wg.Add(1)
+		go func(ins instance.Instance) {
+			defer wg.Done()
+
+			cn, err := p.dialInstance(ins, cfg)
+			if err != nil {
+				p.Lock()
+				p.release()
+				p.Unlock()
+				return
+			}
+			p.put(cn, false)
+		}(ins)
+	}
+	wg.Wait()
+}
+
+// spreadInstances chooses n instances to dial, preferring the ones with
+// the fewest idle connections. It must be called with p locked.
+func (p *pool) spreadInstances(n int) []instance.Instance {
+	counts := make(map[string]int, len(p.instances))
+	for e := p.idleNodes.Front(); e != nil; e = e.Next() {
+		counts[e.Value.(*idleNode).cn.addr()] += 1
+	}
+
+	instances := make([]instance.Instance, n)
+	for i := range instances {
+		min := 0
+		for j, ins := range p.instances {
+			if counts[ins.GetAddr()] < counts[p.instances[min].GetAddr()] {
+				min = j
+			}
+		}
+		instances[i] = p.instances[min]
+		counts[p.instances[min].GetAddr()] += 1
+	}
+	return instances
+}
+
+// jitter returns a random duration in [0, d).
+func jitter(d time.Duration) time.Duration {
+	ms := uint32(d / time.Millisecond)
+	if ms == 0 {
+		return 0
+	}
+	return time.Duration(fastrand.Uint32n(ms)) * time.Millisecond
+}
@@ -0,0 +1,95 @@
+package pool
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/popeyeio/gohbase/instance"
+)
+
+func TestPool_MinIdle(t *testing.T) {
+	addr := listen(t)
+	p := NewPool(WithAddrs(addr), WithMinIdle(2), WithMaxActive(3)).(*pool)
+	defer p.Close()
+
+	waitIdle(t, p, 2)
+
+	if err := p.Reconfigure(WithMinIdle(5)); err != nil {
+		t.Fatalf("Reconfigure error - %v", err)
+	}
+	// capped by max active
+	waitIdle(t, p, 3)
+}
+
+func TestPool_MaxLifetime(t *testing.T) {
+	addr := listen(t)
+	p := NewPool(WithAddrs(addr), WithMaxLifetime(time.Hour, time.Minute)).(*pool)
+	defer p.Close()
+
+	start := now()
+	cn, err := p.dialInstance(instance.NewCustomInstance(addr), p.conf())
+	if err != nil {
+		t.Fatalf("dial error - %v", err)
+	}
+	if cn.expiresAt.Before(start.Add(time.Hour-time.Minute)) || cn.expiresAt.After(now().Add(time.Hour)) {
+		t.Errorf("expiry %v is not within the jitter of the lifetime", cn.expiresAt.Sub(start))
+	}
+
+	// an idle connection is recycled once it expires
+	p.active = 1
+	p.put(cn, false)
+	if s := p.Stats(); s.Idle != 1 {
+		t.Fatalf("connection is not idle - %+v", s)
+	}
+	cn.expiresAt = now()
+	p.cleanUpIdleNodes(false)
+	if s := p.Stats(); s.Idle != 0 || s.Active != 0 {
+		t.Errorf("expired idle connection is not recycled - %+v", s)
+	}
+
+	// an expired connection is closed when it is returned
+	p.active = 1
+	cn = newMemoryConn()
+	cn.expiresAt = now()
+	p.put(cn, false)
+	if s := p.Stats(); s.Idle != 0 || s.Active != 0 {
+		t.Errorf("expired connection is not recycled - %+v", s)
+	}
+}
+
+// listen accepts connections on a local address until the test ends.
+func listen(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error - %v", err)
+	}
+	t.Cleanup(func() {
+		ln.Close()
+	})
+
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(io.Discard, c)
+				c.Close()
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func waitIdle(t *testing.T, p *pool, n int) {
+	t.Helper()
+	for deadline := now().Add(time.Second * 5); p.Stats().Idle != n; {
+		if now().After(deadline) {
+			t.Fatalf("expected %d idle connections - %+v", n, p.Stats())
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
@@ -0,0 +1,142 @@
+package scan
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Checkpoint records the progress of a job over the ranges of a table in a
+// file, so that the job can resume where it stopped. Every range may carry
+// a result of the job, which is kept as raw json. Ranges are keyed by their
+// hex encoded bounds, and rows are saved hex encoded, as they may be binary.
+type Checkpoint struct {
+	sync.Mutex
+
+	path   string
+	Ranges map[string]*Progress `json:"ranges"`
+}
+
+// Progress is the progress of a range.
+type Progress struct {
+	Done bool `json:"done"`
+	// Last is the last row processed, if the range is not done.
+	Last   string          `json:"last,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+}
+
+// progress is Progress without its json methods.
+type progress Progress
+
+func (p Progress) MarshalJSON() ([]byte, error) {
+	v := progress(p)
+	v.Last = hex.EncodeToString([]byte(p.Last))
+	return json.Marshal(v)
+}
+
+func (p *Progress) UnmarshalJSON(b []byte) error {
+	var v progress
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	last, err := hex.DecodeString(v.Last)
+	if err != nil {
+		return err
+	}
+	v.Last = string(last)
+	*p = Progress(v)
+	return nil
+}
+
+// rangeKey identifies r in a checkpoint.
+func rangeKey(r Range) string {
+	return hex.EncodeToString([]byte(r.Start)) + "-" + hex.EncodeToString([]byte(r.Stop))
+}
+
+// OpenCheckpoint loads the checkpoint at path, or creates an empty one if
+// there is none yet. An empty path makes a checkpoint which is never saved.
+func OpenCheckpoint(path string) (*Checkpoint, error) {
+	cp := &Checkpoint{
+		path:   path,
+		Ranges: make(map[string]*Progress),
+	}
+	if path == "" {
+		return cp, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, cp); err != nil {
+		return nil, err
+	}
+	if cp.Ranges == nil {
+		cp.Ranges = make(map[string]*Progress)
+	}
+	return cp, nil
+}
+
+// Get returns the progress of r, nil if r has not started.
+func (cp *Checkpoint) Get(r Range) *Progress {
+	cp.Lock()
+	defer cp.Unlock()
+
+	if p := cp.Ranges[rangeKey(r)]; p != nil {
+		c := *p
+		return &c
+	}
+	return nil
+}
+
+// Resume returns the part of r which is left to process.
+func (cp *Checkpoint) Resume(r Range) Range {
+	if p := cp.Get(r); p != nil && p.Last != "" {
+		r.Start = p.Last + "\x00"
+	}
+	return r
+}
+
+// Set records the progress of r, and saves the checkpoint.
+func (cp *Checkpoint) Set(r Range, p *Progress) error {
+	cp.Lock()
+	defer cp.Unlock()
+
+	cp.Ranges[rangeKey(r)] = p
+	return cp.save()
+}
+
+// save writes the checkpoint atomically. It must be called with cp locked.
+func (cp *Checkpoint) save() error {
+	if cp.path == "" {
+		return nil
+	}
+
+	b, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(cp.path), filepath.Base(cp.path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(b); err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), cp.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
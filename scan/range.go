@@ -0,0 +1,66 @@
+package scan
+
+import (
+	"sort"
+
+	"github.com/popeyeio/gohbase/pool"
+)
+
+// Range is a range of rows [Start, Stop). An empty Start is the first row
+// of a table, and an empty Stop is past the last one.
+type Range struct {
+	Start string `json:"start"`
+	Stop  string `json:"stop"`
+}
+
+// Contains reports whether row is within r.
+func (r Range) Contains(row string) bool {
+	return row >= r.Start && (r.Stop == "" || row < r.Stop)
+}
+
+// String identifies r, e.g. in checkpoints.
+func (r Range) String() string {
+	return "[" + r.Start + ", " + r.Stop + ")"
+}
+
+// Prefix returns the range of the rows starting with prefix.
+func Prefix(prefix string) Range {
+	stop := []byte(prefix)
+	for len(stop) > 0 && stop[len(stop)-1] == 0xff {
+		stop = stop[:len(stop)-1]
+	}
+	if len(stop) > 0 {
+		stop[len(stop)-1]++
+	}
+	return Range{Start: prefix, Stop: string(stop)}
+}
+
+// Split splits the range [start, stop) of table by the regions of table,
+// so that every range is served by a single region and may be scanned in
+// parallel with the others.
+func Split(e pool.Executor, table, start, stop string) ([]Range, error) {
+	regions, err := e.GetTableRegions(table)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(regions))
+	for _, r := range regions {
+		if r != nil && len(r.StartKey) > 0 {
+			keys = append(keys, string(r.StartKey))
+		}
+	}
+	sort.Strings(keys)
+
+	whole := Range{Start: start, Stop: stop}
+	ranges := make([]Range, 0, len(keys)+1)
+	begin := start
+	for _, key := range keys {
+		if key <= begin || !whole.Contains(key) {
+			continue
+		}
+		ranges = append(ranges, Range{Start: begin, Stop: key})
+		begin = key
+	}
+	return append(ranges, Range{Start: begin, Stop: stop}), nil
+}
@@ -0,0 +1,144 @@
+package scan
+
+import (
+	"context"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+	"github.com/popeyeio/gohbase/pool"
+)
+
+const (
+	defaultBatchSize = 256
+)
+
+type scanner struct {
+	columns    []string
+	batchSize  int
+	attributes map[string]string
+	filter     string
+	timestamp  *int64
+	reversed   bool
+}
+
+type Option func(*scanner)
+
+// WithColumns scans only columns, which are families or family:qualifier.
+func WithColumns(columns ...string) Option {
+	return func(s *scanner) {
+		s.columns = columns
+	}
+}
+
+// WithBatchSize sets the number of rows fetched by a call, 256 by default.
+func WithBatchSize(n int) Option {
+	return func(s *scanner) {
+		if n > 0 {
+			s.batchSize = n
+		}
+	}
+}
+
+// WithAttributes sets the attributes of the scanner.
+func WithAttributes(attributes map[string]string) Option {
+	return func(s *scanner) {
+		s.attributes = attributes
+	}
+}
+
+// WithFilter sets the filter string of the scan.
+func WithFilter(filter string) Option {
+	return func(s *scanner) {
+		s.filter = filter
+	}
+}
+
+// WithTimestamp scans only cells whose timestamps are not after ts.
+func WithTimestamp(ts int64) Option {
+	return func(s *scanner) {
+		s.timestamp = &ts
+	}
+}
+
+// WithReversed scans the rows in reverse order, from the Start of the range
+// down to its Stop exclusive. An empty Start is then the last row of the
+// table, and an empty Stop is before the first one.
+func WithReversed() Option {
+	return func(s *scanner) {
+		s.reversed = true
+	}
+}
+
+// Scan scans the rows of r in table, and calls f with every batch of rows in
+// order. Scan stops if f fails or ctx is done. The scanner is bound to the
+// connection it is opened on, so the scan is not retried.
+func Scan(ctx context.Context, e pool.Executor, table string, r Range, f func([]*hbase.TRowResult_) error, opts ...Option) error {
+	s := &scanner{
+		batchSize: defaultBatchSize,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	caching := int32(s.batchSize)
+	sortColumns := true
+	tscan := &hbase.TScan{
+		StartRow:    hbase.Text(r.Start),
+		StopRow:     hbase.Text(r.Stop),
+		Timestamp:   s.timestamp,
+		Caching:     &caching,
+		SortColumns: &sortColumns,
+	}
+	for _, column := range s.columns {
+		tscan.Columns = append(tscan.Columns, []byte(column))
+	}
+	if s.filter != "" {
+		tscan.FilterString = hbase.Text(s.filter)
+	}
+	if s.reversed {
+		tscan.Reversed = &s.reversed
+	}
+
+	return e.Do(ctx, func(c pool.Client) error {
+		id, err := c.ScannerOpenWithScan(table, tscan, s.attributes)
+		if err != nil {
+			return err
+		}
+		defer c.ScannerClose(id)
+
+		for {
+			if err = ctx.Err(); err != nil {
+				return err
+			}
+
+			rows, err := c.ScannerGetList(id, int32(s.batchSize))
+			if err != nil {
+				return err
+			}
+			if len(rows) == 0 {
+				return nil
+			}
+			if err = f(rows); err != nil {
+				return err
+			}
+		}
+	})
+}
+
+// Columns returns the cells of a row keyed by column, whether they are
+// sorted or not.
+func Columns(r *hbase.TRowResult_) map[string]*hbase.TCell {
+	if len(r.SortedColumns) == 0 {
+		return r.Columns
+	}
+
+	columns := make(map[string]*hbase.TCell, len(r.Columns)+len(r.SortedColumns))
+	for column, cell := range r.Columns {
+		columns[column] = cell
+	}
+	for _, column := range r.SortedColumns {
+		if column != nil {
+			columns[string(column.ColumnName)] = column.Cell
+		}
+	}
+	return columns
+}
@@ -0,0 +1,148 @@
+package scan
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/popeyeio/gohbase/gen/hbase"
+	"github.com/popeyeio/gohbase/internal/hbasetest"
+)
+
+func TestSplit(t *testing.T) {
+	e := hbasetest.NewTable("", "m", "d", "t")
+
+	ranges, err := Split(e, "t", "", "")
+	if err != nil {
+		t.Fatalf("Split error - %v", err)
+	}
+	expected := []Range{{"", "d"}, {"d", "m"}, {"m", "t"}, {"t", ""}}
+	if !reflect.DeepEqual(ranges, expected) {
+		t.Errorf("expected ranges %v, got %v", expected, ranges)
+	}
+
+	// the regions outside of the range are left out
+	if ranges, err = Split(e, "t", "e", "n"); err != nil {
+		t.Fatalf("Split error - %v", err)
+	}
+	expected = []Range{{"e", "m"}, {"m", "n"}}
+	if !reflect.DeepEqual(ranges, expected) {
+		t.Errorf("expected ranges %v, got %v", expected, ranges)
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint")
+	cp, err := OpenCheckpoint(path)
+	if err != nil {
+		t.Fatalf("OpenCheckpoint error - %v", err)
+	}
+
+	r, done := Range{Start: "a", Stop: "m"}, Range{Start: "m"}
+	if resumed := cp.Resume(r); resumed != r {
+		t.Errorf("expected a range not started to resume from its start, got %v", resumed)
+	}
+	if err = cp.Set(r, &Progress{Last: "c"}); err != nil {
+		t.Fatalf("Set error - %v", err)
+	}
+	if err = cp.Set(done, &Progress{Done: true}); err != nil {
+		t.Fatalf("Set error - %v", err)
+	}
+
+	// the progress survives reopening
+	if cp, err = OpenCheckpoint(path); err != nil {
+		t.Fatalf("OpenCheckpoint error - %v", err)
+	}
+	if resumed := cp.Resume(r); resumed != (Range{Start: "c\x00", Stop: "m"}) {
+		t.Errorf("expected the range to resume after c, got %v", resumed)
+	}
+	if p := cp.Get(done); p == nil || !p.Done {
+		t.Errorf("expected range %v to be done, got %+v", done, p)
+	}
+}
+
+func TestCheckpoint_BinaryKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint")
+	cp, err := OpenCheckpoint(path)
+	if err != nil {
+		t.Fatalf("OpenCheckpoint error - %v", err)
+	}
+
+	// the bounds of both ranges print as "[a, b, c)"
+	a, b := Range{Start: "a, b", Stop: "c"}, Range{Start: "a", Stop: "b, c"}
+	bin := Range{Start: "\xff\x00", Stop: "\xff\xfe"}
+	if err = cp.Set(a, &Progress{Done: true}); err != nil {
+		t.Fatalf("Set error - %v", err)
+	}
+	if err = cp.Set(bin, &Progress{Last: "\xff\x00\x80"}); err != nil {
+		t.Fatalf("Set error - %v", err)
+	}
+
+	if cp, err = OpenCheckpoint(path); err != nil {
+		t.Fatalf("OpenCheckpoint error - %v", err)
+	}
+	if p := cp.Get(b); p != nil {
+		t.Errorf("expected range %q not to be started, got %+v", b, p)
+	}
+	if p := cp.Get(a); p == nil || !p.Done {
+		t.Errorf("expected range %q to be done, got %+v", a, p)
+	}
+	if resumed := cp.Resume(bin); resumed != (Range{Start: "\xff\x00\x80\x00", Stop: "\xff\xfe"}) {
+		t.Errorf("expected the binary range to resume after its last row, got %q", resumed)
+	}
+}
+
+func TestScan(t *testing.T) {
+	e := hbasetest.NewTable()
+	for _, row := range []string{"a", "b", "c", "d", "e"} {
+		e.Put(row, "f:q", 1, row)
+		e.Put(row, "g:q", 1, row)
+	}
+
+	scan := func(r Range, opts ...Option) []string {
+		var rows []string
+		err := Scan(context.Background(), e, "t", r, func(batch []*hbase.TRowResult_) error {
+			if len(batch) > 2 {
+				t.Errorf("batch of %d rows is larger than the batch size", len(batch))
+			}
+			for _, row := range batch {
+				if _, ok := row.Columns["g:q"]; ok {
+					t.Errorf("column g:q is not scanned")
+				}
+				rows = append(rows, string(row.Row))
+			}
+			return nil
+		}, append(opts, WithBatchSize(2), WithColumns("f"))...)
+		if err != nil {
+			t.Fatalf("Scan error - %v", err)
+		}
+		return rows
+	}
+
+	if rows := scan(Range{Start: "b", Stop: "e"}); !reflect.DeepEqual(rows, []string{"b", "c", "d"}) {
+		t.Errorf("unexpected rows %v", rows)
+	}
+
+	// a reversed scan goes from its start down to its stop exclusive
+	if rows := scan(Range{Start: "d", Stop: "a"}, WithReversed()); !reflect.DeepEqual(rows, []string{"d", "c", "b"}) {
+		t.Errorf("unexpected reversed rows %v", rows)
+	}
+	if rows := scan(Range{}, WithReversed()); !reflect.DeepEqual(rows, []string{"e", "d", "c", "b", "a"}) {
+		t.Errorf("unexpected reversed rows %v", rows)
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	for prefix, expected := range map[string]Range{
+		"ab":     {Start: "ab", Stop: "ac"},
+		"a\xff":  {Start: "a\xff", Stop: "b"},
+		"\xff":   {Start: "\xff", Stop: ""},
+		"":       {},
+		"a\x00z": {Start: "a\x00z", Stop: "a\x00{"},
+	} {
+		if r := Prefix(prefix); r != expected {
+			t.Errorf("Prefix(%q) = %v, expected %v", prefix, r, expected)
+		}
+	}
+}